@@ -6,7 +6,8 @@ import (
 
 // argument represents an argument to a GraphQL selection
 type argument struct {
-	astArg *ast.Argument
+	astArg  *ast.Argument
+	uploads []*boundUpload
 }
 
 // NewArgument constructs a new argument with a value
@@ -16,17 +17,38 @@ func NewArgument(name string, val *Value) *argument {
 			Name:  ast.NewName(&ast.Name{Value: name}),
 			Value: val.astValue,
 		}),
+		uploads: val.uploads,
 	}
 }
 
-// WithArguments is a selection option for specifying arguments
-func WithArguments(args ...*argument) selectionOption {
+// WithArguments is a selection option for specifying arguments.
+// The uploads used within their values are bound to variables, see NewUploadValue.
+func WithArguments(args ...*argument) SelectionOption {
 	return func(s *Selection) {
 		for _, arg := range args {
 			switch n := s.node.(type) {
 			case *ast.Field:
 				n.Arguments = append(n.Arguments, arg.astArg)
+				s.bindUploads(arg.Name(), arg.uploads)
 			}
 		}
 	}
 }
+
+// Name returns the name of the argument
+func (a *argument) Name() string {
+	return a.astArg.Name.Value
+}
+
+// Value returns the value of the argument
+func (a *argument) Value() *Value {
+	return &Value{astValue: a.astArg.Value}
+}
+
+func argumentValues(args []*ast.Argument) map[string]*Value {
+	values := make(map[string]*Value, len(args))
+	for _, arg := range args {
+		values[arg.Name.Value] = &Value{astValue: arg.Value}
+	}
+	return values
+}
@@ -0,0 +1,55 @@
+package fluentgraphql
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// AST returns the graphql-go node the selection builds: an *ast.OperationDefinition for
+// operations, and an *ast.Field, *ast.InlineFragment, *ast.FragmentSpread or
+// *ast.FragmentDefinition otherwise. The node isn't copied, so it changes as the selection
+// is built on, and changes made to it are seen by the selection. Use Document for the
+// document of an operation along with the fragments it spreads.
+func (s *Selection) AST() ast.Node {
+	return s.node
+}
+
+// FromAST returns a selection builder wrapping a graphql-go node, such as one returned by
+// the parser or by AST. The node isn't copied: building on the selection changes the
+// node. An *ast.Document is wrapped as its first operation, with the fragment definitions
+// of the document printed along with it.
+func FromAST(node ast.Node) (*Selection, error) {
+	switch n := node.(type) {
+	case *ast.Document:
+		var s *Selection
+		var fragments []*ast.FragmentDefinition
+		for _, def := range n.Definitions {
+			switch def := def.(type) {
+			case *ast.OperationDefinition:
+				if s == nil {
+					s = &Selection{node: def}
+				}
+			case *ast.FragmentDefinition:
+				fragments = append(fragments, def)
+			}
+		}
+		if s == nil {
+			return nil, errors.New("fluentgraphql: no operation in document")
+		}
+		s.fragments = fragments
+		return s, nil
+	case *ast.OperationDefinition, *ast.Field, *ast.InlineFragment, *ast.FragmentSpread, *ast.FragmentDefinition:
+		return &Selection{node: n}, nil
+	case nil:
+		return nil, errors.New("fluentgraphql: no node to wrap")
+	}
+	return nil, fmt.Errorf("fluentgraphql: cannot wrap a %s node", node.GetKind())
+}
+
+// ValueFromAST wraps a graphql-go value, such as the default value of a parsed variable
+// definition, for Interface and String. The value isn't copied.
+func ValueFromAST(value ast.Value) *Value {
+	return &Value{astValue: value}
+}
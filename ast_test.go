@@ -0,0 +1,80 @@
+package fluentgraphql
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func TestFromAST(t *testing.T) {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source:  `query Repo { repository { name ...ownerFields } } fragment ownerFields on Repository { owner }`,
+		Options: parser.ParseOptions{NoSource: true, NoLocation: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := FromAST(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if q.AST() != doc.Definitions[0] {
+		t.Fatal("expected the operation of the document to be wrapped as is")
+	}
+
+	// building on a wrapped scalar field gives it a selection set
+	q.Children()[0].Children()[0].Scalar("length")
+	wanted := "query Repo {\n  repository {\n    name {\n      length\n    }\n    ...ownerFields\n  }\n}\n\nfragment ownerFields on Repository {\n  owner\n}"
+	if diff := cmp.Diff(wanted, q.String()); diff != "" {
+		t.Fatal("produced GraphQL query does not match what's wanted", diff)
+	}
+
+	for name, node := range map[string]ast.Node{
+		"Empty":    ast.NewDocument(&ast.Document{}),
+		"Value":    ast.NewIntValue(&ast.IntValue{Value: "1"}),
+		"Argument": ast.NewArgument(&ast.Argument{}),
+		"Nil":      nil,
+	} {
+		if _, err := FromAST(node); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestASTExecution(t *testing.T) {
+	s := NewSchema()
+	s.Type("Query").Field("repository", "Repository")
+	s.Type("Repository").Field("name", "String!").Field("owner", "String!")
+	schema, err := s.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := NewFragment("ownerFields", "Repository")
+	fields.Scalar("owner")
+	q := NewQuery().Selection("repository").Scalar("name").Spread(fields).Root()
+
+	doc, err := q.Document()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result := graphql.Execute(graphql.ExecuteParams{
+		Schema: schema,
+		AST:    doc,
+		Root: map[string]interface{}{
+			"repository": map[string]interface{}{"name": "fluentgraphql", "owner": "mergestat"},
+		},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	got, _ := json.Marshal(result.Data)
+	if diff := cmp.Diff(`{"repository":{"name":"fluentgraphql","owner":"mergestat"}}`, string(got)); diff != "" {
+		t.Fatal("execution result does not match what's wanted", diff)
+	}
+}
@@ -0,0 +1,369 @@
+package fluentgraphql
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// BuildSchema builds an executable schema from type system definitions, such as
+// those returned by ParseIntrospection. Fields resolve with the default resolver
+// and abstract types resolve the object type from the __typename of the value.
+func BuildSchema(doc *ast.Document) (graphql.Schema, error) {
+	b := &schemaBuilder{
+		definitions: make(map[string]ast.Node),
+		types: map[string]graphql.Type{
+			"Int":     graphql.Int,
+			"Float":   graphql.Float,
+			"String":  graphql.String,
+			"Boolean": graphql.Boolean,
+			"ID":      graphql.ID,
+		},
+	}
+
+	roots := map[string]string{
+		ast.OperationTypeQuery:        "Query",
+		ast.OperationTypeMutation:     "Mutation",
+		ast.OperationTypeSubscription: "Subscription",
+	}
+	var names []string
+	var directives []*ast.DirectiveDefinition
+	var extensions []*ast.ObjectDefinition
+	for _, def := range doc.Definitions {
+		switch n := def.(type) {
+		case *ast.SchemaDefinition:
+			for _, op := range n.OperationTypes {
+				roots[op.Operation] = op.Type.Name.Value
+			}
+			continue
+		case *ast.DirectiveDefinition:
+			directives = append(directives, n)
+			continue
+		case *ast.TypeExtensionDefinition:
+			extensions = append(extensions, n.Definition)
+			continue
+		case *ast.ScalarDefinition:
+			if isBuiltinScalar(n.Name.Value) {
+				continue
+			}
+			names = append(names, n.Name.Value)
+			b.definitions[n.Name.Value] = n
+		case *ast.ObjectDefinition:
+			names = append(names, n.Name.Value)
+			copied := *n
+			b.definitions[n.Name.Value] = &copied
+		case *ast.InterfaceDefinition:
+			names = append(names, n.Name.Value)
+			b.definitions[n.Name.Value] = n
+		case *ast.UnionDefinition:
+			names = append(names, n.Name.Value)
+			b.definitions[n.Name.Value] = n
+		case *ast.EnumDefinition:
+			names = append(names, n.Name.Value)
+			b.definitions[n.Name.Value] = n
+		case *ast.InputObjectDefinition:
+			names = append(names, n.Name.Value)
+			b.definitions[n.Name.Value] = n
+		}
+	}
+
+	for _, ext := range extensions {
+		object, ok := b.definitions[ext.Name.Value].(*ast.ObjectDefinition)
+		if !ok {
+			return graphql.Schema{}, fmt.Errorf("fluentgraphql: extension of %s, which is not an object type", ext.Name.Value)
+		}
+		object.Fields = append(append([]*ast.FieldDefinition{}, object.Fields...), ext.Fields...)
+		object.Interfaces = append(append([]*ast.Named{}, object.Interfaces...), ext.Interfaces...)
+	}
+
+	config := graphql.SchemaConfig{Directives: graphql.SpecifiedDirectives}
+	for _, name := range names {
+		t, err := b.namedType(name)
+		if err != nil {
+			return graphql.Schema{}, err
+		}
+		config.Types = append(config.Types, t)
+	}
+	for _, d := range directives {
+		args, err := b.arguments(d.Arguments)
+		if err != nil {
+			return graphql.Schema{}, err
+		}
+		locations := make([]string, 0, len(d.Locations))
+		for _, l := range d.Locations {
+			locations = append(locations, l.Value)
+		}
+		config.Directives = append(config.Directives, graphql.NewDirective(graphql.DirectiveConfig{
+			Name:        d.Name.Value,
+			Description: descriptionValue(d.Description),
+			Locations:   locations,
+			Args:        args,
+		}))
+	}
+
+	for operation, root := range map[string]**graphql.Object{
+		ast.OperationTypeQuery:        &config.Query,
+		ast.OperationTypeMutation:     &config.Mutation,
+		ast.OperationTypeSubscription: &config.Subscription,
+	} {
+		if _, ok := b.definitions[roots[operation]]; !ok {
+			continue
+		}
+		t, err := b.namedType(roots[operation])
+		if err != nil {
+			return graphql.Schema{}, err
+		}
+		object, ok := t.(*graphql.Object)
+		if !ok {
+			return graphql.Schema{}, fmt.Errorf("fluentgraphql: %s root type %s is not an object type", operation, roots[operation])
+		}
+		*root = object
+	}
+	if b.err != nil {
+		return graphql.Schema{}, b.err
+	}
+
+	schema, err := graphql.NewSchema(config)
+	if b.err != nil {
+		return graphql.Schema{}, b.err
+	}
+	return schema, err
+}
+
+// schemaBuilder turns type definitions into graphql-go types, building each type once
+type schemaBuilder struct {
+	definitions map[string]ast.Node
+	types       map[string]graphql.Type
+	// err holds the first error raised while resolving the fields thunks
+	err error
+}
+
+func (b *schemaBuilder) namedType(name string) (graphql.Type, error) {
+	if t, ok := b.types[name]; ok {
+		return t, nil
+	}
+	def, ok := b.definitions[name]
+	if !ok {
+		return nil, fmt.Errorf("fluentgraphql: unknown type %s", name)
+	}
+
+	var t graphql.Type
+	switch n := def.(type) {
+	case *ast.ScalarDefinition:
+		t = graphql.NewScalar(graphql.ScalarConfig{
+			Name:        name,
+			Description: descriptionValue(n.Description),
+			Serialize:   func(value interface{}) interface{} { return value },
+			ParseValue:  func(value interface{}) interface{} { return value },
+			ParseLiteral: func(value ast.Value) interface{} {
+				return goValue(value, nil)
+			},
+		})
+	case *ast.ObjectDefinition:
+		t = graphql.NewObject(graphql.ObjectConfig{
+			Name:        name,
+			Description: descriptionValue(n.Description),
+			Fields:      b.fieldsThunk(n.Fields),
+			Interfaces: graphql.InterfacesThunk(func() []*graphql.Interface {
+				interfaces := make([]*graphql.Interface, 0, len(n.Interfaces))
+				for _, named := range n.Interfaces {
+					t, err := b.namedType(named.Name.Value)
+					if err != nil {
+						b.fail(err)
+						continue
+					}
+					iface, ok := t.(*graphql.Interface)
+					if !ok {
+						b.fail(fmt.Errorf("fluentgraphql: %s implements %s, which is not an interface", name, named.Name.Value))
+						continue
+					}
+					interfaces = append(interfaces, iface)
+				}
+				return interfaces
+			}),
+		})
+	case *ast.InterfaceDefinition:
+		t = graphql.NewInterface(graphql.InterfaceConfig{
+			Name:        name,
+			Description: descriptionValue(n.Description),
+			Fields:      b.fieldsThunk(n.Fields),
+			ResolveType: b.resolveType,
+		})
+	case *ast.UnionDefinition:
+		// union members must exist before the union, so register the union afterwards
+		types := make([]*graphql.Object, 0, len(n.Types))
+		for _, named := range n.Types {
+			member, err := b.namedType(named.Name.Value)
+			if err != nil {
+				return nil, err
+			}
+			object, ok := member.(*graphql.Object)
+			if !ok {
+				return nil, fmt.Errorf("fluentgraphql: member %s of union %s is not an object type", named.Name.Value, name)
+			}
+			types = append(types, object)
+		}
+		t = graphql.NewUnion(graphql.UnionConfig{
+			Name:        name,
+			Description: descriptionValue(n.Description),
+			Types:       types,
+			ResolveType: b.resolveType,
+		})
+	case *ast.EnumDefinition:
+		values := make(graphql.EnumValueConfigMap, len(n.Values))
+		for _, v := range n.Values {
+			values[v.Name.Value] = &graphql.EnumValueConfig{
+				Value:             v.Name.Value,
+				Description:       descriptionValue(v.Description),
+				DeprecationReason: deprecationReason(v.Directives),
+			}
+		}
+		t = graphql.NewEnum(graphql.EnumConfig{
+			Name:        name,
+			Description: descriptionValue(n.Description),
+			Values:      values,
+		})
+	case *ast.InputObjectDefinition:
+		t = graphql.NewInputObject(graphql.InputObjectConfig{
+			Name:        name,
+			Description: descriptionValue(n.Description),
+			Fields: graphql.InputObjectConfigFieldMapThunk(func() graphql.InputObjectConfigFieldMap {
+				fields := make(graphql.InputObjectConfigFieldMap, len(n.Fields))
+				for _, f := range n.Fields {
+					arg, err := b.argument(f)
+					if err != nil {
+						b.fail(err)
+						continue
+					}
+					fields[f.Name.Value] = &graphql.InputObjectFieldConfig{
+						Type:         arg.Type,
+						DefaultValue: arg.DefaultValue,
+						Description:  arg.Description,
+					}
+				}
+				return fields
+			}),
+		})
+	}
+	b.types[name] = t
+	return t, nil
+}
+
+func (b *schemaBuilder) fieldsThunk(defs []*ast.FieldDefinition) graphql.FieldsThunk {
+	return func() graphql.Fields {
+		fields := make(graphql.Fields, len(defs))
+		for _, f := range defs {
+			t, err := b.typeRef(f.Type)
+			if err != nil {
+				b.fail(err)
+				continue
+			}
+			output, ok := t.(graphql.Output)
+			if !ok {
+				b.fail(fmt.Errorf("fluentgraphql: type of field %s is not an output type", f.Name.Value))
+				continue
+			}
+			args, err := b.arguments(f.Arguments)
+			if err != nil {
+				b.fail(err)
+				continue
+			}
+			fields[f.Name.Value] = &graphql.Field{
+				Type:              output,
+				Args:              args,
+				Description:       descriptionValue(f.Description),
+				DeprecationReason: deprecationReason(f.Directives),
+			}
+		}
+		return fields
+	}
+}
+
+func (b *schemaBuilder) arguments(defs []*ast.InputValueDefinition) (graphql.FieldConfigArgument, error) {
+	args := make(graphql.FieldConfigArgument, len(defs))
+	for _, def := range defs {
+		arg, err := b.argument(def)
+		if err != nil {
+			return nil, err
+		}
+		args[def.Name.Value] = arg
+	}
+	return args, nil
+}
+
+func (b *schemaBuilder) argument(def *ast.InputValueDefinition) (*graphql.ArgumentConfig, error) {
+	t, err := b.typeRef(def.Type)
+	if err != nil {
+		return nil, err
+	}
+	input, ok := t.(graphql.Input)
+	if !ok {
+		return nil, fmt.Errorf("fluentgraphql: type of %s is not an input type", def.Name.Value)
+	}
+	arg := &graphql.ArgumentConfig{Type: input, Description: descriptionValue(def.Description)}
+	if def.DefaultValue != nil {
+		arg.DefaultValue = goValue(def.DefaultValue, nil)
+	}
+	return arg, nil
+}
+
+func (b *schemaBuilder) typeRef(t ast.Type) (graphql.Type, error) {
+	switch t := t.(type) {
+	case *ast.NonNull:
+		ofType, err := b.typeRef(t.Type)
+		if err != nil {
+			return nil, err
+		}
+		return graphql.NewNonNull(ofType), nil
+	case *ast.List:
+		ofType, err := b.typeRef(t.Type)
+		if err != nil {
+			return nil, err
+		}
+		return graphql.NewList(ofType), nil
+	case *ast.Named:
+		return b.namedType(t.Name.Value)
+	}
+	return nil, fmt.Errorf("fluentgraphql: unknown type reference %v", t)
+}
+
+// resolveType picks the object type of a value from its __typename
+func (b *schemaBuilder) resolveType(p graphql.ResolveTypeParams) *graphql.Object {
+	value, ok := p.Value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	typename, _ := value["__typename"].(string)
+	object, _ := b.types[typename].(*graphql.Object)
+	return object
+}
+
+func (b *schemaBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = err
+	}
+}
+
+func descriptionValue(d *ast.StringValue) string {
+	if d == nil {
+		return ""
+	}
+	return d.Value
+}
+
+// deprecationReason returns the reason of a @deprecated directive, which is empty when there is none
+func deprecationReason(directives []*ast.Directive) string {
+	for _, d := range directives {
+		if d.Name.Value != "deprecated" {
+			continue
+		}
+		for _, arg := range d.Arguments {
+			if s, ok := arg.Value.(*ast.StringValue); ok && arg.Name.Value == "reason" {
+				return s.Value
+			}
+		}
+		return "No longer supported"
+	}
+	return ""
+}
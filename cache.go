@@ -0,0 +1,618 @@
+package fluentgraphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Cache is an in-memory normalized cache of responses. Objects with a __typename and key
+// fields, id by default, are stored once as entities shared by every response they appear
+// in, so that each response updates the objects of the others. Other objects are stored
+// within their parent, and fields along with their arguments.
+type Cache struct {
+	mu            sync.RWMutex
+	keyFields     map[string][]string
+	possibleTypes map[string]map[string]bool
+	// objectTypes are the types of the objects seen in responses, or given as possible types
+	objectTypes map[string]bool
+	records     map[string]map[string]interface{}
+}
+
+type cacheOption func(*Cache)
+
+// NewCache returns an empty cache
+func NewCache(options ...cacheOption) *Cache {
+	c := &Cache{
+		keyFields:     make(map[string][]string),
+		possibleTypes: make(map[string]map[string]bool),
+		objectTypes:   make(map[string]bool),
+		records:       make(map[string]map[string]interface{}),
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// WithKeyFields specifies the fields identifying the objects of a type, in place of id.
+// Without fields, the objects of the type are stored within their parent.
+func WithKeyFields(typeName string, fields ...string) cacheOption {
+	return func(c *Cache) {
+		c.keyFields[typeName] = fields
+	}
+}
+
+// WithPossibleTypes specifies the object types implementing an interface or member of a
+// union, for fragments on the interface or union to apply to their objects. Fragments
+// on the object types given or seen in responses only apply to the objects of that type, while
+// the cache can't tell which objects fragments on other types apply to: it stores the
+// fields of these fragments that responses hold, but doesn't answer queries selecting
+// fields of theirs it is missing.
+func WithPossibleTypes(abstractType string, types ...string) cacheOption {
+	return func(c *Cache) {
+		if c.possibleTypes[abstractType] == nil {
+			c.possibleTypes[abstractType] = make(map[string]bool)
+		}
+		for _, t := range types {
+			c.possibleTypes[abstractType][t] = true
+			c.objectTypes[t] = true
+		}
+	}
+}
+
+// rootQuery is the key of the record holding the root fields of queries
+const rootQuery = "ROOT_QUERY"
+
+// cacheRef is a reference to an entity, in place of the object in its parent
+type cacheRef string
+
+// Write stores the data of a response to the operation at the root of the selection, sent
+// with the given variables. The root fields of mutations and subscriptions aren't stored,
+// but the entities found in their responses are.
+func (c *Cache) Write(s *Selection, variables map[string]interface{}, data json.RawMessage) error {
+	op, err := cachedOperation(s)
+	if err != nil {
+		return err
+	}
+	var value interface{}
+	if err := decodeJSON(data, &value); err != nil {
+		return fmt.Errorf("fluentgraphql: invalid response data: %w", err)
+	}
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return errors.New("fluentgraphql: response data is not an object")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	root := make(map[string]interface{})
+	if op.Operation == ast.OperationTypeQuery {
+		root = c.record(rootQuery)
+	}
+	w := &cacheWalker{cache: c, variables: variables}
+	w.write(op.SelectionSet, root, object)
+	return nil
+}
+
+// Read answers the query at the root of the selection from the cache, returning the data
+// found, and whether it holds every field the query selects
+func (c *Cache) Read(s *Selection, variables map[string]interface{}) (json.RawMessage, bool, error) {
+	op, err := cachedQuery(s)
+	if err != nil {
+		return nil, false, err
+	}
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	w := &cacheWalker{cache: c, variables: variables}
+	data, complete := w.read(op.SelectionSet, c.records[rootQuery])
+	b, err := json.Marshal(data)
+	if err != nil {
+		return nil, false, err
+	}
+	return b, complete, nil
+}
+
+// Missing returns the query at the root of the selection reduced to the fields the cache
+// doesn't hold, or nil when it holds them all. The objects of these fields keep their
+// __typename and key fields, for the response to be stored, and the reduced query keeps
+// the typenames asked for with WithTypenames. Fragment spreads are inlined, and the
+// variables the reduced query no longer uses are left out.
+func (c *Cache) Missing(s *Selection, variables map[string]interface{}) (*Selection, error) {
+	op, err := cachedQuery(s)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.RLock()
+	w := &cacheWalker{cache: c, variables: variables}
+	set := w.prune(op.SelectionSet, []map[string]interface{}{c.records[rootQuery]})
+	c.mu.RUnlock()
+	if set == nil {
+		return nil, nil
+	}
+
+	reduced := *op
+	reduced.SelectionSet = set
+	used := make(map[string]bool)
+	Walk(&Selection{node: &reduced}, VisitorFunc(func(cur *Cursor) WalkAction {
+		if v, ok := cur.node.(*ast.Variable); ok {
+			used[v.Name.Value] = true
+		}
+		return Continue
+	}))
+	reduced.VariableDefinitions = nil
+	for _, def := range op.VariableDefinitions {
+		if used[def.Variable.Name.Value] {
+			reduced.VariableDefinitions = append(reduced.VariableDefinitions, def)
+		}
+	}
+	return s.Root().derive(&reduced), nil
+}
+
+// CacheFirst is a middleware answering queries from the cache when it holds every field
+// they select, and otherwise fetching only the fields it is missing. The data of every
+// response, to queries or not, is stored in the cache.
+func CacheFirst(c *Cache) Middleware {
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			if !isQuery(req.Selection) {
+				return c.execute(ctx, next, req)
+			}
+			if data, complete, err := c.Read(req.Selection, req.Variables); err == nil && complete {
+				return &Response{Data: data}, nil
+			}
+
+			reduced, err := c.Missing(req.Selection, req.Variables)
+			if err != nil || reduced == nil {
+				return c.execute(ctx, next, req)
+			}
+			r := *req
+			r.Selection = reduced
+			r.Variables = usedVariables(reduced, req.Variables)
+			res, err := c.execute(ctx, next, &r)
+			if err != nil {
+				return res, err
+			}
+			data, complete, err := c.Read(req.Selection, req.Variables)
+			if err != nil || !complete {
+				// the missing fields didn't fill the cache in, as they failed or objects
+				// changed in between
+				return c.execute(ctx, next, req)
+			}
+			// the errors of the missing fields have the same paths in the whole response
+			return &Response{Data: data, Errors: res.Errors, Extensions: res.Extensions, Header: res.Header, StatusCode: res.StatusCode}, nil
+		})
+	}
+}
+
+// execute sends a request, storing the data of its response
+func (c *Cache) execute(ctx context.Context, next Executor, req *Request) (*Response, error) {
+	res, err := next.Execute(ctx, req)
+	if err == nil && res.Data != nil && string(res.Data) != "null" {
+		c.Write(req.Selection, req.Variables, res.Data)
+	}
+	return res, err
+}
+
+// usedVariables returns the variables defined by the operation at the root of the selection
+func usedVariables(s *Selection, variables map[string]interface{}) map[string]interface{} {
+	op := s.Root().node.(*ast.OperationDefinition)
+	used := make(map[string]interface{}, len(op.VariableDefinitions))
+	for _, def := range op.VariableDefinitions {
+		if value, ok := variables[def.Variable.Name.Value]; ok {
+			used[def.Variable.Name.Value] = value
+		}
+	}
+	return used
+}
+
+// record returns the record of an entity, creating it if needed
+func (c *Cache) record(key string) map[string]interface{} {
+	record, ok := c.records[key]
+	if !ok {
+		record = make(map[string]interface{})
+		c.records[key] = record
+	}
+	return record
+}
+
+// entityKey returns the key of the entity an object is, if it has a __typename and key fields
+func (c *Cache) entityKey(object map[string]interface{}) (string, bool) {
+	typename, ok := object["__typename"].(string)
+	if !ok {
+		return "", false
+	}
+	fields, ok := c.keyFields[typename]
+	if !ok {
+		fields = []string{"id"}
+	}
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	key := make(map[string]interface{}, len(fields))
+	for _, f := range fields {
+		value, ok := object[f]
+		if !ok || value == nil {
+			return "", false
+		}
+		key[f] = value
+	}
+	if len(fields) == 1 {
+		return fmt.Sprintf("%s:%v", typename, key[fields[0]]), true
+	}
+	b, err := json.Marshal(key)
+	if err != nil {
+		return "", false
+	}
+	return typename + ":" + string(b), true
+}
+
+// isKeyField tells whether a field identifies the objects of some type
+func (c *Cache) isKeyField(name string) bool {
+	if name == "id" || name == "__typename" {
+		return true
+	}
+	for _, fields := range c.keyFields {
+		for _, f := range fields {
+			if f == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// applies tells whether a fragment on typeCondition applies to an object of type typename,
+// which is empty when unknown, and whether the cache knows: a condition that is neither
+// given possible types nor the type of an object seen may be an interface or a union.
+func (c *Cache) applies(typeCondition, typename string) (applies, known bool) {
+	if typeCondition == "" || typename == "" || typeCondition == typename {
+		return true, true
+	}
+	if possible, ok := c.possibleTypes[typeCondition]; ok {
+		return possible[typename], true
+	}
+	return false, c.objectTypes[typeCondition]
+}
+
+// cachedOperation returns the operation at the root of the selection, with its fragment
+// spreads inlined
+func cachedOperation(s *Selection) (*ast.OperationDefinition, error) {
+	if _, ok := s.Root().node.(*ast.OperationDefinition); !ok {
+		return nil, fmt.Errorf("fluentgraphql: cannot cache the response of a %s", s.Root().node.GetKind())
+	}
+	inlined, err := s.Root().InlineFragments()
+	if err != nil {
+		return nil, err
+	}
+	return inlined.node.(*ast.OperationDefinition), nil
+}
+
+// cachedQuery returns the query at the root of the selection, with its fragment spreads inlined
+func cachedQuery(s *Selection) (*ast.OperationDefinition, error) {
+	op, err := cachedOperation(s)
+	if err != nil {
+		return nil, err
+	}
+	if op.Operation != ast.OperationTypeQuery {
+		return nil, fmt.Errorf("fluentgraphql: cannot answer a %s from the cache", op.Operation)
+	}
+	return op, nil
+}
+
+// cacheWalker walks the selection sets of an operation along records of the cache
+type cacheWalker struct {
+	cache     *Cache
+	variables map[string]interface{}
+}
+
+// write stores the fields of data selected by set in record
+func (w *cacheWalker) write(set *ast.SelectionSet, record, data map[string]interface{}) {
+	if set == nil {
+		return
+	}
+	typename, _ := data["__typename"].(string)
+	if typename != "" {
+		w.cache.objectTypes[typename] = true
+	}
+	for _, selection := range set.Selections {
+		switch n := selection.(type) {
+		case *ast.Field:
+			if w.skipped(n.Directives) {
+				continue
+			}
+			value, ok := data[responseKey(n)]
+			if !ok {
+				continue
+			}
+			key := w.storageKey(n)
+			record[key] = w.normalize(n.SelectionSet, value, record[key])
+		case *ast.InlineFragment:
+			if w.skipped(n.Directives) {
+				continue
+			}
+			// the fields of fragments that may apply are stored when the response has them
+			if applies, known := w.cache.applies(typeConditionName(n.TypeCondition), typename); applies || !known {
+				w.write(n.SelectionSet, record, data)
+			}
+		}
+	}
+}
+
+// normalize returns the value to store for a field, replacing entities with references
+// to their records. existing is the value stored so far, which embedded objects are merged with.
+func (w *cacheWalker) normalize(set *ast.SelectionSet, value, existing interface{}) interface{} {
+	if set == nil {
+		return value
+	}
+	switch v := value.(type) {
+	case []interface{}:
+		// items aren't merged with those they replace, which may be other objects
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, w.normalize(set, item, nil))
+		}
+		return list
+	case map[string]interface{}:
+		if key, ok := w.cache.entityKey(v); ok {
+			w.write(set, w.cache.record(key), v)
+			return cacheRef(key)
+		}
+		if ref, ok := existing.(cacheRef); ok {
+			// objects selected without their key fields are the entity they replace
+			w.write(set, w.cache.record(string(ref)), v)
+			return ref
+		}
+		embedded, ok := existing.(map[string]interface{})
+		if !ok {
+			embedded = make(map[string]interface{})
+		}
+		w.write(set, embedded, v)
+		return embedded
+	}
+	return value
+}
+
+// read returns the fields selected by set in record, and whether it holds them all
+func (w *cacheWalker) read(set *ast.SelectionSet, record map[string]interface{}) (map[string]interface{}, bool) {
+	result := make(map[string]interface{})
+	complete := true
+	typename, _ := record["__typename"].(string)
+	for _, selection := range set.Selections {
+		switch n := selection.(type) {
+		case *ast.Field:
+			if w.skipped(n.Directives) {
+				continue
+			}
+			stored, ok := record[w.storageKey(n)]
+			if !ok {
+				complete = false
+				continue
+			}
+			value, done := w.denormalize(n.SelectionSet, stored)
+			complete = complete && done
+			result[responseKey(n)] = mergeValues(result[responseKey(n)], value)
+		case *ast.InlineFragment:
+			if w.skipped(n.Directives) {
+				continue
+			}
+			applies, known := w.cache.applies(typeConditionName(n.TypeCondition), typename)
+			if !applies && known {
+				continue
+			}
+			fields, done := w.read(n.SelectionSet, record)
+			if !applies && !done {
+				// the fragment may apply to the object, without its fields stored
+				complete = false
+				continue
+			}
+			complete = complete && done
+			for key, value := range fields {
+				result[key] = mergeValues(result[key], value)
+			}
+		}
+	}
+	return result, complete
+}
+
+// denormalize returns the value of a field as found in a response, following references
+func (w *cacheWalker) denormalize(set *ast.SelectionSet, stored interface{}) (interface{}, bool) {
+	if set == nil {
+		return stored, true
+	}
+	switch v := stored.(type) {
+	case cacheRef:
+		record, ok := w.cache.records[string(v)]
+		if !ok {
+			return nil, false
+		}
+		return w.read(set, record)
+	case map[string]interface{}:
+		return w.read(set, v)
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+		complete := true
+		for _, item := range v {
+			value, done := w.denormalize(set, item)
+			complete = complete && done
+			list = append(list, value)
+		}
+		return list, complete
+	}
+	return stored, true
+}
+
+// prune returns the selections of set that some of the records are missing, or nil
+func (w *cacheWalker) prune(set *ast.SelectionSet, records []map[string]interface{}) *ast.SelectionSet {
+	var kept []ast.Selection
+	for _, selection := range set.Selections {
+		switch n := selection.(type) {
+		case *ast.Field:
+			if w.skipped(n.Directives) {
+				continue
+			}
+			key := w.storageKey(n)
+			var objects []map[string]interface{}
+			missing := false
+			for _, record := range records {
+				stored, ok := record[key]
+				if !ok {
+					missing = true
+					break
+				}
+				if objects, ok = w.objects(stored, objects); !ok {
+					missing = true
+					break
+				}
+			}
+			if missing {
+				kept = append(kept, n)
+				continue
+			}
+			if n.SelectionSet == nil {
+				continue
+			}
+			if sub := w.prune(n.SelectionSet, objects); sub != nil {
+				copied := *n
+				copied.SelectionSet = w.withKeyFields(sub, n.SelectionSet)
+				kept = append(kept, &copied)
+			}
+		case *ast.InlineFragment:
+			if w.skipped(n.Directives) {
+				continue
+			}
+			var matching []map[string]interface{}
+			for _, record := range records {
+				typename, _ := record["__typename"].(string)
+				if applies, known := w.cache.applies(typeConditionName(n.TypeCondition), typename); applies || !known {
+					matching = append(matching, record)
+				}
+			}
+			if sub := w.prune(n.SelectionSet, matching); sub != nil {
+				copied := *n
+				copied.SelectionSet = sub
+				kept = append(kept, &copied)
+			}
+		}
+	}
+	if len(kept) == 0 {
+		return nil
+	}
+	return ast.NewSelectionSet(&ast.SelectionSet{Selections: kept})
+}
+
+// objects appends the objects of a stored value to objects, following references, and
+// reports false when a reference leads to no record
+func (w *cacheWalker) objects(stored interface{}, objects []map[string]interface{}) ([]map[string]interface{}, bool) {
+	switch v := stored.(type) {
+	case cacheRef:
+		record, ok := w.cache.records[string(v)]
+		if !ok {
+			return objects, false
+		}
+		return append(objects, record), true
+	case map[string]interface{}:
+		return append(objects, v), true
+	case []interface{}:
+		for _, item := range v {
+			var ok bool
+			if objects, ok = w.objects(item, objects); !ok {
+				return objects, false
+			}
+		}
+	}
+	return objects, true
+}
+
+// withKeyFields adds to a pruned selection set the __typename and the key fields of the
+// original one, which identify the objects the missing fields belong to
+func (w *cacheWalker) withKeyFields(pruned, original *ast.SelectionSet) *ast.SelectionSet {
+	selected := make(map[string]bool)
+	for _, selection := range pruned.Selections {
+		if f, ok := selection.(*ast.Field); ok {
+			selected[responseKey(f)] = true
+		}
+	}
+	var keys []ast.Selection
+	if !selected["__typename"] {
+		keys = append(keys, newField("__typename"))
+		selected["__typename"] = true
+	}
+	for _, selection := range original.Selections {
+		f, ok := selection.(*ast.Field)
+		if ok && f.SelectionSet == nil && len(f.Arguments) == 0 && !selected[responseKey(f)] && w.cache.isKeyField(f.Name.Value) {
+			keys = append(keys, f)
+			selected[responseKey(f)] = true
+		}
+	}
+	return ast.NewSelectionSet(&ast.SelectionSet{Selections: append(keys, pruned.Selections...)})
+}
+
+// storageKey returns the key a field is stored at in a record: its name, followed by its
+// arguments as JSON if it has any
+func (w *cacheWalker) storageKey(f *ast.Field) string {
+	if len(f.Arguments) == 0 {
+		return f.Name.Value
+	}
+	args := make(map[string]interface{}, len(f.Arguments))
+	for _, arg := range f.Arguments {
+		args[arg.Name.Value] = goValue(arg.Value, w.variables)
+	}
+	b, err := json.Marshal(args)
+	if err != nil {
+		return f.Name.Value
+	}
+	return f.Name.Value + "(" + string(b) + ")"
+}
+
+// skipped tells whether @skip or @include leave a selection out
+func (w *cacheWalker) skipped(directives []*ast.Directive) bool {
+	for _, d := range directives {
+		for _, arg := range d.Arguments {
+			if arg.Name.Value != "if" {
+				continue
+			}
+			condition, _ := goValue(arg.Value, w.variables).(bool)
+			switch d.Name.Value {
+			case "skip":
+				if condition {
+					return true
+				}
+			case "include":
+				if !condition {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// mergeValues merges the values of the same response key selected by several selections,
+// such as the fragments of a cached query or the payloads of an incremental response
+func mergeValues(dst, src interface{}) interface{} {
+	switch s := src.(type) {
+	case map[string]interface{}:
+		if d, ok := dst.(map[string]interface{}); ok {
+			for key, value := range s {
+				d[key] = mergeValues(d[key], value)
+			}
+			return d
+		}
+	case []interface{}:
+		if d, ok := dst.([]interface{}); ok && len(d) == len(s) {
+			for i := range s {
+				d[i] = mergeValues(d[i], s[i])
+			}
+			return d
+		}
+	}
+	return src
+}
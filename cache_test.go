@@ -0,0 +1,350 @@
+package fluentgraphql
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestCache(t *testing.T) {
+	cache := NewCache(WithKeyFields("Repository", "owner", "name"), WithKeyFields("License"), WithPossibleTypes("Node", "Issue", "PullRequest"))
+	written := []struct {
+		query     string
+		variables map[string]interface{}
+		data      string
+	}{
+		{
+			query: `query ($owner: String!) {
+				viewer { __typename id login }
+				repository(owner: $owner, name: "fluentgraphql") {
+					__typename owner name stargazerCount
+					license { __typename key }
+					issues(first: 2) { __typename id title }
+				}
+			}`,
+			variables: map[string]interface{}{"owner": "mergestat"},
+			data: `{
+				"viewer": {"__typename": "User", "id": "U1", "login": "octocat"},
+				"repository": {
+					"__typename": "Repository", "owner": "mergestat", "name": "fluentgraphql", "stargazerCount": 10,
+					"license": {"__typename": "License", "key": "mit"},
+					"issues": [{"__typename": "Issue", "id": "I1", "title": "Bug"}, {"__typename": "Issue", "id": "I2", "title": "Feature"}]
+				}
+			}`,
+		},
+		{
+			query: `mutation { closeIssue(id: "I1") { issue { __typename id title state } } }`,
+			data:  `{"closeIssue": {"issue": {"__typename": "Issue", "id": "I1", "title": "Fixed bug", "state": "CLOSED"}}}`,
+		},
+		{
+			query: `{ node(id: "I2") { __typename ... on Issue { id state } } }`,
+			data:  `{"node": {"__typename": "Issue", "id": "I2", "state": "OPEN"}}`,
+		},
+		{
+			query: `{ node(id: "I1") { __typename id ... on Closable { closed } } }`,
+			data:  `{"node": {"__typename": "Issue", "id": "I1", "closed": true}}`,
+		},
+	}
+	for _, w := range written {
+		s, err := Parse(w.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := cache.Write(s, w.variables, json.RawMessage(w.data)); err != nil {
+			t.Fatalf("unexpected error writing %s: %v", w.query, err)
+		}
+	}
+
+	type test struct {
+		name      string
+		query     string
+		variables map[string]interface{}
+		// data is the data read, and complete whether it is expected to be complete
+		data     string
+		complete bool
+		// missing is the reduced query, empty when nothing is missing
+		missing string
+	}
+
+	var tests = []test{
+		{
+			name:     "Complete",
+			query:    `{ me: viewer { login } }`,
+			data:     `{"me":{"login":"octocat"}}`,
+			complete: true,
+		},
+		{
+			name:      "Entities",
+			query:     `query Issues($owner: String!) { repository(owner: $owner, name: "fluentgraphql") { issues(first: 2) { id title state } } }`,
+			variables: map[string]interface{}{"owner": "mergestat"},
+			data:      `{"repository":{"issues":[{"id":"I1","state":"CLOSED","title":"Fixed bug"},{"id":"I2","state":"OPEN","title":"Feature"}]}}`,
+			complete:  true,
+		},
+		{
+			name:     "Fragments",
+			query:    `{ node(id: "I2") { ... on Node { ... on Issue { title } } ... on PullRequest { merged } } }`,
+			data:     `{"node":{"title":"Feature"}}`,
+			complete: true,
+		},
+		{
+			name:     "UnknownTypeCondition",
+			query:    `{ node(id: "I1") { ... on Closable { closed } } }`,
+			data:     `{"node":{"closed":true}}`,
+			complete: true,
+		},
+		{
+			name:  "UnknownTypeConditionMissing",
+			query: `{ node(id: "I2") { ... on Closable { closed } } }`,
+			data:  `{"node":{}}`,
+			missing: `{
+  node(id: "I2") {
+    __typename
+    ... on Closable {
+      closed
+    }
+  }
+}`,
+		},
+		{
+			name:      "OtherArguments",
+			query:     `query ($owner: String!) { repository(owner: $owner, name: "fluentgraphql") { name } }`,
+			variables: map[string]interface{}{"owner": "askgitdev"},
+			data:      `{}`,
+			missing: `query ($owner: String!) {
+  repository(owner: $owner, name: "fluentgraphql") {
+    name
+  }
+}`,
+		},
+		{
+			name: "Partial",
+			query: `query Repo($owner: String!, $first: Int) {
+				viewer { login }
+				repository(owner: $owner, name: "fluentgraphql") {
+					stargazerCount
+					forkCount
+					license { key name }
+					issues(first: 2) { title author { login } }
+				}
+				rateLimit(first: $first) { remaining }
+			}`,
+			variables: map[string]interface{}{"owner": "mergestat", "first": 1},
+			data:      `{"repository":{"issues":[{"title":"Fixed bug"},{"title":"Feature"}],"license":{"key":"mit"},"stargazerCount":10},"viewer":{"login":"octocat"}}`,
+			missing: `query Repo($owner: String!, $first: Int) {
+  repository(owner: $owner, name: "fluentgraphql") {
+    __typename
+    forkCount
+    license {
+      __typename
+      name
+    }
+    issues(first: 2) {
+      __typename
+      author {
+        login
+      }
+    }
+  }
+  rateLimit(first: $first) {
+    remaining
+  }
+}`,
+		},
+		{
+			name:      "UnusedVariables",
+			query:     `query ($owner: String!, $skip: Boolean!) { viewer { id login email } repository(owner: $owner, name: "fluentgraphql") @skip(if: $skip) { name } }`,
+			variables: map[string]interface{}{"owner": "mergestat", "skip": true},
+			data:      `{"viewer":{"id":"U1","login":"octocat"}}`,
+			missing: `{
+  viewer {
+    __typename
+    id
+    email
+  }
+}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			s, err := Parse(test.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, complete, err := cache.Read(s, test.variables)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(test.data, string(data)); diff != "" {
+				t.Errorf("unexpected data (-want +got):\n%s", diff)
+			}
+			if complete != test.complete {
+				t.Errorf("expected complete to be %t", test.complete)
+			}
+
+			missing, err := cache.Missing(s, test.variables)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			var got string
+			if missing != nil {
+				got = missing.String()
+			}
+			if diff := cmp.Diff(test.missing, got); diff != "" {
+				t.Errorf("unexpected missing fields (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestCacheErrors(t *testing.T) {
+	cache := NewCache()
+	mutation := NewMutation().Scalar("addStar")
+	if _, _, err := cache.Read(mutation, nil); err == nil {
+		t.Error("expected an error reading a mutation")
+	}
+	if err := cache.Write(NewQuery().Scalar("viewer"), nil, json.RawMessage(`[]`)); err == nil {
+		t.Error("expected an error writing data that is not an object")
+	}
+	if err := cache.Write(NewQuery().FragmentSpread("missing"), nil, json.RawMessage(`{}`)); err == nil {
+		t.Error("expected an error writing a query spreading an undefined fragment")
+	}
+}
+
+func TestCacheMissingTypenames(t *testing.T) {
+	schema, err := NewSchema().
+		Type("Query").Field("viewer", "User").Field("organization", "Organization").Schema().
+		Type("User").Field("id", "ID!").Field("login", "String!").Field("email", "String").Schema().
+		Type("Organization").Field("id", "ID!").Field("name", "String!").Schema().
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache := NewCache()
+	written := NewQuery(WithTypenames(WithSchemaKeyField(schema, "id"))).Selection("viewer").Scalar("login").Root()
+	if err := cache.Write(written, nil, json.RawMessage(`{"viewer":{"__typename":"User","id":"U1","login":"octocat"}}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	q := NewQuery(WithTypenames(WithSchemaKeyField(schema, "id"))).Selection("viewer").Scalar("login").Scalar("email").Root()
+	missing, err := cache.Missing(q, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// the selections added to the reduced query get typenames and key fields too
+	missing.Selection("organization").Scalar("name")
+	expected := `{
+  viewer {
+    __typename
+    id
+    email
+  }
+  organization {
+    __typename
+    id
+    name
+  }
+}`
+	if diff := cmp.Diff(expected, missing.String()); diff != "" {
+		t.Errorf("unexpected missing fields (-want +got):\n%s", diff)
+	}
+}
+
+func TestCacheFirst(t *testing.T) {
+	var sent []string
+	executor := Chain(
+		ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			sent = append(sent, req.Selection.Root().String())
+			responses := map[string]string{
+				"viewer": `{"viewer":{"__typename":"User","id":"U1","login":"octocat"}}`,
+				"email":  `{"viewer":{"__typename":"User","email":"octocat@github.com"}}`,
+				"follow": `{"followUser":{"user":{"__typename":"User","id":"U1","followers":1}}}`,
+			}
+			key := "viewer"
+			switch {
+			case isMutation(req.Selection):
+				key = "follow"
+			case strings.Contains(req.Selection.String(), "email"):
+				key = "email"
+			}
+			return &Response{Data: json.RawMessage(responses[key])}, nil
+		}),
+		CacheFirst(NewCache()),
+	)
+
+	var data []string
+	for _, query := range []string{
+		`{ viewer { __typename id login } }`,
+		`{ viewer { login } }`,
+		`{ viewer { login email } }`,
+		`mutation { followUser(login: "octocat") { user { __typename id followers } } }`,
+		`{ viewer { login email followers } }`,
+	} {
+		s, err := Parse(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := executor.Execute(context.Background(), NewRequest(s, nil))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		data = append(data, string(res.Data))
+	}
+
+	expectedData := []string{
+		`{"viewer":{"__typename":"User","id":"U1","login":"octocat"}}`,
+		`{"viewer":{"login":"octocat"}}`,
+		`{"viewer":{"email":"octocat@github.com","login":"octocat"}}`,
+		`{"followUser":{"user":{"__typename":"User","id":"U1","followers":1}}}`,
+		`{"viewer":{"email":"octocat@github.com","followers":1,"login":"octocat"}}`,
+	}
+	if diff := cmp.Diff(expectedData, data); diff != "" {
+		t.Errorf("unexpected data (-want +got):\n%s", diff)
+	}
+	expectedSent := []string{
+		"{\n  viewer {\n    __typename\n    id\n    login\n  }\n}",
+		"{\n  viewer {\n    __typename\n    email\n  }\n}",
+		"mutation {\n  followUser(login: \"octocat\") {\n    user {\n      __typename\n      id\n      followers\n    }\n  }\n}",
+	}
+	if diff := cmp.Diff(expectedSent, sent); diff != "" {
+		t.Errorf("unexpected requests (-want +got):\n%s", diff)
+	}
+}
+
+func TestCacheFirstErrors(t *testing.T) {
+	cache := NewCache()
+	written, err := Parse(`{ viewer { __typename id login } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cache.Write(written, nil, json.RawMessage(`{"viewer":{"__typename":"User","id":"U1","login":"octocat"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	executor := Chain(
+		ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			return &Response{
+				Data:   json.RawMessage(`{"viewer":{"__typename":"User","email":null}}`),
+				Errors: ResponseErrors{{Message: "forbidden", Path: []interface{}{"viewer", "email"}}},
+			}, nil
+		}),
+		CacheFirst(cache),
+	)
+
+	s, err := Parse(`{ viewer { login email } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := executor.Execute(context.Background(), NewRequest(s, nil))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(`{"viewer":{"email":null,"login":"octocat"}}`, string(res.Data)); diff != "" {
+		t.Errorf("unexpected data (-want +got):\n%s", diff)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Message != "forbidden" {
+		t.Errorf("expected the error of the missing field, got %v", res.Errors)
+	}
+}
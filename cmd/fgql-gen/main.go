@@ -0,0 +1,79 @@
+// Command fgql-gen generates typed fluentgraphql builders from a GraphQL schema.
+//
+//	fgql-gen -schema schema.graphql -package github -o github/builders.go
+//
+// Given a document of operations, it instead generates response types and Execute
+// functions for each of its operations.
+//
+//	fgql-gen -schema schema.graphql -operations queries.graphql -package queries -o queries/queries.go
+//
+// The schema is read as SDL, or as the JSON result of an introspection query.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/mergestat/fluentgraphql/codegen"
+)
+
+func main() {
+	schemaPath := flag.String("schema", "", "path to the schema, as SDL or introspection JSON (- for stdin)")
+	packageName := flag.String("package", "", "name of the generated package")
+	operations := flag.String("operations", "", "path to a document of operations to generate response types for")
+	output := flag.String("o", "", "path of the generated file (defaults to stdout)")
+	flag.Parse()
+
+	if *schemaPath == "" || *packageName == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if err := run(*schemaPath, *operations, *packageName, *output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(schemaPath, operations, packageName, output string) error {
+	var r io.Reader = os.Stdin
+	if schemaPath != "-" {
+		f, err := os.Open(schemaPath)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	schema, err := codegen.LoadSchema(r)
+	if err != nil {
+		return err
+	}
+
+	var src []byte
+	if operations != "" {
+		document, err := ioutil.ReadFile(operations)
+		if err != nil {
+			return err
+		}
+		doc, err := codegen.ParseOperations(string(document))
+		if err != nil {
+			return err
+		}
+		if src, err = codegen.GenerateOperations(schema, doc, packageName); err != nil {
+			return err
+		}
+	} else if src, err = codegen.GenerateBuilders(schema, packageName); err != nil {
+		return err
+	}
+
+	if output == "" {
+		_, err = os.Stdout.Write(src)
+		return err
+	}
+	return ioutil.WriteFile(output, src, 0644)
+}
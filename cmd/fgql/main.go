@@ -0,0 +1,322 @@
+// Command fgql builds, formats and validates GraphQL documents.
+//
+//	fgql fmt [-minify] [-w] [file ...]
+//	fgql validate -schema schema.graphql [file ...]
+//	fgql build [-spec spec.json] [-minify]
+//	fgql hash [-operation name] [file ...]
+//	fgql vars [-schema schema.graphql] [-operation name] [file]
+//
+// Documents are read from the files given, or from stdin when there are none or a file
+// is -, so that fgql can be used in shell pipelines. Schemas are read as SDL, or as the
+// JSON result of an introspection query.
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/printer"
+	fgql "github.com/mergestat/fluentgraphql"
+	"github.com/mergestat/fluentgraphql/codegen"
+)
+
+const usage = `Usage: fgql <command> [flags] [file ...]
+
+Commands:
+  fmt       pretty-print or minify documents
+  validate  validate documents against a schema
+  build     build an operation from a JSON or YAML spec
+  hash      print the SHA-256 of the minified operation of documents
+  vars      print a JSON skeleton of the variables of an operation
+
+Run fgql <command> -h for the flags of a command.
+`
+
+var commands = map[string]func(args []string) error{
+	"fmt":      formatCommand,
+	"validate": validateCommand,
+	"build":    buildCommand,
+	"hash":     hashCommand,
+	"vars":     varsCommand,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	command, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "fgql: unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+	if err := command(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// input is a document read from a file, or from stdin when its name is -
+type input struct {
+	name string
+	body []byte
+}
+
+// readInputs reads the files of paths, or stdin when there are none
+func readInputs(paths []string) ([]input, error) {
+	if len(paths) == 0 {
+		paths = []string{"-"}
+	}
+	inputs := make([]input, 0, len(paths))
+	for _, path := range paths {
+		var body []byte
+		var err error
+		if path == "-" {
+			body, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			body, err = ioutil.ReadFile(path)
+		}
+		if err != nil {
+			return nil, err
+		}
+		inputs = append(inputs, input{name: path, body: body})
+	}
+	return inputs, nil
+}
+
+func (in input) parse() (*ast.Document, error) {
+	doc, err := fgql.ParseDocument(string(in.body))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", in.name, err)
+	}
+	return doc, nil
+}
+
+// loadSchema reads a schema from a file, or from stdin when path is -
+func loadSchema(path string) (*codegen.Schema, error) {
+	f := os.Stdin
+	if path != "-" {
+		var err error
+		if f, err = os.Open(path); err != nil {
+			return nil, err
+		}
+		defer f.Close()
+	}
+	return codegen.LoadSchema(f)
+}
+
+// operation wraps the operation of a document named name, or its only operation when
+// name is empty, along with the fragment definitions of the document
+func operation(doc *ast.Document, name string) (*fgql.Selection, error) {
+	var op *ast.OperationDefinition
+	var count int
+	definitions := []ast.Node{nil}
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *ast.OperationDefinition:
+			count++
+			if name == "" || (def.Name != nil && def.Name.Value == name) {
+				op = def
+			}
+		case *ast.FragmentDefinition:
+			definitions = append(definitions, def)
+		}
+	}
+	switch {
+	case name == "" && count > 1:
+		return nil, fmt.Errorf("document has %d operations, pick one with -operation", count)
+	case op == nil && name != "":
+		return nil, fmt.Errorf("no operation named %s", name)
+	case op == nil:
+		return nil, errors.New("no operation in document")
+	}
+	definitions[0] = op
+	s, err := fgql.FromAST(ast.NewDocument(&ast.Document{Definitions: definitions}))
+	if err != nil {
+		return nil, err
+	}
+	// printing doesn't report fragments sharing a name
+	if _, err := s.Document(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func formatCommand(args []string) error {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	minify := flags.Bool("minify", false, "print documents on a single line, without ignored whitespace, commas and comments")
+	write := flags.Bool("w", false, "write the result to the files instead of stdout")
+	flags.Parse(args)
+
+	inputs, err := readInputs(flags.Args())
+	if err != nil {
+		return err
+	}
+	for _, in := range inputs {
+		doc, err := in.parse()
+		if err != nil {
+			return err
+		}
+		var formatted string
+		if *minify {
+			if formatted, err = fgql.Minify(string(in.body)); err != nil {
+				return err
+			}
+			formatted += "\n"
+		} else {
+			formatted = printer.Print(doc).(string)
+		}
+
+		if *write && in.name != "-" {
+			if err := ioutil.WriteFile(in.name, []byte(formatted), 0644); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := os.Stdout.WriteString(formatted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateCommand(args []string) error {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	schemaPath := flags.String("schema", "", "path to the schema, as SDL or introspection JSON (- for stdin)")
+	flags.Parse(args)
+	if *schemaPath == "" {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	s, err := loadSchema(*schemaPath)
+	if err != nil {
+		return err
+	}
+	schema, err := fgql.BuildSchema(s.Document)
+	if err != nil {
+		return err
+	}
+
+	inputs, err := readInputs(flags.Args())
+	if err != nil {
+		return err
+	}
+	var problems []string
+	for _, in := range inputs {
+		doc, err := in.parse()
+		if err != nil {
+			return err
+		}
+		// every operation of the document is validated, where Validate would only check one
+		result := graphql.ValidateDocument(&schema, doc, nil)
+		for _, e := range result.Errors {
+			location := in.name
+			if len(e.Locations) > 0 {
+				location = fmt.Sprintf("%s:%d:%d", in.name, e.Locations[0].Line, e.Locations[0].Column)
+			}
+			problems = append(problems, location+": "+e.Message)
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "\n"))
+	}
+	return nil
+}
+
+func buildCommand(args []string) error {
+	flags := flag.NewFlagSet("build", flag.ExitOnError)
+	specPath := flags.String("spec", "-", "path to the spec, as JSON or YAML (- for stdin)")
+	minify := flags.Bool("minify", false, "print the operation on a single line")
+	flags.Parse(args)
+
+	inputs, err := readInputs([]string{*specPath})
+	if err != nil {
+		return err
+	}
+	s, err := fgql.FromSpec(bytes.NewReader(inputs[0].body))
+	if err != nil {
+		return err
+	}
+	if _, err := s.Document(); err != nil {
+		return err
+	}
+	if *minify {
+		fmt.Println(s.Minified())
+	} else {
+		fmt.Println(s.String())
+	}
+	return nil
+}
+
+func hashCommand(args []string) error {
+	flags := flag.NewFlagSet("hash", flag.ExitOnError)
+	operationName := flags.String("operation", "", "name of the operation to hash, when documents have several")
+	flags.Parse(args)
+
+	inputs, err := readInputs(flags.Args())
+	if err != nil {
+		return err
+	}
+	for _, in := range inputs {
+		doc, err := in.parse()
+		if err != nil {
+			return err
+		}
+		s, err := operation(doc, *operationName)
+		if err != nil {
+			return fmt.Errorf("%s: %w", in.name, err)
+		}
+		if len(inputs) == 1 {
+			fmt.Println(s.Hash())
+		} else {
+			fmt.Printf("%s  %s\n", s.Hash(), in.name)
+		}
+	}
+	return nil
+}
+
+func varsCommand(args []string) error {
+	flags := flag.NewFlagSet("vars", flag.ExitOnError)
+	schemaPath := flags.String("schema", "", "path to a schema, to fill in enums and input objects (- for stdin)")
+	operationName := flags.String("operation", "", "name of the operation, when the document has several")
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+
+	var schema *codegen.Schema
+	if *schemaPath != "" {
+		var err error
+		if schema, err = loadSchema(*schemaPath); err != nil {
+			return err
+		}
+	}
+	inputs, err := readInputs(flags.Args())
+	if err != nil {
+		return err
+	}
+	doc, err := inputs[0].parse()
+	if err != nil {
+		return err
+	}
+	s, err := operation(doc, *operationName)
+	if err != nil {
+		return err
+	}
+
+	b, err := variablesSkeleton(s.AST().(*ast.OperationDefinition), schema)
+	if err != nil {
+		return err
+	}
+	fmt.Println(string(b))
+	return nil
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/mergestat/fluentgraphql/codegen"
+)
+
+// captureStdout returns what f writes to stdout
+func captureStdout(t *testing.T, f func() error) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	err = f()
+	os.Stdout = stdout
+	w.Close()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// writeFile writes a file in a temporary directory, returning its path
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestFormatCommand(t *testing.T) {
+	path := writeFile(t, "query.graphql", "# requête\nquery Hero($episode: Episode = JEDI) {\n  hero(episode: $episode) { name, friends { name } }\n}\n")
+
+	pretty := captureStdout(t, func() error { return formatCommand([]string{path}) })
+	wanted := "query Hero($episode: Episode = JEDI) {\n  hero(episode: $episode) {\n    name\n    friends {\n      name\n    }\n  }\n}\n"
+	if diff := cmp.Diff(wanted, pretty); diff != "" {
+		t.Errorf("unexpected output (-want +got):\n%s", diff)
+	}
+
+	if err := formatCommand([]string{"-minify", "-w", path}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wanted = "query Hero($episode:Episode=JEDI){hero(episode:$episode){name friends{name}}}\n"
+	if diff := cmp.Diff(wanted, string(b)); diff != "" {
+		t.Errorf("unexpected file (-want +got):\n%s", diff)
+	}
+}
+
+func TestHashCommand(t *testing.T) {
+	path := writeFile(t, "queries.graphql", `query A { a } query B { repository(owner: "mergestat") { name } }`)
+
+	out := captureStdout(t, func() error { return hashCommand([]string{"-operation", "B", path}) })
+	if diff := cmp.Diff("5a1577fc2f0632ba22bf98ba5067d718b6376cd329f1cd69b4aa1d21ebfdca39\n", out); diff != "" {
+		t.Errorf("unexpected output (-want +got):\n%s", diff)
+	}
+	if err := hashCommand([]string{path}); err == nil || !strings.Contains(err.Error(), "2 operations") {
+		t.Errorf("expected an error for a document with several operations, got %v", err)
+	}
+}
+
+func TestOperation(t *testing.T) {
+	in := input{name: "doc.graphql", body: []byte(`query A { ...f } query B { b } fragment f on Query { a }`)}
+	doc, err := in.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := operation(doc, "A")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff("query A{...f}fragment f on Query{a}", s.Minified()); diff != "" {
+		t.Errorf("unexpected operation (-want +got):\n%s", diff)
+	}
+	for name, wanted := range map[string]string{"": "2 operations", "C": "no operation named C"} {
+		if _, err := operation(doc, name); err == nil || !strings.Contains(err.Error(), wanted) {
+			t.Errorf("expected an error containing %q, got %v", wanted, err)
+		}
+	}
+}
+
+func TestVariablesSkeleton(t *testing.T) {
+	schema, err := codegen.LoadSchema(strings.NewReader(`
+		type Query { search(filter: Filter, first: Int, states: [State!]): [String] }
+		enum State { OPEN CLOSED }
+		input Filter { query: String! states: [State!] = [OPEN] next: Filter exact: Boolean }
+	`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	in := input{name: "query.graphql", body: []byte(`query ($filter: Filter!, $first: Int = 10, $states: [State!], $sort: Sort = { field: NAME, descending: true }, $id: ID) {
+		search(filter: $filter, first: $first, states: $states)
+	}`)}
+	doc, err := in.parse()
+	if err != nil {
+		t.Fatal(err)
+	}
+	op := doc.Definitions[0].(*ast.OperationDefinition)
+
+	b, err := variablesSkeleton(op, schema)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wanted := `{
+  "filter": {
+    "query": "",
+    "states": [
+      "OPEN"
+    ],
+    "next": null,
+    "exact": false
+  },
+  "first": 10,
+  "states": [
+    "OPEN"
+  ],
+  "sort": {
+    "descending": true,
+    "field": "NAME"
+  },
+  "id": ""
+}`
+	if diff := cmp.Diff(wanted, string(b)); diff != "" {
+		t.Errorf("unexpected skeleton (-want +got):\n%s", diff)
+	}
+
+	b, err = variablesSkeleton(op, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(string(b), `"filter": null`) || !strings.Contains(string(b), `"states": [
+    null
+  ]`) {
+		t.Errorf("expected types unknown without a schema to be null, got %s", b)
+	}
+}
+
+func TestValidateCommand(t *testing.T) {
+	schema := writeFile(t, "schema.graphql", `type Query { hero: Character } type Character { name: String! }`)
+	valid := writeFile(t, "valid.graphql", "# héros\n{ hero { name } }")
+	invalid := writeFile(t, "invalid.graphql", "# héros\n{ hero { age } }")
+
+	if err := validateCommand([]string{"-schema", schema, valid}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	err := validateCommand([]string{"-schema", schema, valid, invalid})
+	if err == nil || !strings.HasPrefix(err.Error(), invalid+":2:10: ") {
+		t.Errorf("expected an error located in %s, got %v", invalid, err)
+	}
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/graphql-go/graphql/language/ast"
+	fgql "github.com/mergestat/fluentgraphql"
+	"github.com/mergestat/fluentgraphql/codegen"
+)
+
+// object is a JSON object that keeps the order of its members
+type object []member
+
+type member struct {
+	key   string
+	value interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var b bytes.Buffer
+	b.WriteByte('{')
+	for i, m := range o {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		key, err := json.Marshal(m.key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := json.Marshal(m.value)
+		if err != nil {
+			return nil, err
+		}
+		b.Write(key)
+		b.WriteByte(':')
+		b.Write(value)
+	}
+	b.WriteByte('}')
+	return b.Bytes(), nil
+}
+
+// variablesSkeleton returns the variables of an operation as indented JSON, with their
+// default value, or a placeholder for their type. The schema, which may be nil, fills in
+// enums with their first value and input objects with their fields.
+func variablesSkeleton(op *ast.OperationDefinition, schema *codegen.Schema) ([]byte, error) {
+	variables := make(object, 0, len(op.VariableDefinitions))
+	for _, v := range op.VariableDefinitions {
+		var value interface{}
+		if v.DefaultValue != nil {
+			value = fgql.ValueFromAST(v.DefaultValue).Interface()
+		} else {
+			value = placeholder(v.Type, schema, make(map[string]bool))
+		}
+		variables = append(variables, member{key: v.Variable.Name.Value, value: value})
+	}
+	return json.MarshalIndent(variables, "", "  ")
+}
+
+// placeholder returns the value to fill in for a type, which is null when the type is
+// unknown. seen holds the input objects being filled in, to stop at recursive ones.
+func placeholder(t ast.Type, schema *codegen.Schema, seen map[string]bool) interface{} {
+	switch t := t.(type) {
+	case *ast.NonNull:
+		return placeholder(t.Type, schema, seen)
+	case *ast.List:
+		return []interface{}{placeholder(t.Type, schema, seen)}
+	case *ast.Named:
+		name := t.Name.Value
+		switch name {
+		case "Int", "Float":
+			return 0
+		case "String", "ID":
+			return ""
+		case "Boolean":
+			return false
+		}
+		if schema == nil {
+			return nil
+		}
+		switch def := schema.Type(name).(type) {
+		case *ast.EnumDefinition:
+			if len(def.Values) > 0 {
+				return def.Values[0].Name.Value
+			}
+		case *ast.InputObjectDefinition:
+			if seen[name] {
+				return nil
+			}
+			seen[name] = true
+			defer delete(seen, name)
+			fields := make(object, 0, len(def.Fields))
+			for _, f := range def.Fields {
+				var value interface{}
+				if f.DefaultValue != nil {
+					value = fgql.ValueFromAST(f.DefaultValue).Interface()
+				} else {
+					value = placeholder(f.Type, schema, seen)
+				}
+				fields = append(fields, member{key: f.Name.Value, value: value})
+			}
+			return fields
+		}
+	}
+	return nil
+}
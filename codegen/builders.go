@@ -0,0 +1,341 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// builders generates typed wrappers over fluentgraphql selections
+type builders struct {
+	schema *Schema
+	buf    bytes.Buffer
+	// types maps GraphQL type names to the Go names generated for them
+	types map[string]string
+	// global hands out the package level identifiers
+	global *names
+}
+
+// GenerateBuilders generates the source of a Go package with typed builders for a schema.
+//
+// Every object, interface and union type gets a wrapper over *fluentgraphql.Selection
+// (which stays embedded for dynamic use) with a method per field, suffixed with Field
+// when it would shadow a method of the selection such as Name or String. Leaf fields return the
+// wrapper they were selected on, composite fields return the wrapper of their type.
+// Required arguments become method parameters, while optional arguments become
+// SelectionOption functions named after the type, field and argument.
+// Enums get a string type with a constant per value, input objects get a struct,
+// and both convert to a fluentgraphql.Value with their Value method.
+// Custom scalars are represented as strings.
+func GenerateBuilders(schema *Schema, packageName string) ([]byte, error) {
+	b := &builders{
+		schema: schema,
+		types:  make(map[string]string),
+		global: newNames("NewQuery", "NewMutation"),
+	}
+
+	for _, name := range schema.TypeNames() {
+		switch schema.Type(name).(type) {
+		case *ast.ObjectDefinition, *ast.InterfaceDefinition, *ast.UnionDefinition, *ast.EnumDefinition, *ast.InputObjectDefinition:
+			b.types[name] = b.global.get(exportedName(name))
+		}
+	}
+
+	b.printf("// Code generated by fgql-gen. DO NOT EDIT.\n\n")
+	b.printf("package %s\n\n", packageName)
+	b.printf("import fgql %q\n\n", "github.com/mergestat/fluentgraphql")
+
+	for _, root := range []struct {
+		operation, constructor string
+	}{
+		{ast.OperationTypeQuery, "NewQuery"},
+		{ast.OperationTypeMutation, "NewMutation"},
+	} {
+		if name := schema.RootType(root.operation); name != "" {
+			b.printf("// %s returns a builder for a new %s\n", root.constructor, root.operation)
+			b.printf("func %s(options ...fgql.OperationOption) %s {\n", root.constructor, b.types[name])
+			b.printf("return %s{fgql.%s(options...)}\n}\n\n", b.types[name], root.constructor)
+		}
+	}
+
+	for _, name := range schema.TypeNames() {
+		var err error
+		switch def := schema.Type(name).(type) {
+		case *ast.ObjectDefinition:
+			err = b.wrapper(name, def.Description, def.Fields, nil)
+		case *ast.InterfaceDefinition:
+			err = b.wrapper(name, def.Description, def.Fields, schema.Implementations(name))
+		case *ast.UnionDefinition:
+			err = b.wrapper(name, def.Description, nil, schema.PossibleTypes(name))
+		case *ast.EnumDefinition:
+			b.enum(def)
+		case *ast.InputObjectDefinition:
+			err = b.input(def)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return format.Source(b.buf.Bytes())
+}
+
+func (b *builders) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&b.buf, format, args...)
+}
+
+// wrapper generates the wrapper of an object, interface or union type
+func (b *builders) wrapper(name string, description *ast.StringValue, fields []*ast.FieldDefinition, possibleTypes []string) error {
+	goName := b.types[name]
+	reserved := []string{"Selection", "Typename"}
+	for method := range selectionMethods {
+		reserved = append(reserved, method)
+	}
+	methods := newNames(reserved...)
+
+	b.printf("// %s builds selections on the %s type\n", goName, name)
+	if description != nil {
+		b.printf("//\n%s", comment(description.Value))
+	}
+	b.printf("type %s struct {\n*fgql.Selection\n}\n\n", goName)
+
+	b.printf("// Typename selects the __typename field\n")
+	b.printf("func (t %s) Typename(options ...fgql.SelectionOption) %s {\n", goName, goName)
+	b.printf("t.Selection.Scalar(%q, options...)\nreturn t\n}\n\n", "__typename")
+
+	for _, f := range fields {
+		if err := b.field(name, methods, f); err != nil {
+			return err
+		}
+	}
+
+	for _, possible := range possibleTypes {
+		method := methods.get("On" + b.types[possible])
+		b.printf("// %s selects fields of the %s type with an inline fragment\n", method, possible)
+		b.printf("func (t %s) %s() %s {\n", goName, method, b.types[possible])
+		b.printf("return %s{t.Selection.InlineFragment(%q)}\n}\n\n", b.types[possible], possible)
+	}
+
+	return nil
+}
+
+// field generates the method selecting a field, along with the options of its optional arguments
+func (b *builders) field(typeName string, methods *names, f *ast.FieldDefinition) error {
+	goType := b.types[typeName]
+	method := methods.get(methodName(f.Name.Value))
+
+	fieldType := namedType(f.Type)
+	if b.schema.Type(fieldType) == nil {
+		return fmt.Errorf("codegen: unknown type %s of field %s.%s", fieldType, typeName, f.Name.Value)
+	}
+
+	var params, args bytes.Buffer
+	paramNames := newNames()
+	for _, arg := range f.Arguments {
+		if _, required := arg.Type.(*ast.NonNull); !required || arg.DefaultValue != nil {
+			if err := b.argumentOption(typeName, f, arg); err != nil {
+				return err
+			}
+			continue
+		}
+		goArgType, err := b.inputType(arg.Type, false)
+		if err != nil {
+			return err
+		}
+		param := paramNames.get(paramName(arg.Name.Value))
+		fmt.Fprintf(&params, "%s %s, ", param, goArgType)
+		fmt.Fprintf(&args, "fgql.NewArgument(%q, %s),\n", arg.Name.Value, b.valueExpr(arg.Type, param, 0))
+	}
+
+	b.printf("// %s selects the %s field\n", method, f.Name.Value)
+	if f.Description != nil {
+		b.printf("//\n%s", comment(f.Description.Value))
+	}
+	if reason, ok := deprecationReason(f.Directives); ok {
+		b.printf("//\n%s", comment("Deprecated: "+reason))
+	}
+
+	returns := goType
+	if !b.schema.IsLeaf(fieldType) {
+		returns = b.types[fieldType]
+	}
+	b.printf("func (t %s) %s(%soptions ...fgql.SelectionOption) %s {\n", goType, method, params.String(), returns)
+	if args.Len() > 0 {
+		b.printf("options = append([]fgql.SelectionOption{fgql.WithArguments(\n%s)}, options...)\n", args.String())
+	}
+	if b.schema.IsLeaf(fieldType) {
+		b.printf("t.Selection.Scalar(%q, options...)\nreturn t\n}\n\n", f.Name.Value)
+	} else {
+		b.printf("return %s{t.Selection.Selection(%q, options...)}\n}\n\n", returns, f.Name.Value)
+	}
+
+	return nil
+}
+
+// argumentOption generates the SelectionOption function for an optional argument of a field
+func (b *builders) argumentOption(typeName string, f *ast.FieldDefinition, arg *ast.InputValueDefinition) error {
+	goArgType, err := b.inputType(arg.Type, false)
+	if err != nil {
+		return err
+	}
+	option := b.global.get(b.types[typeName] + exportedName(f.Name.Value) + exportedName(arg.Name.Value))
+
+	b.printf("// %s is an option for the %s argument of the %s.%s field\n", option, arg.Name.Value, typeName, f.Name.Value)
+	if arg.Description != nil {
+		b.printf("//\n%s", comment(arg.Description.Value))
+	}
+	b.printf("func %s(v %s) fgql.SelectionOption {\n", option, goArgType)
+	b.printf("return fgql.WithArguments(fgql.NewArgument(%q, %s))\n}\n\n", arg.Name.Value, b.valueExpr(arg.Type, "v", 0))
+	return nil
+}
+
+// enum generates the string type and constants of an enum
+func (b *builders) enum(def *ast.EnumDefinition) {
+	goName := b.types[def.Name.Value]
+
+	b.printf("// %s is the %s enum\n", goName, def.Name.Value)
+	if def.Description != nil {
+		b.printf("//\n%s", comment(def.Description.Value))
+	}
+	b.printf("type %s string\n\n", goName)
+
+	if len(def.Values) > 0 {
+		b.printf("// Values of the %s enum\nconst (\n", def.Name.Value)
+		for _, v := range def.Values {
+			if v.Description != nil {
+				b.printf("%s", comment(v.Description.Value))
+			}
+			if reason, ok := deprecationReason(v.Directives); ok {
+				b.printf("%s", comment("Deprecated: "+reason))
+			}
+			b.printf("%s %s = %q\n", b.global.get(goName+enumValueName(v.Name.Value)), goName, v.Name.Value)
+		}
+		b.printf(")\n\n")
+	}
+
+	b.printf("// Value returns the enum as a GraphQL value\n")
+	b.printf("func (e %s) Value() *fgql.Value {\nreturn fgql.NewEnumValue(string(e))\n}\n\n", goName)
+}
+
+// input generates the struct of an input object
+func (b *builders) input(def *ast.InputObjectDefinition) error {
+	goName := b.types[def.Name.Value]
+	fields := newNames("Value")
+
+	b.printf("// %s is the %s input object. Nullable fields are omitted from its value when nil.\n", goName, def.Name.Value)
+	if def.Description != nil {
+		b.printf("//\n%s", comment(def.Description.Value))
+	}
+	b.printf("type %s struct {\n", goName)
+
+	var value bytes.Buffer
+	for _, f := range def.Fields {
+		goFieldType, err := b.inputType(f.Type, true)
+		if err != nil {
+			return err
+		}
+		goField := fields.get(exportedName(f.Name.Value))
+		if f.Description != nil {
+			b.printf("%s", comment(f.Description.Value))
+		}
+		b.printf("%s %s\n", goField, goFieldType)
+
+		if _, required := f.Type.(*ast.NonNull); required {
+			fmt.Fprintf(&value, "fields = append(fields, fgql.NewObjectValueField(%q, %s))\n", f.Name.Value, b.valueExpr(f.Type, "in."+goField, 0))
+		} else {
+			expr := "*in." + goField
+			if _, list := f.Type.(*ast.List); list {
+				expr = "in." + goField
+			}
+			fmt.Fprintf(&value, "if in.%s != nil {\nfields = append(fields, fgql.NewObjectValueField(%q, %s))\n}\n", goField, f.Name.Value, b.valueExpr(f.Type, expr, 0))
+		}
+	}
+	b.printf("}\n\n")
+
+	b.printf("// Value returns the input object as a GraphQL value\n")
+	b.printf("func (in %s) Value() *fgql.Value {\n", goName)
+	b.printf("fields := make([]*fgql.ObjectValueField, 0, %d)\n%s", len(def.Fields), value.String())
+	b.printf("return fgql.NewObjectValue(fields...)\n}\n\n")
+	return nil
+}
+
+// inputType returns the Go type of an input type. Nullable named types are
+// pointers if nullablePointer is set, lists are always slices.
+func (b *builders) inputType(t ast.Type, nullablePointer bool) (string, error) {
+	switch n := t.(type) {
+	case *ast.NonNull:
+		return b.inputType(n.Type, false)
+	case *ast.List:
+		elem, err := b.inputType(n.Type, false)
+		return "[]" + elem, err
+	case *ast.Named:
+		var goType string
+		switch b.schema.Type(n.Name.Value).(type) {
+		case *ast.ScalarDefinition:
+			switch n.Name.Value {
+			case "Int":
+				goType = "int"
+			case "Float":
+				goType = "float64"
+			case "Boolean":
+				goType = "bool"
+			default:
+				goType = "string"
+			}
+		case *ast.EnumDefinition, *ast.InputObjectDefinition:
+			goType = b.types[n.Name.Value]
+		default:
+			return "", fmt.Errorf("codegen: %s is not an input type", n.Name.Value)
+		}
+		if nullablePointer {
+			return "*" + goType, nil
+		}
+		return goType, nil
+	}
+	return "", fmt.Errorf("codegen: unknown type %v", t)
+}
+
+// valueExpr returns a Go expression converting expr, of the Go type of t, into a *fgql.Value
+func (b *builders) valueExpr(t ast.Type, expr string, depth int) string {
+	switch n := t.(type) {
+	case *ast.NonNull:
+		return b.valueExpr(n.Type, expr, depth)
+	case *ast.List:
+		v := fmt.Sprintf("v%d", depth)
+		return fmt.Sprintf("func() *fgql.Value {\nvalues := make([]*fgql.Value, 0, len(%s))\nfor _, %s := range %s {\nvalues = append(values, %s)\n}\nreturn fgql.NewListValue(values...)\n}()",
+			expr, v, expr, b.valueExpr(n.Type, v, depth+1))
+	case *ast.Named:
+		switch b.schema.Type(n.Name.Value).(type) {
+		case *ast.EnumDefinition, *ast.InputObjectDefinition:
+			return expr + ".Value()"
+		}
+		switch n.Name.Value {
+		case "Int":
+			return "fgql.NewIntValue(" + expr + ")"
+		case "Float":
+			return "fgql.NewFloatValue(" + expr + ")"
+		case "Boolean":
+			return "fgql.NewBooleanValue(" + expr + ")"
+		}
+		return "fgql.NewStringValue(" + expr + ")"
+	}
+	return "nil"
+}
+
+// deprecationReason returns the reason of a @deprecated directive, if there's one
+func deprecationReason(directives []*ast.Directive) (string, bool) {
+	for _, d := range directives {
+		if d.Name.Value != "deprecated" {
+			continue
+		}
+		for _, arg := range d.Arguments {
+			if s, ok := arg.Value.(*ast.StringValue); ok && arg.Name.Value == "reason" {
+				return s.Value, true
+			}
+		}
+		return "No longer supported", true
+	}
+	return "", false
+}
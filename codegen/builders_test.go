@@ -0,0 +1,121 @@
+package codegen
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"strings"
+	"testing"
+)
+
+func loadTestSchema(t *testing.T, path string) *Schema {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer f.Close()
+
+	schema, err := LoadSchema(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return schema
+}
+
+// fset and sourceImporter are shared by type checks, for packages to be imported once
+var (
+	fset           = token.NewFileSet()
+	sourceImporter = importer.ForCompiler(fset, "source", nil)
+)
+
+// typeCheck parses and type-checks generated code, returning the package it declares
+func typeCheck(t *testing.T, src []byte) *types.Package {
+	t.Helper()
+	f, err := parser.ParseFile(fset, "generated.go", src, 0)
+	if err != nil {
+		t.Fatalf("generated code does not parse: %v", err)
+	}
+	config := types.Config{Importer: sourceImporter}
+	pkg, err := config.Check("generated", fset, []*ast.File{f}, nil)
+	if err != nil {
+		t.Fatalf("generated code does not type-check: %v", err)
+	}
+	return pkg
+}
+
+func TestGenerateBuilders(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		schema string
+		wanted []string
+	}{
+		"SDL": {
+			schema: "testdata/schema.graphql",
+			wanted: []string{
+				"func NewQuery(options ...fgql.OperationOption) Query {",
+				"func NewMutation(options ...fgql.OperationOption) Mutation {",
+				"func (t Query) Repository(owner string, name string, options ...fgql.SelectionOption) Repository {",
+				"func QueryRepositoryFollowRenames(v bool) fgql.SelectionOption {",
+				"func (t Repository) StargazerCount(options ...fgql.SelectionOption) Repository {",
+				"func RepositoryIssuesStates(v []IssueState) fgql.SelectionOption {",
+				"func (t RepositoryOwner) OnUser() User {",
+				"func (t SearchResultItem) OnIssue() Issue {",
+				"IssueStateNotPlanned IssueState = \"NOT_PLANNED\"",
+				"Limit       *int",
+				"// Deprecated: Use languages instead.",
+			},
+		},
+		"Introspection": {
+			schema: "testdata/introspection.json",
+			wanted: []string{
+				"func NewQuery(options ...fgql.OperationOption) Query {",
+				"func QueryHeroEpisode(v Episode) fgql.SelectionOption {",
+				"func (t Query) Hero(options ...fgql.SelectionOption) Character {",
+				"func (t Character) OnDroid() Droid {",
+				"// Deprecated: Droids have many functions.",
+			},
+		},
+		"Collisions": {
+			schema: "testdata/collisions.graphql",
+			wanted: []string{
+				"func (t Query) Node(valuesArg []string, v0Arg [][]int, fgqlArg string, lenArg int, typeArg string, typeArg_ string, tArg int, optionsArg int, options ...fgql.SelectionOption) Node {",
+				"func (t Node) NameField(options ...fgql.SelectionOption) Node {",
+				"func (t Node) NameField_(options ...fgql.SelectionOption) Node {",
+				"func (t Node) StringField(options ...fgql.SelectionOption) Node {",
+				"func (t Node) ParentField(options ...fgql.SelectionOption) Node {",
+				"func (t Node) RootField(options ...fgql.SelectionOption) Node {",
+				"func (t Node) SelectionField(options ...fgql.SelectionOption) Node {",
+				"func (t Node) Typename_(options ...fgql.SelectionOption) Node {",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			src, err := GenerateBuilders(loadTestSchema(t, testCase.schema), "generated")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			pkg := typeCheck(t, src)
+			for _, wanted := range testCase.wanted {
+				if !strings.Contains(string(src), wanted) {
+					t.Errorf("generated code does not contain %q", wanted)
+				}
+			}
+			if strings.Contains(string(src), "__Schema") {
+				t.Error("generated code contains introspection types")
+			}
+			// wrappers keep the methods of the embedded selection, such as String
+			stringer := types.NewInterfaceType([]*types.Func{types.NewFunc(token.NoPos, nil, "String",
+				types.NewSignatureType(nil, nil, nil, nil, types.NewTuple(types.NewVar(token.NoPos, nil, "", types.Typ[types.String])), false)),
+			}, nil).Complete()
+			for _, name := range pkg.Scope().Names() {
+				typ := pkg.Scope().Lookup(name).Type()
+				if s, ok := typ.Underlying().(*types.Struct); ok && s.NumFields() == 1 && s.Field(0).Embedded() && !types.Implements(typ, stringer) {
+					t.Errorf("wrapper %s is not a fmt.Stringer", name)
+				}
+			}
+		})
+	}
+}
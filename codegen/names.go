@@ -0,0 +1,119 @@
+package codegen
+
+import (
+	"go/token"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	fgql "github.com/mergestat/fluentgraphql"
+)
+
+// names hands out Go identifiers that are unique within a scope
+type names struct {
+	used map[string]bool
+}
+
+func newNames(reserved ...string) *names {
+	n := &names{used: make(map[string]bool)}
+	for _, name := range reserved {
+		n.used[name] = true
+	}
+	return n
+}
+
+// get returns name, or name suffixed with underscores if it was already handed out
+func (n *names) get(name string) string {
+	for n.used[name] {
+		name += "_"
+	}
+	n.used[name] = true
+	return name
+}
+
+// exportedName turns a GraphQL name into an exported Go identifier
+func exportedName(name string) string {
+	name = strings.TrimLeft(name, "_")
+	if name == "" {
+		return "X"
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// enumValueName turns an enum value such as MERGE_COMMIT into a Go identifier suffix such as MergeCommit
+func enumValueName(value string) string {
+	var b strings.Builder
+	for _, part := range strings.Split(value, "_") {
+		if part == "" {
+			continue
+		}
+		if strings.ToUpper(part) == part {
+			part = strings.ToLower(part)
+		}
+		b.WriteString(exportedName(part))
+	}
+	if b.Len() == 0 {
+		return "X"
+	}
+	return b.String()
+}
+
+// selectionMethods are the methods of the *fgql.Selection embedded in wrappers, which
+// the methods generated for fields must not shadow
+var selectionMethods = func() map[string]bool {
+	methods := make(map[string]bool)
+	t := reflect.TypeOf(&fgql.Selection{})
+	for i := 0; i < t.NumMethod(); i++ {
+		methods[t.Method(i).Name] = true
+	}
+	return methods
+}()
+
+// methodName turns a GraphQL field name into the name of a wrapper method, suffixed with
+// Field when it would shadow a method of the embedded selection, such as Name or String
+func methodName(name string) string {
+	method := exportedName(name)
+	if selectionMethods[method] {
+		return method + "Field"
+	}
+	return method
+}
+
+// paramName turns a GraphQL argument name into a Go parameter name that doesn't clash
+// with keywords, predeclared identifiers, the fgql package, or the identifiers used in
+// generated method bodies: the receiver t, options, and the values and v0, v1… of lists
+func paramName(name string) string {
+	switch {
+	case token.IsKeyword(name), types.Universe.Lookup(name) != nil, isGeneratedIdent(name):
+		return name + "Arg"
+	case strings.HasPrefix(name, "_"):
+		return "arg" + name
+	}
+	return name
+}
+
+func isGeneratedIdent(name string) bool {
+	switch name {
+	case "t", "options", "values", "fgql":
+		return true
+	}
+	if strings.HasPrefix(name, "v") {
+		_, err := strconv.Atoi(name[1:])
+		return err == nil
+	}
+	return false
+}
+
+// comment returns text as a Go comment, one comment line per line of text
+func comment(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		b.WriteString(strings.TrimRight("// "+strings.TrimSpace(line), " "))
+		b.WriteString("\n")
+	}
+	return b.String()
+}
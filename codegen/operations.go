@@ -0,0 +1,529 @@
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+	fgql "github.com/mergestat/fluentgraphql"
+)
+
+// ParseOperations parses a document of operations and fragment definitions
+func ParseOperations(document string) (*ast.Document, error) {
+	return parser.Parse(parser.ParseParams{
+		Source:  document,
+		Options: parser.ParseOptions{NoSource: true},
+	})
+}
+
+// SelectionDocument returns the document of the operation built by a selection
+func SelectionDocument(s *fgql.Selection) (*ast.Document, error) {
+	if _, err := s.Root().Document(); err != nil {
+		return nil, err
+	}
+	return ParseOperations(s.Root().String())
+}
+
+// operations generates response types for the operations of a document
+type operations struct {
+	schema    *Schema
+	doc       *ast.Document
+	fragments map[string]*ast.FragmentDefinition
+	buf       bytes.Buffer
+	global    *names
+	// inputs holds the input objects used by variables, which get a struct each
+	inputs map[string]string
+}
+
+// GenerateOperations generates the source of a Go package with response types and
+// Execute functions for the named operations of a document, which may also hold the
+// fragment definitions the operations spread.
+//
+// For each operation, a struct mirrors the shape of its response: a field per
+// response key (so aliases are kept), pointers and slices where the schema says a value
+// is nullable or a list, and a nested struct per composite field. Inline fragments and
+// spreads on a type other than the one of their selection become On<Type> fields, set
+// according to the __typename of the response, which is added to the selection.
+// A variables struct and an Execute<Operation> function sending the operation through a
+// fluentgraphql.Executor are generated too. Custom scalars are decoded as json.RawMessage
+// and enums as strings.
+func GenerateOperations(schema *Schema, doc *ast.Document, packageName string) ([]byte, error) {
+	o := &operations{
+		schema:    schema,
+		doc:       doc,
+		fragments: make(map[string]*ast.FragmentDefinition),
+		global:    newNames("toVariables"),
+		inputs:    make(map[string]string),
+	}
+	for _, def := range doc.Definitions {
+		if f, ok := def.(*ast.FragmentDefinition); ok {
+			o.fragments[f.Name.Value] = f
+		}
+	}
+
+	var body bytes.Buffer
+	for _, def := range doc.Definitions {
+		op, ok := def.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		if op.Name == nil || op.Name.Value == "" {
+			return nil, fmt.Errorf("codegen: operations must be named")
+		}
+		if err := o.operation(op); err != nil {
+			return nil, err
+		}
+		body.Write(o.buf.Bytes())
+		o.buf.Reset()
+	}
+	if err := o.inputObjects(); err != nil {
+		return nil, err
+	}
+	body.Write(o.buf.Bytes())
+	o.buf.Reset()
+
+	o.printf("// Code generated by fgql-gen. DO NOT EDIT.\n\n")
+	o.printf("package %s\n\n", packageName)
+	o.printf("import (\n\"context\"\n\"encoding/json\"\n\nfgql %q\n)\n\n", "github.com/mergestat/fluentgraphql")
+	o.buf.Write(body.Bytes())
+	o.printf("// toVariables converts a variables struct into the map sent along with an operation\n")
+	o.printf("func toVariables(v interface{}) (map[string]interface{}, error) {\n")
+	o.printf("b, err := json.Marshal(v)\nif err != nil {\nreturn nil, err\n}\n")
+	o.printf("var variables map[string]interface{}\nreturn variables, json.Unmarshal(b, &variables)\n}\n")
+
+	return format.Source(o.buf.Bytes())
+}
+
+func (o *operations) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&o.buf, format, args...)
+}
+
+// operation generates the document, variables, response types and Execute function of an operation
+func (o *operations) operation(op *ast.OperationDefinition) error {
+	root := o.schema.RootType(op.Operation)
+	if root == "" {
+		return fmt.Errorf("codegen: schema has no %s type", op.Operation)
+	}
+	name := exportedName(op.Name.Value)
+
+	o.addTypenames(root, op.SelectionSet, make(map[string]bool))
+	document, err := o.document(op)
+	if err != nil {
+		return err
+	}
+
+	documentName := o.global.get(name + "Document")
+	operationVar := o.global.get(strings.ToLower(name[:1]) + name[1:] + "Operation")
+	o.printf("// %s is the %s %s\n", documentName, op.Name.Value, op.Operation)
+	o.printf("const %s = `%s`\n\n", documentName, document)
+	o.printf("var %s = func() *fgql.Selection {\n", operationVar)
+	o.printf("s, err := fgql.Parse(%s)\nif err != nil {\npanic(err)\n}\nreturn s\n}()\n\n", documentName)
+
+	variablesName := ""
+	if len(op.VariableDefinitions) > 0 {
+		variablesName = o.global.get(name + "Variables")
+		if err := o.variables(variablesName, op); err != nil {
+			return err
+		}
+	}
+
+	responseName := o.global.get(name + "Response")
+	doc := fmt.Sprintf("%s is the data of the %s %s", responseName, op.Name.Value, op.Operation)
+	if err := o.object(responseName, root, []*ast.SelectionSet{op.SelectionSet}, doc); err != nil {
+		return err
+	}
+
+	if op.Operation == ast.OperationTypeSubscription {
+		return nil
+	}
+
+	execute := o.global.get("Execute" + name)
+	o.printf("// %s executes the %s %s\n", execute, op.Operation, op.Name.Value)
+	if variablesName != "" {
+		o.printf("func %s(ctx context.Context, executor fgql.Executor, variables %s) (*%s, error) {\n", execute, variablesName, responseName)
+		o.printf("vars, err := toVariables(variables)\nif err != nil {\nreturn nil, err\n}\n")
+	} else {
+		o.printf("func %s(ctx context.Context, executor fgql.Executor) (*%s, error) {\n", execute, responseName)
+		o.printf("var vars map[string]interface{}\n")
+	}
+	o.printf("res, err := executor.Execute(ctx, fgql.NewRequest(%s, vars))\nif err != nil {\nreturn nil, err\n}\n", operationVar)
+	o.printf("var data %s\n", responseName)
+	o.printf("if len(res.Data) > 0 {\nif err := json.Unmarshal(res.Data, &data); err != nil {\nreturn nil, err\n}\n}\n")
+	o.printf("if len(res.Errors) > 0 {\nreturn &data, res.Errors\n}\nreturn &data, nil\n}\n\n")
+
+	return nil
+}
+
+// document prints an operation along with the fragments it spreads, transitively
+func (o *operations) document(op *ast.OperationDefinition) (string, error) {
+	used := make(map[string]bool)
+	if err := o.spreads(op.SelectionSet, used); err != nil {
+		return "", err
+	}
+	names := make([]string, 0, len(used))
+	for name := range used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	definitions := []ast.Node{op}
+	for _, name := range names {
+		definitions = append(definitions, o.fragments[name])
+	}
+	printed := printer.Print(ast.NewDocument(&ast.Document{Definitions: definitions})).(string)
+	if strings.Contains(printed, "`") {
+		return "", fmt.Errorf("codegen: operation %s contains a backtick", op.Name.Value)
+	}
+	return printed, nil
+}
+
+// spreads records the names of the fragments spread within a selection set, transitively
+func (o *operations) spreads(set *ast.SelectionSet, used map[string]bool) error {
+	if set == nil {
+		return nil
+	}
+	for _, selection := range set.Selections {
+		switch n := selection.(type) {
+		case *ast.Field:
+			if err := o.spreads(n.SelectionSet, used); err != nil {
+				return err
+			}
+		case *ast.InlineFragment:
+			if err := o.spreads(n.SelectionSet, used); err != nil {
+				return err
+			}
+		case *ast.FragmentSpread:
+			f, ok := o.fragments[n.Name.Value]
+			if !ok {
+				return fmt.Errorf("codegen: unknown fragment %s", n.Name.Value)
+			}
+			if !used[n.Name.Value] {
+				used[n.Name.Value] = true
+				if err := o.spreads(f.SelectionSet, used); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// addTypenames selects __typename wherever a fragment applies to a type other than
+// the one of its selection, so that responses can be told apart when decoding
+func (o *operations) addTypenames(typeName string, set *ast.SelectionSet, visited map[string]bool) {
+	if set == nil {
+		return
+	}
+	needed, selected := false, false
+	for _, selection := range set.Selections {
+		switch n := selection.(type) {
+		case *ast.Field:
+			if n.Name.Value == "__typename" && (n.Alias == nil || n.Alias.Value == "") {
+				selected = true
+			}
+			if f := o.schema.Field(typeName, n.Name.Value); f != nil {
+				o.addTypenames(namedType(f.Type), n.SelectionSet, visited)
+			}
+		case *ast.InlineFragment:
+			condition := typeName
+			if n.TypeCondition != nil {
+				condition = n.TypeCondition.Name.Value
+			}
+			if condition != typeName {
+				needed = true
+			}
+			o.addTypenames(condition, n.SelectionSet, visited)
+		case *ast.FragmentSpread:
+			if f, ok := o.fragments[n.Name.Value]; ok {
+				if f.TypeCondition.Name.Value != typeName {
+					needed = true
+				}
+				if !visited[n.Name.Value] {
+					visited[n.Name.Value] = true
+					o.addTypenames(f.TypeCondition.Name.Value, f.SelectionSet, visited)
+				}
+			}
+		}
+	}
+	if needed && !selected {
+		set.Selections = append([]ast.Selection{ast.NewField(&ast.Field{
+			Name:       ast.NewName(&ast.Name{Value: "__typename"}),
+			Arguments:  []*ast.Argument{},
+			Directives: []*ast.Directive{},
+		})}, set.Selections...)
+	}
+}
+
+// fieldGroup is the fields selected under the same response key
+type fieldGroup struct {
+	key    string
+	fields []*ast.Field
+}
+
+// collect merges the fields selected on typeName by a list of selection sets, including
+// fragments on that type, and returns apart the selection sets of fragments on other types
+func (o *operations) collect(typeName string, sets []*ast.SelectionSet) ([]*fieldGroup, map[string][]*ast.SelectionSet, []string) {
+	var groups []*fieldGroup
+	byKey := make(map[string]*fieldGroup)
+	variants := make(map[string][]*ast.SelectionSet)
+	var conditions []string
+
+	var visit func(set *ast.SelectionSet)
+	fragment := func(condition string, set *ast.SelectionSet) {
+		if condition == typeName {
+			visit(set)
+			return
+		}
+		if _, ok := variants[condition]; !ok {
+			conditions = append(conditions, condition)
+		}
+		variants[condition] = append(variants[condition], set)
+	}
+	visit = func(set *ast.SelectionSet) {
+		if set == nil {
+			return
+		}
+		for _, selection := range set.Selections {
+			switch n := selection.(type) {
+			case *ast.Field:
+				key := n.Name.Value
+				if n.Alias != nil && n.Alias.Value != "" {
+					key = n.Alias.Value
+				}
+				group, ok := byKey[key]
+				if !ok {
+					group = &fieldGroup{key: key}
+					byKey[key] = group
+					groups = append(groups, group)
+				}
+				group.fields = append(group.fields, n)
+			case *ast.InlineFragment:
+				condition := typeName
+				if n.TypeCondition != nil {
+					condition = n.TypeCondition.Name.Value
+				}
+				fragment(condition, n.SelectionSet)
+			case *ast.FragmentSpread:
+				if f, ok := o.fragments[n.Name.Value]; ok {
+					fragment(f.TypeCondition.Name.Value, f.SelectionSet)
+				}
+			}
+		}
+	}
+	for _, set := range sets {
+		visit(set)
+	}
+
+	return groups, variants, conditions
+}
+
+// object generates the struct of the selections on a composite type
+func (o *operations) object(goName, typeName string, sets []*ast.SelectionSet, doc string) error {
+	groups, variants, conditions := o.collect(typeName, sets)
+	fields := newNames("UnmarshalJSON")
+
+	var nested []func() error
+	o.printf("// %s\n", doc)
+	o.printf("type %s struct {\n", goName)
+	for _, group := range groups {
+		goField := fields.get(exportedName(group.key))
+		field := group.fields[0]
+
+		if field.Name.Value == "__typename" {
+			o.printf("%s string `json:%q`\n", goField, group.key)
+			continue
+		}
+		def := o.schema.Field(typeName, field.Name.Value)
+		if def == nil {
+			return fmt.Errorf("codegen: unknown field %s on type %s", field.Name.Value, typeName)
+		}
+
+		fieldType := namedType(def.Type)
+		elem := o.leafType(fieldType)
+		if !o.schema.IsLeaf(fieldType) {
+			elem = o.global.get(goName + goField)
+			var sets []*ast.SelectionSet
+			for _, f := range group.fields {
+				sets = append(sets, f.SelectionSet)
+			}
+			elemName, elemType := elem, fieldType
+			nested = append(nested, func() error {
+				doc := fmt.Sprintf("%s is the %s selected by %s.%s", elemName, elemType, goName, goField)
+				return o.object(elemName, elemType, sets, doc)
+			})
+		}
+		o.printf("%s %s `json:%q`\n", goField, outputType(def.Type, elem, true), group.key)
+	}
+
+	variantFields := make(map[string]string, len(conditions))
+	variantNames := make(map[string]string, len(conditions))
+	for _, condition := range conditions {
+		if o.schema.Type(condition) == nil {
+			return fmt.Errorf("codegen: unknown type %s", condition)
+		}
+		goField := fields.get("On" + exportedName(condition))
+		variantFields[condition] = goField
+		variantNames[condition] = o.global.get(goName + goField)
+		o.printf("%s *%s `json:\"-\"`\n", goField, variantNames[condition])
+
+		condition := condition
+		nested = append(nested, func() error {
+			doc := fmt.Sprintf("%s is set on %s when the response is of type %s", variantNames[condition], goName, condition)
+			return o.object(variantNames[condition], condition, variants[condition], doc)
+		})
+	}
+	o.printf("}\n\n")
+
+	if len(conditions) > 0 {
+		o.printf("// UnmarshalJSON decodes the fields of the %s type, and those of the fragments matching the __typename\n", typeName)
+		o.printf("func (v *%s) UnmarshalJSON(b []byte) error {\n", goName)
+		o.printf("type plain %s\nif err := json.Unmarshal(b, (*plain)(v)); err != nil {\nreturn err\n}\n", goName)
+		o.printf("var typename struct {\nTypename string `json:\"__typename\"`\n}\n")
+		o.printf("if err := json.Unmarshal(b, &typename); err != nil {\nreturn err\n}\n")
+		for _, condition := range conditions {
+			possible := o.schema.PossibleTypes(condition)
+			if possible == nil {
+				possible = []string{condition}
+			}
+			quoted := make([]string, 0, len(possible))
+			for _, p := range possible {
+				quoted = append(quoted, fmt.Sprintf("%q", p))
+			}
+			goField := variantFields[condition]
+			o.printf("switch typename.Typename {\ncase %s:\nv.%s = new(%s)\nif err := json.Unmarshal(b, v.%s); err != nil {\nreturn err\n}\n}\n",
+				strings.Join(quoted, ", "), goField, variantNames[condition], goField)
+		}
+		o.printf("return nil\n}\n\n")
+	}
+
+	for _, generate := range nested {
+		if err := generate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// variables generates the struct of the variables of an operation
+func (o *operations) variables(goName string, op *ast.OperationDefinition) error {
+	fields := newNames()
+	o.printf("// %s are the variables of the %s %s\n", goName, op.Name.Value, op.Operation)
+	o.printf("type %s struct {\n", goName)
+	for _, def := range op.VariableDefinitions {
+		goType, err := o.inputType(def.Type, true)
+		if err != nil {
+			return err
+		}
+		tag := def.Variable.Name.Value
+		if _, required := def.Type.(*ast.NonNull); !required {
+			tag += ",omitempty"
+		}
+		o.printf("%s %s `json:%q`\n", fields.get(exportedName(def.Variable.Name.Value)), goType, tag)
+	}
+	o.printf("}\n\n")
+	return nil
+}
+
+// inputObjects generates the structs of the input objects used by variables, and those they use
+func (o *operations) inputObjects() error {
+	generated := make(map[string]bool)
+	for {
+		var pending []string
+		for name := range o.inputs {
+			if !generated[name] {
+				pending = append(pending, name)
+			}
+		}
+		if len(pending) == 0 {
+			return nil
+		}
+		sort.Strings(pending)
+
+		for _, name := range pending {
+			generated[name] = true
+			def := o.schema.Type(name).(*ast.InputObjectDefinition)
+			fields := newNames()
+			o.printf("// %s is the %s input object\n", o.inputs[name], name)
+			o.printf("type %s struct {\n", o.inputs[name])
+			for _, f := range def.Fields {
+				goType, err := o.inputType(f.Type, true)
+				if err != nil {
+					return err
+				}
+				tag := f.Name.Value
+				if _, required := f.Type.(*ast.NonNull); !required {
+					tag += ",omitempty"
+				}
+				o.printf("%s %s `json:%q`\n", fields.get(exportedName(f.Name.Value)), goType, tag)
+			}
+			o.printf("}\n\n")
+		}
+	}
+}
+
+// inputType returns the Go type of a variable or input object field. Nullable
+// named types are pointers if nullablePointer is set, lists are always slices.
+func (o *operations) inputType(t ast.Type, nullablePointer bool) (string, error) {
+	switch n := t.(type) {
+	case *ast.NonNull:
+		return o.inputType(n.Type, false)
+	case *ast.List:
+		elem, err := o.inputType(n.Type, false)
+		return "[]" + elem, err
+	case *ast.Named:
+		var goType string
+		switch o.schema.Type(n.Name.Value).(type) {
+		case *ast.ScalarDefinition, *ast.EnumDefinition:
+			goType = o.leafType(n.Name.Value)
+		case *ast.InputObjectDefinition:
+			if _, ok := o.inputs[n.Name.Value]; !ok {
+				o.inputs[n.Name.Value] = o.global.get(exportedName(n.Name.Value))
+			}
+			goType = o.inputs[n.Name.Value]
+		default:
+			return "", fmt.Errorf("codegen: %s is not an input type", n.Name.Value)
+		}
+		if nullablePointer && goType != "json.RawMessage" {
+			return "*" + goType, nil
+		}
+		return goType, nil
+	}
+	return "", fmt.Errorf("codegen: unknown type %v", t)
+}
+
+// leafType returns the Go type of a scalar or enum
+func (o *operations) leafType(name string) string {
+	switch name {
+	case "Int":
+		return "int"
+	case "Float":
+		return "float64"
+	case "Boolean":
+		return "bool"
+	case "String", "ID":
+		return "string"
+	}
+	if _, ok := o.schema.Type(name).(*ast.EnumDefinition); ok {
+		return "string"
+	}
+	return "json.RawMessage"
+}
+
+// outputType returns the Go type of a response value of type t, whose named type is elem
+func outputType(t ast.Type, elem string, nullable bool) string {
+	switch n := t.(type) {
+	case *ast.NonNull:
+		return outputType(n.Type, elem, false)
+	case *ast.List:
+		return "[]" + outputType(n.Type, elem, true)
+	}
+	if nullable && elem != "json.RawMessage" {
+		return "*" + elem
+	}
+	return elem
+}
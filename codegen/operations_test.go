@@ -0,0 +1,72 @@
+package codegen
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/graphql-go/graphql/language/ast"
+	fgql "github.com/mergestat/fluentgraphql"
+)
+
+func TestGenerateOperations(t *testing.T) {
+	document, err := ioutil.ReadFile("testdata/operations.graphql")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fromFile, err := ParseOperations(string(document))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	fromSelection, err := SelectionDocument(fgql.NewQuery(fgql.WithName("Stars")).
+		Selection("repository", fgql.WithAlias("repo"), fgql.WithArguments(
+			fgql.NewArgument("owner", fgql.NewStringValue("mergestat")),
+			fgql.NewArgument("name", fgql.NewStringValue("fluentgraphql")),
+		)).
+		Scalar("stargazerCount"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	for name, testCase := range map[string]struct {
+		doc    *ast.Document
+		wanted []string
+	}{
+		"File": {
+			doc: fromFile,
+			wanted: []string{
+				"Stars  int                                `json:\"stars\"`",
+				"Repo *RepositoryIssuesResponseRepo `json:\"repo\"`",
+				"OnUser         *RepositoryIssuesResponseRepoOwnerOnUser         `json:\"-\"`",
+				"Edges      []*RepositoryIssuesResponseRepoIssuesEdges `json:\"edges\"`",
+				"CreatedAt json.RawMessage `json:\"createdAt\"`",
+				"OrderBy *IssueOrder `json:\"orderBy,omitempty\"`",
+				"func ExecuteRepositoryIssues(ctx context.Context, executor fgql.Executor, variables RepositoryIssuesVariables) (*RepositoryIssuesResponse, error) {",
+				"func ExecuteSearch(ctx context.Context, executor fgql.Executor) (*SearchResponse, error) {",
+				"case \"Issue\", \"Organization\", \"Repository\", \"User\":",
+				"fragment issueFields on Issue {",
+			},
+		},
+		"Selection": {
+			doc: fromSelection,
+			wanted: []string{
+				"Repo *StarsResponseRepo `json:\"repo\"`",
+				"StargazerCount int `json:\"stargazerCount\"`",
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			src, err := GenerateOperations(loadTestSchema(t, "testdata/schema.graphql"), testCase.doc, "generated")
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			typeCheck(t, src)
+			for _, wanted := range testCase.wanted {
+				if !strings.Contains(string(src), wanted) {
+					t.Errorf("generated code does not contain %q", wanted)
+				}
+			}
+		})
+	}
+}
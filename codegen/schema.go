@@ -0,0 +1,195 @@
+// Package codegen generates Go code from GraphQL schemas for use with fluentgraphql
+package codegen
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sort"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+
+	fgql "github.com/mergestat/fluentgraphql"
+)
+
+// builtinScalars are the scalars every schema has, whether or not it defines them
+var builtinScalars = []string{"Int", "Float", "String", "Boolean", "ID"}
+
+// Schema is an index of the type definitions of a GraphQL schema
+type Schema struct {
+	Document *ast.Document
+
+	types           map[string]ast.Node
+	implementations map[string][]string
+	roots           map[string]string
+}
+
+// LoadSchema reads a schema either as SDL or as the JSON result of an introspection query
+func LoadSchema(r io.Reader) (*Schema, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc *ast.Document
+	if trimmed := bytes.TrimSpace(b); len(trimmed) > 0 && trimmed[0] == '{' {
+		if doc, err = fgql.ParseIntrospection(trimmed); err != nil {
+			return nil, err
+		}
+	} else {
+		if doc, err = parser.Parse(parser.ParseParams{Source: string(b), Options: parser.ParseOptions{NoSource: true}}); err != nil {
+			return nil, err
+		}
+	}
+
+	return NewSchema(doc)
+}
+
+// NewSchema indexes the type system definitions of a document
+func NewSchema(doc *ast.Document) (*Schema, error) {
+	s := &Schema{
+		Document:        doc,
+		types:           make(map[string]ast.Node),
+		implementations: make(map[string][]string),
+		roots: map[string]string{
+			ast.OperationTypeQuery:        "Query",
+			ast.OperationTypeMutation:     "Mutation",
+			ast.OperationTypeSubscription: "Subscription",
+		},
+	}
+	for _, name := range builtinScalars {
+		s.types[name] = ast.NewScalarDefinition(&ast.ScalarDefinition{Name: ast.NewName(&ast.Name{Value: name})})
+	}
+
+	var extensions []*ast.ObjectDefinition
+	for _, def := range doc.Definitions {
+		switch n := def.(type) {
+		case *ast.SchemaDefinition:
+			for _, op := range n.OperationTypes {
+				s.roots[op.Operation] = op.Type.Name.Value
+			}
+		case *ast.ObjectDefinition:
+			copied := *n
+			s.types[n.Name.Value] = &copied
+		case *ast.InterfaceDefinition:
+			s.types[n.Name.Value] = n
+		case *ast.UnionDefinition:
+			s.types[n.Name.Value] = n
+		case *ast.EnumDefinition:
+			s.types[n.Name.Value] = n
+		case *ast.InputObjectDefinition:
+			s.types[n.Name.Value] = n
+		case *ast.ScalarDefinition:
+			s.types[n.Name.Value] = n
+		case *ast.TypeExtensionDefinition:
+			extensions = append(extensions, n.Definition)
+		}
+	}
+
+	for _, ext := range extensions {
+		obj, ok := s.types[ext.Name.Value].(*ast.ObjectDefinition)
+		if !ok {
+			return nil, fmt.Errorf("codegen: cannot extend unknown type %s", ext.Name.Value)
+		}
+		obj.Fields = append(append([]*ast.FieldDefinition(nil), obj.Fields...), ext.Fields...)
+		obj.Interfaces = append(append([]*ast.Named(nil), obj.Interfaces...), ext.Interfaces...)
+	}
+
+	for _, name := range s.TypeNames() {
+		if obj, ok := s.types[name].(*ast.ObjectDefinition); ok {
+			for _, iface := range obj.Interfaces {
+				s.implementations[iface.Name.Value] = append(s.implementations[iface.Name.Value], name)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// Type returns the definition of the named type, or nil if there's none
+func (s *Schema) Type(name string) ast.Node {
+	return s.types[name]
+}
+
+// TypeNames returns the sorted names of the types of the schema
+func (s *Schema) TypeNames() []string {
+	names := make([]string, 0, len(s.types))
+	for name := range s.types {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RootType returns the name of the root type of an operation type (query, mutation or subscription)
+func (s *Schema) RootType(operation string) string {
+	if _, ok := s.types[s.roots[operation]]; !ok {
+		return ""
+	}
+	return s.roots[operation]
+}
+
+// Implementations returns the names of the object types implementing an interface
+func (s *Schema) Implementations(iface string) []string {
+	return s.implementations[iface]
+}
+
+// PossibleTypes returns the names of the object types a value of an abstract type can be
+func (s *Schema) PossibleTypes(name string) []string {
+	switch n := s.types[name].(type) {
+	case *ast.UnionDefinition:
+		types := make([]string, 0, len(n.Types))
+		for _, t := range n.Types {
+			types = append(types, t.Name.Value)
+		}
+		return types
+	case *ast.InterfaceDefinition:
+		return s.implementations[name]
+	}
+	return nil
+}
+
+// Fields returns the field definitions of an object or interface type
+func (s *Schema) Fields(name string) []*ast.FieldDefinition {
+	switch n := s.types[name].(type) {
+	case *ast.ObjectDefinition:
+		return n.Fields
+	case *ast.InterfaceDefinition:
+		return n.Fields
+	}
+	return nil
+}
+
+// Field returns the definition of a field of an object or interface type, or nil if there's none
+func (s *Schema) Field(typeName, fieldName string) *ast.FieldDefinition {
+	for _, f := range s.Fields(typeName) {
+		if f.Name.Value == fieldName {
+			return f
+		}
+	}
+	return nil
+}
+
+// IsLeaf reports whether the named type is a scalar or an enum
+func (s *Schema) IsLeaf(name string) bool {
+	switch s.types[name].(type) {
+	case *ast.ScalarDefinition, *ast.EnumDefinition:
+		return true
+	}
+	return false
+}
+
+// namedType unwraps the list and non-null modifiers of a type
+func namedType(t ast.Type) string {
+	switch n := t.(type) {
+	case *ast.NonNull:
+		return namedType(n.Type)
+	case *ast.List:
+		return namedType(n.Type)
+	case *ast.Named:
+		return n.Name.Value
+	}
+	return ""
+}
@@ -0,0 +1,343 @@
+package fluentgraphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+var (
+	// ErrNotConnection is returned when paginating a selection that was not built with Connection
+	ErrNotConnection = errors.New("fluentgraphql: selection is not a connection")
+	// ErrNestedConnection is returned when paginating a connection within the nodes of another connection
+	ErrNestedConnection = errors.New("fluentgraphql: connection is nested within another connection")
+)
+
+// connection holds what's needed to paginate a Relay connection selection
+type connection struct {
+	field     *ast.Field
+	path      []string
+	pageSize  int
+	backward  bool
+	sizeVar   string
+	cursorVar string
+	// nested is set when the connection is within the nodes of another connection
+	nested bool
+}
+
+// Connection adds a Relay connection to the current selection and returns it.
+// The connection is selected with `first` and `after` arguments bound to variables
+// (`last` and `before` with WithBackwardPagination), which are declared on the operation
+// with pageSize as the default page size. The variables are named after the response keys
+// leading to the connection, and numbered when another connection took these names already.
+// The node callback builds the selection of each node.
+//
+//	repository { issues(first: $repository_issues_first, after: $repository_issues_after) {
+//	  edges { node { ... } } pageInfo { hasNextPage endCursor } totalCount
+//	} }
+func (s *Selection) Connection(fieldName string, pageSize int, node func(node *Selection), options ...SelectionOption) *Selection {
+	conn := &connection{pageSize: pageSize}
+	newS := &Selection{
+		parent: s,
+		node: ast.NewField(&ast.Field{
+			Name:         ast.NewName(&ast.Name{Value: fieldName}),
+			SelectionSet: ast.NewSelectionSet(&ast.SelectionSet{}),
+			Arguments:    make([]*ast.Argument, 0),
+			Directives:   make([]*ast.Directive, 0),
+		}),
+		connection: conn,
+	}
+	addSelection(s.node, newS.node.(*ast.Field))
+
+	for _, option := range options {
+		option(newS)
+	}
+
+	sizeArg, cursorArg := "first", "after"
+	hasMore, cursor := "hasNextPage", "endCursor"
+	if conn.backward {
+		sizeArg, cursorArg = "last", "before"
+		hasMore, cursor = "hasPreviousPage", "startCursor"
+	}
+
+	for current := s; current != nil; current = current.parent {
+		if current.connection != nil {
+			conn.nested = true
+		}
+	}
+	conn.field = newS.node.(*ast.Field)
+	conn.path = newS.path()
+	root := s.Root()
+	op, _ := root.node.(*ast.OperationDefinition)
+	// different paths may join into the same prefix, such as a_b.c and a.b_c
+	base := strings.Join(conn.path, "_")
+	prefix := base
+	for i := 2; declaresVariable(op, prefix+"_"+sizeArg) || declaresVariable(op, prefix+"_"+cursorArg); i++ {
+		prefix = fmt.Sprintf("%s_%d", base, i)
+	}
+	conn.sizeVar = prefix + "_" + sizeArg
+	conn.cursorVar = prefix + "_" + cursorArg
+
+	WithArguments(
+		NewArgument(sizeArg, NewVariableValue(conn.sizeVar)),
+		NewArgument(cursorArg, NewVariableValue(conn.cursorVar)),
+	)(newS)
+
+	if op != nil {
+		op.VariableDefinitions = append(op.VariableDefinitions,
+			NewVariableDefinition(conn.sizeVar, "Int", false, NewIntValue(pageSize)).astVarDef,
+			NewVariableDefinition(conn.cursorVar, "String", false, nil).astVarDef,
+		)
+	}
+	root.connections = append(root.connections, conn)
+
+	nodeS := newS.Selection("edges").Selection("node")
+	if node != nil {
+		node(nodeS)
+	}
+	newS.Selection("pageInfo").Scalar(hasMore).Scalar(cursor)
+	newS.Scalar("totalCount")
+
+	return newS
+}
+
+// declaresVariable tells whether op declares a variable with the given name
+func declaresVariable(op *ast.OperationDefinition, name string) bool {
+	if op == nil {
+		return false
+	}
+	for _, def := range op.VariableDefinitions {
+		if def.Variable.Name.Value == name {
+			return true
+		}
+	}
+	return false
+}
+
+// WithBackwardPagination is a connection option for paginating with `last` and `before`
+func WithBackwardPagination() SelectionOption {
+	return func(s *Selection) {
+		if s.connection != nil {
+			s.connection.backward = true
+		}
+	}
+}
+
+// PageInfo is the pagination information of a connection page
+type PageInfo struct {
+	HasNextPage     bool   `json:"hasNextPage"`
+	HasPreviousPage bool   `json:"hasPreviousPage"`
+	StartCursor     string `json:"startCursor"`
+	EndCursor       string `json:"endCursor"`
+}
+
+// Page is a single page of a connection
+type Page struct {
+	// Path holds the response keys leading to the connection
+	Path       []string
+	Nodes      []json.RawMessage
+	PageInfo   PageInfo
+	TotalCount int
+	Response   *Response
+}
+
+type connectionData struct {
+	Edges []struct {
+		Node json.RawMessage `json:"node"`
+	} `json:"edges"`
+	PageInfo   PageInfo `json:"pageInfo"`
+	TotalCount int      `json:"totalCount"`
+}
+
+// PageIterator iterates over the pages of a connection
+type PageIterator struct {
+	executor  Executor
+	selection *Selection
+	variables map[string]interface{}
+	cursor    string
+	page      *Page
+	done      bool
+	err       error
+}
+
+// Pages returns an iterator that executes the operation of a connection selection,
+// advancing its cursor after each page until there are no more pages.
+// The given variables are sent along with the pagination variables.
+//
+//	it := conn.Pages(executor, nil)
+//	for it.Next(ctx) {
+//		page := it.Page()
+//	}
+//	if err := it.Err(); err != nil { ... }
+func (s *Selection) Pages(executor Executor, variables map[string]interface{}) *PageIterator {
+	it := &PageIterator{
+		executor:  executor,
+		selection: s,
+		variables: variables,
+	}
+	if s.connection == nil {
+		it.done, it.err = true, ErrNotConnection
+	} else if s.connection.nested {
+		it.done, it.err = true, ErrNestedConnection
+	}
+	return it
+}
+
+// Next fetches the next page, returning false when there are no more pages or an error occurred
+func (it *PageIterator) Next(ctx context.Context) bool {
+	if it.done {
+		return false
+	}
+	conn := it.selection.connection
+
+	variables := make(map[string]interface{}, len(it.variables)+2)
+	for name, value := range it.variables {
+		variables[name] = value
+	}
+	variables[conn.sizeVar] = conn.pageSize
+	if it.cursor != "" {
+		variables[conn.cursorVar] = it.cursor
+	}
+
+	res, err := it.executor.Execute(ctx, NewRequest(it.selection, variables))
+	if err != nil {
+		it.done, it.err = true, err
+		return false
+	}
+	page, err := conn.page(res)
+	if err != nil {
+		if _, ok := err.(*noDataError); ok && len(res.Errors) > 0 {
+			err = res.Errors
+		}
+		it.done, it.err = true, err
+		return false
+	}
+
+	next := conn.nextCursor(page.PageInfo)
+	if next == "" {
+		it.done = true
+	} else if next == it.cursor {
+		it.done, it.err = true, fmt.Errorf("fluentgraphql: connection %s did not advance its cursor", strings.Join(conn.path, "."))
+		return false
+	}
+	it.cursor = next
+	it.page = page
+	return true
+}
+
+// Page returns the page fetched by the last call to Next
+func (it *PageIterator) Page() *Page {
+	return it.page
+}
+
+// Err returns the error that stopped the iteration, if any
+func (it *PageIterator) Err() error {
+	return it.err
+}
+
+// page decodes the connection out of a response. When its data is missing, the errors
+// of the response located where the data is missing are returned, or a *noDataError if
+// there are none.
+func (c *connection) page(res *Response) (*Page, error) {
+	raw, err := lookupPath(res.Data, c.path)
+	if err != nil {
+		noData, ok := err.(*noDataError)
+		if !ok {
+			if len(res.Errors) > 0 {
+				return nil, res.Errors
+			}
+			return nil, err
+		}
+		if errs := errorsWithin(res.Errors, noData.path); len(errs) > 0 {
+			return nil, errs
+		}
+		return nil, err
+	}
+
+	var data connectionData
+	if err := json.Unmarshal(raw, &data); err != nil {
+		return nil, err
+	}
+
+	page := &Page{
+		Path:       c.path,
+		Nodes:      make([]json.RawMessage, 0, len(data.Edges)),
+		PageInfo:   data.PageInfo,
+		TotalCount: data.TotalCount,
+		Response:   res,
+	}
+	for _, edge := range data.Edges {
+		page.Nodes = append(page.Nodes, edge.Node)
+	}
+	return page, nil
+}
+
+// nextCursor returns the cursor of the following page, or an empty string if there are no more pages
+func (c *connection) nextCursor(info PageInfo) string {
+	if c.backward {
+		if info.HasPreviousPage {
+			return info.StartCursor
+		}
+		return ""
+	}
+	if info.HasNextPage {
+		return info.EndCursor
+	}
+	return ""
+}
+
+// lookupPath returns the value found by following the response keys of path into data
+func lookupPath(data json.RawMessage, path []string) (json.RawMessage, error) {
+	current := data
+	for i, key := range path {
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(current, &object); err != nil {
+			return nil, err
+		}
+		value, ok := object[key]
+		if !ok || string(value) == "null" {
+			return nil, &noDataError{path: path[:i+1]}
+		}
+		current = value
+	}
+	return current, nil
+}
+
+// errorsWithin returns the errors located at path or below it, along with the errors
+// without a path, which may be located anywhere
+func errorsWithin(errs ResponseErrors, path []string) ResponseErrors {
+	var within ResponseErrors
+	for _, e := range errs {
+		if len(e.Path) == 0 {
+			within = append(within, e)
+			continue
+		}
+		if len(e.Path) < len(path) {
+			continue
+		}
+		matches := true
+		for i, key := range path {
+			if fmt.Sprint(e.Path[i]) != key {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			within = append(within, e)
+		}
+	}
+	return within
+}
+
+// noDataError is returned when a response holds no data at a path
+type noDataError struct {
+	path []string
+}
+
+func (e *noDataError) Error() string {
+	return fmt.Sprintf("fluentgraphql: no data at %s", strings.Join(e.path, "."))
+}
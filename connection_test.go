@@ -0,0 +1,143 @@
+package fluentgraphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestConnections(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		wanted    string
+		selection *Selection
+	}{
+		"Forward": {
+			wanted: `query($issues_first: Int = 10, $issues_after: String) {
+				issues(first: $issues_first, after: $issues_after) {
+					edges { node { title } }
+					pageInfo { hasNextPage endCursor }
+					totalCount
+				}
+			}`,
+			selection: NewQuery().Connection("issues", 10, func(node *Selection) {
+				node.Scalar("title")
+			}),
+		},
+		"Backward": {
+			wanted: `query($issues_last: Int = 10, $issues_before: String) {
+				issues(last: $issues_last, before: $issues_before) {
+					edges { node { title } }
+					pageInfo { hasPreviousPage startCursor }
+					totalCount
+				}
+			}`,
+			selection: NewQuery().Connection("issues", 10, func(node *Selection) {
+				node.Scalar("title")
+			}, WithBackwardPagination()),
+		},
+		"AliasedAndNested": {
+			wanted: `query($repo_0_open_first: Int = 5, $repo_0_open_after: String) {
+				repo_0: repository(owner: "mergestat") {
+					open: issues(states: OPEN, first: $repo_0_open_first, after: $repo_0_open_after) {
+						edges { node { number } }
+						pageInfo { hasNextPage endCursor }
+						totalCount
+					}
+				}
+			}`,
+			selection: NewQuery().
+				Selection("repository", WithAlias("repo_0"), WithArguments(NewArgument("owner", NewStringValue("mergestat")))).
+				Connection("issues", 5, func(node *Selection) {
+					node.Scalar("number")
+				}, WithAlias("open"), WithArguments(NewArgument("states", NewEnumValue("OPEN")))),
+		},
+		"CollidingPaths": {
+			wanted: `query($a_b_c_first: Int = 10, $a_b_c_after: String, $a_b_c_2_first: Int = 10, $a_b_c_2_after: String) {
+				a_b: repository {
+					c: issues(first: $a_b_c_first, after: $a_b_c_after) {
+						edges { node { number } }
+						pageInfo { hasNextPage endCursor }
+						totalCount
+					}
+				}
+				a: repository {
+					b_c: issues(first: $a_b_c_2_first, after: $a_b_c_2_after) {
+						edges { node { number } }
+						pageInfo { hasNextPage endCursor }
+						totalCount
+					}
+				}
+			}`,
+			selection: func() *Selection {
+				q := NewQuery()
+				q.Selection("repository", WithAlias("a_b")).Connection("issues", 10, func(node *Selection) {
+					node.Scalar("number")
+				}, WithAlias("c"))
+				q.Selection("repository", WithAlias("a")).Connection("issues", 10, func(node *Selection) {
+					node.Scalar("number")
+				}, WithAlias("b_c"))
+				return q
+			}(),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			root := testCase.selection.Root()
+			if diff := queryMatchesTree(t, testCase.wanted, root.node); diff != "" {
+				t.Log("produced GraphQL query does not match what's wanted", diff)
+				t.Fatal()
+			}
+		})
+	}
+}
+
+func TestPages(t *testing.T) {
+	conn := NewQuery().Selection("repository").Connection("issues", 2, func(node *Selection) {
+		node.Scalar("number")
+	})
+
+	var cursors []interface{}
+	executor := ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		cursor := req.Variables["repository_issues_after"]
+		cursors = append(cursors, cursor)
+
+		page, hasNext := 1, true
+		if cursor == "c1" {
+			page, hasNext = 2, false
+		}
+		data := fmt.Sprintf(`{"repository": {"issues": {
+			"edges": [{"node": {"number": %d}}],
+			"pageInfo": {"hasNextPage": %v, "endCursor": "c%d"},
+			"totalCount": 2
+		}}}`, page, hasNext, page)
+		return &Response{Data: json.RawMessage(data)}, nil
+	})
+
+	var nodes []string
+	it := conn.Pages(executor, nil)
+	for it.Next(context.Background()) {
+		for _, node := range it.Page().Nodes {
+			nodes = append(nodes, string(node))
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(cursors) != 2 || cursors[0] != nil || cursors[1] != "c1" {
+		t.Fatalf("unexpected cursors sent: %v", cursors)
+	}
+	if len(nodes) != 2 || nodes[0] != `{"number": 1}` || nodes[1] != `{"number": 2}` {
+		t.Fatalf("unexpected nodes: %v", nodes)
+	}
+}
+
+func TestPagesNotConnection(t *testing.T) {
+	it := NewQuery().Selection("repository").Pages(nil, nil)
+	if it.Next(context.Background()) {
+		t.Fatal("expected no pages")
+	}
+	if it.Err() != ErrNotConnection {
+		t.Fatalf("unexpected error: %v", it.Err())
+	}
+}
@@ -0,0 +1,93 @@
+package fluentgraphql
+
+import (
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// directive represents a directive applied to a GraphQL node
+type directive struct {
+	astDirective *ast.Directive
+}
+
+// NewDirective constructs a new directive with arguments
+func NewDirective(name string, args ...*argument) *directive {
+	astArgs := make([]*ast.Argument, 0, len(args))
+	for _, arg := range args {
+		astArgs = append(astArgs, arg.astArg)
+	}
+	return &directive{
+		astDirective: ast.NewDirective(&ast.Directive{
+			Name:      ast.NewName(&ast.Name{Value: name}),
+			Arguments: astArgs,
+		}),
+	}
+}
+
+// Name returns the name of the directive, without the @
+func (d *directive) Name() string {
+	return d.astDirective.Name.Value
+}
+
+// Arguments returns the values of the arguments of the directive by name
+func (d *directive) Arguments() map[string]*Value {
+	return argumentValues(d.astDirective.Arguments)
+}
+
+// WithDirectives is a selection option for specifying directives of a field, fragment or operation.
+// @include(if: $withIssues)
+func WithDirectives(directives ...*directive) SelectionOption {
+	return func(s *Selection) {
+		for _, dir := range directives {
+			switch n := s.node.(type) {
+			case *ast.Field:
+				n.Directives = append(n.Directives, dir.astDirective)
+			case *ast.InlineFragment:
+				n.Directives = append(n.Directives, dir.astDirective)
+			case *ast.FragmentSpread:
+				n.Directives = append(n.Directives, dir.astDirective)
+			case *ast.FragmentDefinition:
+				n.Directives = append(n.Directives, dir.astDirective)
+			case *ast.OperationDefinition:
+				n.Directives = append(n.Directives, dir.astDirective)
+			}
+		}
+	}
+}
+
+// WithDefer defers an inline fragment or fragment spread, for servers supporting incremental
+// delivery to send it after the rest of the response. The label, which identifies the
+// deferred payload, is left out when empty, and so is the condition when nil.
+// ... @defer(label: "issues", if: $deferIssues)
+func WithDefer(label string, condition *Value) SelectionOption {
+	return func(s *Selection) {
+		switch s.node.(type) {
+		case *ast.InlineFragment, *ast.FragmentSpread:
+			WithDirectives(NewDirective("defer", incrementalArguments(label, condition)...))(s)
+		}
+	}
+}
+
+// WithStream streams the items of a list field after the first initialCount ones, for
+// servers supporting incremental delivery. The label is left out when empty, and so is
+// the condition when nil.
+// issues @stream(initialCount: 10, label: "issues")
+func WithStream(initialCount int, label string, condition *Value) SelectionOption {
+	return func(s *Selection) {
+		if _, ok := s.node.(*ast.Field); ok {
+			args := append([]*argument{NewArgument("initialCount", NewIntValue(initialCount))}, incrementalArguments(label, condition)...)
+			WithDirectives(NewDirective("stream", args...))(s)
+		}
+	}
+}
+
+// incrementalArguments returns the label and if arguments of @defer and @stream
+func incrementalArguments(label string, condition *Value) []*argument {
+	var args []*argument
+	if label != "" {
+		args = append(args, NewArgument("label", NewStringValue(label)))
+	}
+	if condition != nil {
+		args = append(args, NewArgument("if", condition))
+	}
+	return args
+}
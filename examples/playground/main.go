@@ -4,7 +4,6 @@ import (
 	"fmt"
 
 	"github.com/graphql-go/graphql/language/ast"
-	"github.com/graphql-go/graphql/language/printer"
 	fgql "github.com/mergestat/fluentgraphql"
 )
 
@@ -78,7 +77,9 @@ func main() {
 		}),
 	})
 
-	// printer.Print(a)
-	fmt.Println(printer.Print(a))
-
+	m, err := fgql.FromAST(a)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(m.Scalar("more").String())
 }
@@ -0,0 +1,398 @@
+package fluentgraphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Request is a GraphQL operation to be sent to a server
+type Request struct {
+	Selection     *Selection
+	OperationName string
+	Variables     map[string]interface{}
+	Extensions    map[string]interface{}
+	// Header holds HTTP headers sent with this request only, taking precedence over those
+	// of the executor
+	Header http.Header
+	// Idempotent marks a mutation as safe to send more than once, for it to be retried
+	Idempotent bool
+}
+
+// NewRequest returns a request for the operation at the root of the selection.
+// The operation name is taken from the operation, if it has one, and the uploads used
+// as values in the operation are added to the variables, see NewUploadValue.
+func NewRequest(s *Selection, variables map[string]interface{}) *Request {
+	root := s.Root()
+	if len(root.uploads) > 0 {
+		withUploads := make(map[string]interface{}, len(variables)+len(root.uploads))
+		for name, value := range variables {
+			withUploads[name] = value
+		}
+		for name, upload := range root.uploads {
+			withUploads[name] = upload
+		}
+		variables = withUploads
+	}
+	return &Request{
+		Selection:     root,
+		OperationName: operationName(root),
+		Variables:     variables,
+	}
+}
+
+// Query returns the printed operation of the request
+func (r *Request) Query() string {
+	return r.Selection.Root().String()
+}
+
+// body returns the request as the JSON object servers expect, leaving out empty members,
+// or the error that keeps its document from being sent, such as a fragment cycle
+func (r *Request) body() (map[string]interface{}, error) {
+	if _, err := r.Selection.Root().Document(); err != nil {
+		return nil, err
+	}
+	body := map[string]interface{}{
+		"query": r.Query(),
+	}
+	if r.OperationName != "" {
+		body["operationName"] = r.OperationName
+	}
+	if len(r.Variables) > 0 {
+		body["variables"] = r.Variables
+	}
+	if len(r.Extensions) > 0 {
+		body["extensions"] = r.Extensions
+	}
+	return body, nil
+}
+
+// Response is a GraphQL response as returned by a server. Executors over HTTP set
+// Header and StatusCode to the headers and status of the HTTP response, which may be
+// a failure, such as a 503 status, when its body is a GraphQL response nonetheless.
+type Response struct {
+	Data       json.RawMessage        `json:"data,omitempty"`
+	Errors     ResponseErrors         `json:"errors,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+	Header     http.Header            `json:"-"`
+	StatusCode int                    `json:"-"`
+}
+
+// ResponseError is an entry of the errors list of a GraphQL response. Type is the kind
+// of error that some servers, such as GitHub's, report, like RATE_LIMITED.
+type ResponseError struct {
+	Message    string                 `json:"message"`
+	Type       string                 `json:"type,omitempty"`
+	Locations  []ErrorLocation        `json:"locations,omitempty"`
+	Path       []interface{}          `json:"path,omitempty"`
+	Extensions map[string]interface{} `json:"extensions,omitempty"`
+}
+
+// ErrorLocation is a location in the operation an error refers to
+type ErrorLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+func (e *ResponseError) Error() string {
+	return e.Message
+}
+
+// ResponseErrors is the errors list of a GraphQL response
+type ResponseErrors []*ResponseError
+
+func (e ResponseErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Executor sends a request to a GraphQL server and returns its response
+type Executor interface {
+	Execute(ctx context.Context, req *Request) (*Response, error)
+}
+
+// ExecutorFunc is an adapter to allow the use of ordinary functions as executors
+type ExecutorFunc func(ctx context.Context, req *Request) (*Response, error)
+
+// Execute calls f(ctx, req)
+func (f ExecutorFunc) Execute(ctx context.Context, req *Request) (*Response, error) {
+	return f(ctx, req)
+}
+
+// HTTPError is returned when a server responds with a non-successful status
+// and a body that is not a GraphQL response
+type HTTPError struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("fluentgraphql: unexpected HTTP status %d", e.StatusCode)
+}
+
+// HTTPExecutor executes requests by POSTing them as JSON to a GraphQL endpoint
+type HTTPExecutor struct {
+	endpoint     string
+	client       *http.Client
+	header       http.Header
+	maxURLLength int
+	persisted    bool
+}
+
+type httpExecutorOption func(*HTTPExecutor)
+
+// NewHTTPExecutor returns an executor for the GraphQL endpoint at the given URL
+func NewHTTPExecutor(endpoint string, options ...httpExecutorOption) *HTTPExecutor {
+	e := &HTTPExecutor{
+		endpoint: endpoint,
+		client:   http.DefaultClient,
+		header:   make(http.Header),
+	}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+// WithHTTPClient specifies the HTTP client used to send requests
+func WithHTTPClient(client *http.Client) httpExecutorOption {
+	return func(e *HTTPExecutor) {
+		e.client = client
+	}
+}
+
+// WithHeader adds a header to every request sent by the executor
+func WithHeader(key, value string) httpExecutorOption {
+	return func(e *HTTPExecutor) {
+		e.header.Add(key, value)
+	}
+}
+
+// WithGET sends queries as GET requests, for CDNs and HTTP caches, with the operation
+// minified and its variables and extensions JSON-encoded in the URL. Requests whose URL
+// would be longer than maxURLLength are POSTed, and so are mutations, subscriptions and
+// uploads, which must not be sent with a GET request.
+func WithGET(maxURLLength int) httpExecutorOption {
+	return func(e *HTTPExecutor) {
+		e.maxURLLength = maxURLLength
+	}
+}
+
+// WithPersistedQueries sends the SHA-256 hash of operations in place of their document,
+// following Apollo's automatic persisted queries. The minified document is sent along with
+// its hash only when the server doesn't know it yet, for the server to store it. Combined
+// with WithGET, this keeps the URLs of queries short. Requests with uploads are always
+// sent with their document.
+func WithPersistedQueries() httpExecutorOption {
+	return func(e *HTTPExecutor) {
+		e.persisted = true
+	}
+}
+
+// Execute sends the request and decodes the GraphQL response
+func (e *HTTPExecutor) Execute(ctx context.Context, req *Request) (*Response, error) {
+	// the files of uploads can only be read once, leaving no second chance to send the document
+	hashOnly := e.persisted && len(findUploads(req.Variables).uploads) == 0
+	response, err := e.execute(ctx, req, hashOnly)
+	if err == nil && hashOnly && persistedQueryMissed(response) {
+		return e.execute(ctx, req, false)
+	}
+	return response, err
+}
+
+// execute sends the request, with only the hash of its operation when hashOnly is set
+func (e *HTTPExecutor) execute(ctx context.Context, req *Request, hashOnly bool) (*Response, error) {
+	httpReq, err := e.newRequest(ctx, req, hashOnly)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "application/json")
+
+	res, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	return decodeResponse(res)
+}
+
+// persistedQueryMissed tells whether the server didn't know the hash of a persisted
+// query, or doesn't support persisted queries
+func persistedQueryMissed(response *Response) bool {
+	for _, err := range response.Errors {
+		switch err.Message {
+		case "PersistedQueryNotFound", "PersistedQueryNotSupported":
+			return true
+		}
+		switch err.Extensions["code"] {
+		case "PERSISTED_QUERY_NOT_FOUND", "PERSISTED_QUERY_NOT_SUPPORTED":
+			return true
+		}
+	}
+	return false
+}
+
+// newRequest returns the HTTP request sending req to the endpoint: a GET request when
+// enabled and possible, a multipart request when its variables hold uploads, and a POST
+// request with a JSON body otherwise
+func (e *HTTPExecutor) newRequest(ctx context.Context, req *Request, hashOnly bool) (*http.Request, error) {
+	body, err := e.body(req, hashOnly)
+	if err != nil {
+		return nil, err
+	}
+	uploads := findUploads(req.Variables)
+	if uploads.err != nil {
+		return nil, uploads.err
+	}
+
+	if e.maxURLLength > 0 && len(uploads.uploads) == 0 && isQuery(req.Selection) {
+		if target, err := getURL(e.endpoint, body); err == nil && len(target) <= e.maxURLLength {
+			httpReq, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+			if err != nil {
+				return nil, err
+			}
+			copyHeader(httpReq.Header, e.header)
+			copyHeader(httpReq.Header, req.Header)
+			return httpReq, nil
+		}
+	}
+
+	var r io.Reader
+	contentType := "application/json"
+	if len(uploads.uploads) > 0 {
+		// files are streamed rather than read in memory
+		pr, pw := io.Pipe()
+		mw := multipart.NewWriter(pw)
+		go func() {
+			pw.CloseWithError(uploads.writeMultipart(mw, body))
+		}()
+		r, contentType = pr, mw.FormDataContentType()
+	} else {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, e.endpoint, r)
+	if err != nil {
+		if c, ok := r.(io.Closer); ok {
+			c.Close()
+		}
+		return nil, err
+	}
+	copyHeader(httpReq.Header, e.header)
+	copyHeader(httpReq.Header, req.Header)
+	httpReq.Header.Set("Content-Type", contentType)
+	return httpReq, nil
+}
+
+// body returns the JSON body of a request. The operation is minified for GET requests and
+// persisted queries, whose hash is added to the extensions, and left out when hashOnly is set.
+func (e *HTTPExecutor) body(req *Request, hashOnly bool) (map[string]interface{}, error) {
+	body, err := req.body()
+	if err != nil {
+		return nil, err
+	}
+	if e.maxURLLength > 0 || e.persisted {
+		body["query"] = req.Selection.Root().Minified()
+	}
+	if e.persisted {
+		extensions := map[string]interface{}{
+			"persistedQuery": map[string]interface{}{
+				"version":    1,
+				"sha256Hash": req.Selection.Root().Hash(),
+			},
+		}
+		for key, value := range req.Extensions {
+			extensions[key] = value
+		}
+		body["extensions"] = extensions
+		if hashOnly {
+			delete(body, "query")
+		}
+	}
+	return body, nil
+}
+
+// getURL returns the URL of a GET request with the members of body as query parameters,
+// objects encoded as JSON
+func getURL(endpoint string, body map[string]interface{}) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	query := u.Query()
+	for key, value := range body {
+		switch value := value.(type) {
+		case string:
+			query.Set(key, value)
+		default:
+			b, err := json.Marshal(value)
+			if err != nil {
+				return "", err
+			}
+			query.Set(key, string(b))
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// isQuery tells whether the selection is rooted in a query, which can be sent with a GET request
+func isQuery(s *Selection) bool {
+	n, ok := s.Root().node.(*ast.OperationDefinition)
+	return ok && n.Operation == ast.OperationTypeQuery
+}
+
+// decodeResponse reads the GraphQL response in the body of an HTTP response, returning an
+// HTTPError when the request failed and the body is something else
+func decodeResponse(res *http.Response) (*Response, error) {
+	resBody, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var response Response
+	if err := json.Unmarshal(resBody, &response); err != nil || (response.Data == nil && response.Errors == nil) {
+		if res.StatusCode >= http.StatusBadRequest {
+			return nil, &HTTPError{StatusCode: res.StatusCode, Header: res.Header, Body: resBody}
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	response.Header = res.Header
+	response.StatusCode = res.StatusCode
+	return &response, nil
+}
+
+// copyHeader sets the values of src in dst, replacing those of the same keys
+func copyHeader(dst, src http.Header) {
+	for key, values := range src {
+		dst[key] = append([]string(nil), values...)
+	}
+}
+
+// operationName returns the name of the operation at the root of the selection, if any
+func operationName(root *Selection) string {
+	if n, ok := root.node.(*ast.OperationDefinition); ok && n.Name != nil {
+		return n.Name.Value
+	}
+	return ""
+}
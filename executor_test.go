@@ -0,0 +1,182 @@
+package fluentgraphql
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// recordedRequest is a request received by a server, with the members of its body or
+// the parameters of its URL
+type recordedRequest struct {
+	Method     string
+	Query      string
+	Operation  string
+	Variables  string
+	Extensions string
+}
+
+// recordingServer records the requests it receives, answering queries whose hash it
+// doesn't know with PersistedQueryNotFound
+func recordingServer(t *testing.T) (*httptest.Server, *[]recordedRequest) {
+	var requests []recordedRequest
+	persisted := make(map[string]bool)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Query         string
+			OperationName string
+			Variables     json.RawMessage
+			Extensions    json.RawMessage
+		}
+		if r.Method == http.MethodGet {
+			params := r.URL.Query()
+			body.Query, body.OperationName = params.Get("query"), params.Get("operationName")
+			body.Variables, body.Extensions = json.RawMessage(params.Get("variables")), json.RawMessage(params.Get("extensions"))
+		} else if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Error(err)
+		}
+		requests = append(requests, recordedRequest{r.Method, body.Query, body.OperationName, string(body.Variables), string(body.Extensions)})
+
+		var extensions struct{ PersistedQuery struct{ Sha256Hash string } }
+		json.Unmarshal(body.Extensions, &extensions)
+		if hash := extensions.PersistedQuery.Sha256Hash; hash != "" {
+			if body.Query == "" && !persisted[hash] {
+				io.WriteString(w, `{"errors":[{"message":"PersistedQueryNotFound","extensions":{"code":"PERSISTED_QUERY_NOT_FOUND"}}]}`)
+				return
+			}
+			persisted[hash] = true
+		}
+		io.WriteString(w, `{"data":{"ok":true}}`)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestHTTPExecutorGET(t *testing.T) {
+	query := NewQuery(WithName("Repo"), WithVariableDefinitions(NewVariableDefinition("owner", "String", true, nil))).
+		Selection("repository", WithArguments(NewArgument("owner", NewVariableValue("owner")))).
+		Scalar("name").
+		Root()
+	mutation := NewMutation().Selection("star", WithArguments(NewArgument("id", NewStringValue("R_1")))).Scalar("count").Root()
+	variables := map[string]interface{}{"owner": "mergestat"}
+	hash := query.Hash()
+
+	type test struct {
+		name     string
+		options  []httpExecutorOption
+		requests []*Request
+		expected []recordedRequest
+	}
+
+	var tests = []test{
+		{
+			name:     "Query",
+			options:  []httpExecutorOption{WithGET(2048)},
+			requests: []*Request{NewRequest(query, variables)},
+			expected: []recordedRequest{
+				{Method: "GET", Query: query.Minified(), Operation: "Repo", Variables: `{"owner":"mergestat"}`},
+			},
+		},
+		{
+			name:     "Mutation",
+			options:  []httpExecutorOption{WithGET(2048)},
+			requests: []*Request{NewRequest(mutation, nil)},
+			expected: []recordedRequest{
+				{Method: "POST", Query: mutation.Minified()},
+			},
+		},
+		{
+			name:     "TooLong",
+			options:  []httpExecutorOption{WithGET(100)},
+			requests: []*Request{NewRequest(query, map[string]interface{}{"owner": strings.Repeat("x", 100)})},
+			expected: []recordedRequest{
+				{Method: "POST", Query: query.Minified(), Operation: "Repo", Variables: `{"owner":"` + strings.Repeat("x", 100) + `"}`},
+			},
+		},
+		{
+			name:     "PersistedQueries",
+			options:  []httpExecutorOption{WithPersistedQueries()},
+			requests: []*Request{NewRequest(query, variables), NewRequest(query, variables)},
+			expected: []recordedRequest{
+				{Method: "POST", Operation: "Repo", Variables: `{"owner":"mergestat"}`, Extensions: `{"persistedQuery":{"sha256Hash":"` + hash + `","version":1}}`},
+				{Method: "POST", Query: query.Minified(), Operation: "Repo", Variables: `{"owner":"mergestat"}`, Extensions: `{"persistedQuery":{"sha256Hash":"` + hash + `","version":1}}`},
+				{Method: "POST", Operation: "Repo", Variables: `{"owner":"mergestat"}`, Extensions: `{"persistedQuery":{"sha256Hash":"` + hash + `","version":1}}`},
+			},
+		},
+		{
+			name:     "PersistedQueriesWithGET",
+			options:  []httpExecutorOption{WithPersistedQueries(), WithGET(2048)},
+			requests: []*Request{NewRequest(query, variables), NewRequest(query, variables)},
+			expected: []recordedRequest{
+				{Method: "GET", Operation: "Repo", Variables: `{"owner":"mergestat"}`, Extensions: `{"persistedQuery":{"sha256Hash":"` + hash + `","version":1}}`},
+				{Method: "GET", Query: query.Minified(), Operation: "Repo", Variables: `{"owner":"mergestat"}`, Extensions: `{"persistedQuery":{"sha256Hash":"` + hash + `","version":1}}`},
+				{Method: "GET", Operation: "Repo", Variables: `{"owner":"mergestat"}`, Extensions: `{"persistedQuery":{"sha256Hash":"` + hash + `","version":1}}`},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server, requests := recordingServer(t)
+			executor := NewHTTPExecutor(server.URL, test.options...)
+			for _, req := range test.requests {
+				response, err := executor.Execute(context.Background(), req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(response.Data) != `{"ok":true}` || len(response.Errors) > 0 {
+					t.Errorf("unexpected response %s %v", response.Data, response.Errors)
+				}
+			}
+			if diff := cmp.Diff(test.expected, *requests); diff != "" {
+				t.Errorf("unexpected requests (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestHTTPExecutorPersistedUpload(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+			return
+		}
+		var operations struct{ Query string }
+		json.Unmarshal([]byte(r.FormValue("operations")), &operations)
+		file, _, err := r.FormFile("0")
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		content, _ := io.ReadAll(file)
+		received = append(received, operations.Query+" "+string(content))
+		if operations.Query == "" {
+			io.WriteString(w, `{"errors":[{"message":"PersistedQueryNotFound"}]}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"ok":true}}`)
+	}))
+	defer server.Close()
+
+	mutation := NewMutation(WithVariableDefinitions(NewVariableDefinition("file", "Upload", true, nil))).
+		Scalar("upload", WithArguments(NewArgument("file", NewVariableValue("file")))).
+		Root()
+	req := NewRequest(mutation, map[string]interface{}{"file": NewUpload(strings.NewReader("CONTENT"), "build")})
+	res, err := NewHTTPExecutor(server.URL, WithPersistedQueries()).Execute(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res.Data) != `{"ok":true}` {
+		t.Errorf("unexpected response %s %v", res.Data, res.Errors)
+	}
+	if diff := cmp.Diff([]string{mutation.Minified() + " CONTENT"}, received); diff != "" {
+		t.Errorf("unexpected requests (-want +got):\n%s", diff)
+	}
+}
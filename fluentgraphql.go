@@ -1,6 +1,8 @@
 package fluentgraphql
 
 import (
+	"strings"
+
 	"github.com/graphql-go/graphql/language/ast"
 	"github.com/graphql-go/graphql/language/printer"
 )
@@ -8,11 +10,24 @@ import (
 type Selection struct {
 	parent *Selection
 	node   ast.Node
+
+	// connection is set when the selection is a Relay connection
+	connection *connection
+	// connections registers every connection built under a root selection
+	connections []*connection
+	// fragments holds the fragment definitions printed along with a root selection
+	fragments []*ast.FragmentDefinition
+	// spreads holds the fragments built with NewFragment that are spread under a root selection
+	spreads []*Selection
+	// typenames adds __typename to the object selections of a root selection when printed
+	typenames *typenamer
+	// uploads holds the uploads used as values under a root selection, by variable name
+	uploads map[string]*Upload
 }
 
 // NewQuery returns a selection builder for a new GraphQL query.
 // query { ... }
-func NewQuery(options ...operationOption) *Selection {
+func NewQuery(options ...OperationOption) *Selection {
 	s := &Selection{
 		parent: nil,
 		node: ast.NewOperationDefinition(&ast.OperationDefinition{
@@ -30,7 +45,7 @@ func NewQuery(options ...operationOption) *Selection {
 
 // NewMutation returns a selection builder for a new GraphQL mutation.
 // mutation { ... }
-func NewMutation(options ...operationOption) *Selection {
+func NewMutation(options ...OperationOption) *Selection {
 	s := &Selection{
 		parent: nil,
 		node: ast.NewOperationDefinition(&ast.OperationDefinition{
@@ -46,10 +61,29 @@ func NewMutation(options ...operationOption) *Selection {
 	return s
 }
 
-type operationOption selectionOption
+// NewSubscription returns a selection builder for a new GraphQL subscription.
+// subscription { ... }
+func NewSubscription(options ...OperationOption) *Selection {
+	s := &Selection{
+		parent: nil,
+		node: ast.NewOperationDefinition(&ast.OperationDefinition{
+			Operation:           ast.OperationTypeSubscription,
+			VariableDefinitions: make([]*ast.VariableDefinition, 0),
+			Directives:          make([]*ast.Directive, 0),
+			SelectionSet:        ast.NewSelectionSet(&ast.SelectionSet{}),
+		}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// OperationOption enables options for an operation
+type OperationOption SelectionOption
 
 // WithName specifies a name for the operation
-func WithName(name string) operationOption {
+func WithName(name string) OperationOption {
 	return func(s *Selection) {
 		switch n := s.node.(type) {
 		case *ast.OperationDefinition:
@@ -97,7 +131,7 @@ func NewVariableDefinition(name string, varType string, required bool, defaultVa
 }
 
 // WithVariableDefinitions is an operation option for declaring variable definitions
-func WithVariableDefinitions(vars ...*variableDefinition) operationOption {
+func WithVariableDefinitions(vars ...*variableDefinition) OperationOption {
 	return func(s *Selection) {
 		switch n := s.node.(type) {
 		case *ast.OperationDefinition:
@@ -111,24 +145,16 @@ func WithVariableDefinitions(vars ...*variableDefinition) operationOption {
 }
 
 // Scalar adds a scalar field to the current selection
-func (s *Selection) Scalar(fieldName string, options ...selectionOption) *Selection {
+func (s *Selection) Scalar(fieldName string, options ...SelectionOption) *Selection {
 	newS := &Selection{
+		parent: s,
 		node: ast.NewField(&ast.Field{
 			Name:       ast.NewName(&ast.Name{Value: fieldName}),
 			Arguments:  make([]*ast.Argument, 0),
 			Directives: make([]*ast.Directive, 0),
 		}),
 	}
-	switch n := s.node.(type) {
-	case *ast.OperationDefinition:
-		n.SelectionSet.Selections = append(n.SelectionSet.Selections, newS.node.(*ast.Field))
-	case *ast.Field:
-		n.SelectionSet.Selections = append(n.SelectionSet.Selections, newS.node.(*ast.Field))
-	case *ast.InlineFragment:
-		n.SelectionSet.Selections = append(n.SelectionSet.Selections, newS.node.(*ast.Field))
-	case *ast.FragmentDefinition:
-		n.SelectionSet.Selections = append(n.SelectionSet.Selections, newS.node.(*ast.Field))
-	}
+	addSelection(s.node, newS.node.(*ast.Field))
 
 	for _, option := range options {
 		option(newS)
@@ -138,7 +164,7 @@ func (s *Selection) Scalar(fieldName string, options ...selectionOption) *Select
 }
 
 // Selection adds a subselection to the current selection
-func (s *Selection) Selection(fieldName string, options ...selectionOption) *Selection {
+func (s *Selection) Selection(fieldName string, options ...SelectionOption) *Selection {
 	newS := &Selection{
 		parent: s,
 		node: ast.NewField(&ast.Field{
@@ -148,16 +174,7 @@ func (s *Selection) Selection(fieldName string, options ...selectionOption) *Sel
 			Directives:   make([]*ast.Directive, 0),
 		}),
 	}
-	switch n := s.node.(type) {
-	case *ast.OperationDefinition:
-		n.SelectionSet.Selections = append(n.SelectionSet.Selections, newS.node.(*ast.Field))
-	case *ast.Field:
-		n.SelectionSet.Selections = append(n.SelectionSet.Selections, newS.node.(*ast.Field))
-	case *ast.InlineFragment:
-		n.SelectionSet.Selections = append(n.SelectionSet.Selections, newS.node.(*ast.Field))
-	case *ast.FragmentDefinition:
-		n.SelectionSet.Selections = append(n.SelectionSet.Selections, newS.node.(*ast.Field))
-	}
+	addSelection(s.node, newS.node.(*ast.Field))
 
 	for _, option := range options {
 		option(newS)
@@ -166,19 +183,25 @@ func (s *Selection) Selection(fieldName string, options ...selectionOption) *Sel
 	return newS
 }
 
-// InlineFragment adds an inline fragment to the current selection
-func (s *Selection) InlineFragment(typeCondition string) *Selection {
+// InlineFragment adds an inline fragment to the current selection.
+// The type condition is left out when typeCondition is empty.
+// ... on typeCondition { ... }
+func (s *Selection) InlineFragment(typeCondition string, options ...SelectionOption) *Selection {
 	newFrag := ast.NewInlineFragment((&ast.InlineFragment{
-		TypeCondition: ast.NewNamed(&ast.Named{Name: ast.NewName(&ast.Name{Value: typeCondition})}),
-		SelectionSet:  ast.NewSelectionSet(&ast.SelectionSet{}),
+		Directives:   make([]*ast.Directive, 0),
+		SelectionSet: ast.NewSelectionSet(&ast.SelectionSet{}),
 	}))
+	if typeCondition != "" {
+		newFrag.TypeCondition = ast.NewNamed(&ast.Named{Name: ast.NewName(&ast.Name{Value: typeCondition})})
+	}
 	newS := &Selection{
 		parent: s,
 		node:   newFrag,
 	}
-	switch n := s.node.(type) {
-	case *ast.Field:
-		n.SelectionSet.Selections = append(n.SelectionSet.Selections, newS.node.(*ast.InlineFragment))
+	addSelection(s.node, newFrag)
+
+	for _, option := range options {
+		option(newS)
 	}
 
 	return newS
@@ -206,19 +229,19 @@ func (s *Selection) Fragment(name, typeCondition string) *Selection {
 }
 
 // FragmentSpread adds a fragement spread
-func (s *Selection) FragmentSpread(name string) *Selection {
+func (s *Selection) FragmentSpread(name string, options ...SelectionOption) *Selection {
 	newFrag := ast.NewFragmentSpread((&ast.FragmentSpread{
-		Name: ast.NewName(&ast.Name{Value: name}),
+		Name:       ast.NewName(&ast.Name{Value: name}),
+		Directives: make([]*ast.Directive, 0),
 	}))
 	newS := &Selection{
 		parent: s,
 		node:   newFrag,
 	}
-	switch n := s.node.(type) {
-	case *ast.Field:
-		n.SelectionSet.Selections = append(n.SelectionSet.Selections, newS.node.(*ast.FragmentSpread))
-	case *ast.OperationDefinition:
-		n.SelectionSet.Selections = append(n.SelectionSet.Selections, newS.node.(*ast.FragmentSpread))
+	addSelection(s.node, newFrag)
+
+	for _, option := range options {
+		option(newS)
 	}
 
 	return s
@@ -244,16 +267,135 @@ func (s *Selection) Root() *Selection {
 	}
 }
 
-// String returns the selection as a GraphQL query string
+// Kind returns the kind of the selection, one of the graphql-go kinds OperationDefinition,
+// Field, InlineFragment, FragmentSpread or FragmentDefinition
+func (s *Selection) Kind() string {
+	return s.node.GetKind()
+}
+
+// Name returns the name of a field, of an operation, or of the fragment of a fragment
+// spread or definition. It's empty for inline fragments and anonymous operations.
+func (s *Selection) Name() string {
+	switch n := s.node.(type) {
+	case *ast.Field:
+		return n.Name.Value
+	case *ast.OperationDefinition:
+		if n.Name != nil {
+			return n.Name.Value
+		}
+	case *ast.FragmentSpread:
+		return n.Name.Value
+	case *ast.FragmentDefinition:
+		return n.Name.Value
+	}
+	return ""
+}
+
+// Alias returns the alias of a field, which is empty when the field has none
+func (s *Selection) Alias() string {
+	if f, ok := s.node.(*ast.Field); ok && f.Alias != nil {
+		return f.Alias.Value
+	}
+	return ""
+}
+
+// ResponseKey returns the key a field is found at in a response: its alias, or its name.
+// It's empty for selections that aren't fields.
+func (s *Selection) ResponseKey() string {
+	if f, ok := s.node.(*ast.Field); ok {
+		return responseKey(f)
+	}
+	return ""
+}
+
+// TypeCondition returns the type condition of an inline fragment or fragment definition,
+// which is empty when the inline fragment has none
+func (s *Selection) TypeCondition() string {
+	switch n := s.node.(type) {
+	case *ast.InlineFragment:
+		if n.TypeCondition != nil {
+			return n.TypeCondition.Name.Value
+		}
+	case *ast.FragmentDefinition:
+		return n.TypeCondition.Name.Value
+	}
+	return ""
+}
+
+// Arguments returns the values of the arguments of a field by name
+func (s *Selection) Arguments() map[string]*Value {
+	if f, ok := s.node.(*ast.Field); ok {
+		return argumentValues(f.Arguments)
+	}
+	return map[string]*Value{}
+}
+
+// Directives returns the directives of the selection, in order
+func (s *Selection) Directives() []*directive {
+	var astDirectives []*ast.Directive
+	switch n := s.node.(type) {
+	case *ast.OperationDefinition:
+		astDirectives = n.Directives
+	case *ast.Field:
+		astDirectives = n.Directives
+	case *ast.InlineFragment:
+		astDirectives = n.Directives
+	case *ast.FragmentSpread:
+		astDirectives = n.Directives
+	case *ast.FragmentDefinition:
+		astDirectives = n.Directives
+	}
+	directives := make([]*directive, 0, len(astDirectives))
+	for _, d := range astDirectives {
+		directives = append(directives, &directive{astDirective: d})
+	}
+	return directives
+}
+
+// Children returns the selections within the selection, in order. Selections built on
+// the children are added to the same tree.
+func (s *Selection) Children() []*Selection {
+	var set *ast.SelectionSet
+	switch n := s.node.(type) {
+	case *ast.OperationDefinition:
+		set = n.SelectionSet
+	case *ast.Field:
+		set = n.SelectionSet
+	case *ast.InlineFragment:
+		set = n.SelectionSet
+	case *ast.FragmentDefinition:
+		set = n.SelectionSet
+	}
+	if set == nil {
+		return []*Selection{}
+	}
+	children := make([]*Selection, 0, len(set.Selections))
+	for _, selection := range set.Selections {
+		children = append(children, &Selection{parent: s, node: selection.(ast.Node)})
+	}
+	return children
+}
+
+// String returns the selection as a GraphQL query string. It prints the selection even
+// when its document can't be sent, such as with fragments spreading each other in a
+// cycle or sharing a name, which Document reports, as do executors sending it.
 func (s *Selection) String() string {
-	return printer.Print(s.node).(string)
+	if typed, err := s.typed(); err == nil {
+		s = typed
+	}
+	definitions, _ := s.definitions()
+	if len(definitions) == 1 {
+		return printer.Print(s.node).(string)
+	}
+	doc := ast.NewDocument(&ast.Document{Definitions: definitions})
+	return strings.TrimSuffix(printer.Print(doc).(string), "\n")
 }
 
-// selectionOption enables options for a selection
-type selectionOption func(*Selection)
+// SelectionOption enables options for a selection
+type SelectionOption func(*Selection)
 
 // WithAlias is an option for specifying a selection alias
-func WithAlias(alias string) selectionOption {
+func WithAlias(alias string) SelectionOption {
 	return func(s *Selection) {
 		switch n := s.node.(type) {
 		case *ast.Field:
@@ -263,3 +405,44 @@ func WithAlias(alias string) selectionOption {
 		}
 	}
 }
+
+// path returns the response keys of the fields from the root to this selection
+func (s *Selection) path() []string {
+	var path []string
+	for current := s; current != nil; current = current.parent {
+		if f, ok := current.node.(*ast.Field); ok {
+			path = append([]string{responseKey(f)}, path...)
+		}
+	}
+	return path
+}
+
+// responseKey returns the key a field is found at in a response
+func responseKey(f *ast.Field) string {
+	if f.Alias != nil && f.Alias.Value != "" {
+		return f.Alias.Value
+	}
+	return f.Name.Value
+}
+
+// addSelection appends a selection to the selection set of a node
+func addSelection(parent ast.Node, selection ast.Selection) {
+	var set **ast.SelectionSet
+	switch n := parent.(type) {
+	case *ast.OperationDefinition:
+		set = &n.SelectionSet
+	case *ast.Field:
+		set = &n.SelectionSet
+	case *ast.InlineFragment:
+		set = &n.SelectionSet
+	case *ast.FragmentDefinition:
+		set = &n.SelectionSet
+	default:
+		return
+	}
+	// nodes from elsewhere, such as parsed scalar fields, may have no selection set yet
+	if *set == nil {
+		*set = ast.NewSelectionSet(&ast.SelectionSet{})
+	}
+	(*set).Selections = append((*set).Selections, selection)
+}
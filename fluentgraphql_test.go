@@ -6,6 +6,7 @@ import (
 	"github.com/google/go-cmp/cmp"
 	"github.com/graphql-go/graphql/language/ast"
 	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
 )
 
 func queryMatchesTree(t *testing.T, query string, root ast.Node) string {
@@ -98,6 +99,43 @@ func TestSelections(t *testing.T) {
 			wanted:    `{ hello { world } }`,
 			selection: NewQuery().Selection("hello").Scalar("world"),
 		},
+		"InlineFragmentOnOperation": {
+			wanted:    `{ ... on Query { hello } }`,
+			selection: NewQuery().InlineFragment("Query").Scalar("hello"),
+		},
+		"NestedInlineFragments": {
+			wanted:    `{ node { ... on Node { ... on User { login } } } }`,
+			selection: NewQuery().Selection("node").InlineFragment("Node").InlineFragment("User").Scalar("login"),
+		},
+		"InlineFragmentWithoutTypeCondition": {
+			wanted: `query($withIssues: Boolean!) { repository { ... @include(if: $withIssues) { issues { totalCount } } } }`,
+			selection: NewQuery(WithVariableDefinitions(NewVariableDefinition("withIssues", "Boolean", true, nil))).
+				Selection("repository").
+				InlineFragment("", WithDirectives(NewDirective("include", NewArgument("if", NewVariableValue("withIssues"))))).
+				Selection("issues").Scalar("totalCount"),
+		},
+		"Directives": {
+			wanted: `{ hello @skip(if: true) ...worldFields @include(if: false) }`,
+			selection: NewQuery().
+				Scalar("hello", WithDirectives(NewDirective("skip", NewArgument("if", NewBooleanValue(true))))).
+				FragmentSpread("worldFields", WithDirectives(NewDirective("include", NewArgument("if", NewBooleanValue(false))))),
+		},
+		"Defer": {
+			wanted: `query($deferIssues: Boolean!) { repository { name ...ownerFields @defer ... @defer(label: "issues", if: $deferIssues) { issues { totalCount } } } }`,
+			selection: NewQuery(WithVariableDefinitions(NewVariableDefinition("deferIssues", "Boolean", true, nil))).
+				Selection("repository").
+				Scalar("name").
+				FragmentSpread("ownerFields", WithDefer("", nil)).
+				InlineFragment("", WithDefer("issues", NewVariableValue("deferIssues"))).
+				Selection("issues").Scalar("totalCount"),
+		},
+		"Stream": {
+			wanted: `{ repository { issues(first: 100) @stream(initialCount: 10, label: "issues") { title } stargazers @stream(initialCount: 0) { login } } }`,
+			selection: NewQuery().
+				Selection("repository").
+				Selection("issues", WithArguments(NewArgument("first", NewIntValue(100))), WithStream(10, "issues", nil)).Scalar("title").Parent().
+				Selection("stargazers", WithStream(0, "", nil)).Scalar("login"),
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			root := testCase.selection.Root()
@@ -109,6 +147,16 @@ func TestSelections(t *testing.T) {
 	}
 }
 
+func TestInlineFragmentInFragmentDefinition(t *testing.T) {
+	fragment := NewQuery().Fragment("userFields", "Actor")
+	fragment.InlineFragment("User").Scalar("login")
+
+	wanted := "fragment userFields on Actor {\n  ... on User {\n    login\n  }\n}"
+	if diff := cmp.Diff(wanted, printer.Print(fragment.node)); diff != "" {
+		t.Fatal("produced fragment does not match what's wanted", diff)
+	}
+}
+
 func TestMutations(t *testing.T) {
 	for name, testCase := range map[string]struct {
 		wanted    string
@@ -157,6 +205,10 @@ func TestMutations(t *testing.T) {
 			wanted:    `mutation SomeName { hello }`,
 			selection: NewMutation(WithName("SomeName")).Scalar("hello"),
 		},
+		"Subscription": {
+			wanted:    `subscription OnHello($id: ID!) { hello(id: $id) { world } }`,
+			selection: NewSubscription(WithName("OnHello"), WithVariableDefinitions(NewVariableDefinition("id", "ID", true, nil))).Selection("hello", WithArguments(NewArgument("id", NewVariableValue("id")))).Scalar("world"),
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			root := testCase.selection.Root()
@@ -167,3 +219,86 @@ func TestMutations(t *testing.T) {
 		})
 	}
 }
+
+func TestParse(t *testing.T) {
+	document := `query Hero($episode: Episode) {
+  hero(episode: $episode) {
+    ...heroFields
+  }
+}
+
+fragment heroFields on Character {
+  name
+}`
+
+	s, err := Parse(document)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.String() != document {
+		t.Fatalf("parsed document does not print as the original: %s", s.String())
+	}
+	if name := operationName(s); name != "Hero" {
+		t.Fatalf("unexpected operation name: %s", name)
+	}
+}
+
+func TestAccessors(t *testing.T) {
+	q, err := Parse(`query Issues($states: [IssueState!]) @cached {
+		repo: repository(owner: "mergestat", stars: 10, ratio: 0.5, archived: false, states: $states, filter: { labels: ["bug"], state: OPEN }) @include(if: true) {
+			name
+			... on Repository { ...issueFields }
+		}
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if q.Kind() != "OperationDefinition" || q.Name() != "Issues" || len(q.Directives()) != 1 || q.Directives()[0].Name() != "cached" {
+		t.Fatalf("unexpected operation %s %q with directives %v", q.Kind(), q.Name(), q.Directives())
+	}
+
+	repo := q.Children()[0]
+	if repo.Kind() != "Field" || repo.Name() != "repository" || repo.Alias() != "repo" || repo.ResponseKey() != "repo" {
+		t.Fatalf("unexpected field %s %q aliased %q", repo.Kind(), repo.Name(), repo.Alias())
+	}
+	if repo.Parent() != q {
+		t.Fatal("expected the parent of a child to be the selection it was returned by")
+	}
+
+	arguments := make(map[string]interface{})
+	for name, value := range repo.Arguments() {
+		arguments[name] = value.Interface()
+	}
+	wanted := map[string]interface{}{
+		"owner":    "mergestat",
+		"stars":    10,
+		"ratio":    0.5,
+		"archived": false,
+		"states":   nil,
+		"filter":   map[string]interface{}{"labels": []interface{}{"bug"}, "state": "OPEN"},
+	}
+	if diff := cmp.Diff(wanted, arguments); diff != "" {
+		t.Fatal("arguments do not match what's wanted", diff)
+	}
+	if states := repo.Arguments()["states"]; states.Kind() != "Variable" || states.String() != "$states" {
+		t.Fatalf("unexpected variable %s %s", states.Kind(), states)
+	}
+
+	directives := repo.Directives()
+	if len(directives) != 1 || directives[0].Name() != "include" || directives[0].Arguments()["if"].Interface() != true {
+		t.Fatalf("unexpected directives %v", directives)
+	}
+
+	children := repo.Children()
+	if len(children) != 2 || children[0].Name() != "name" || len(children[0].Children()) != 0 {
+		t.Fatalf("unexpected children %v", children)
+	}
+	fragment := children[1]
+	if fragment.Kind() != "InlineFragment" || fragment.TypeCondition() != "Repository" || fragment.ResponseKey() != "" {
+		t.Fatalf("unexpected inline fragment %s on %q", fragment.Kind(), fragment.TypeCondition())
+	}
+	if spread := fragment.Children()[0]; spread.Kind() != "FragmentSpread" || spread.Name() != "issueFields" {
+		t.Fatalf("unexpected fragment spread %s %q", spread.Kind(), spread.Name())
+	}
+}
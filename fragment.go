@@ -0,0 +1,200 @@
+package fluentgraphql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// NewFragment returns a selection builder for a new fragment definition, which can be
+// spread into any number of operations, and into other fragments, with Spread.
+// fragment name on typeCondition { ... }
+func NewFragment(name, typeCondition string, options ...SelectionOption) *Selection {
+	s := &Selection{
+		node: ast.NewFragmentDefinition(&ast.FragmentDefinition{
+			Name:          ast.NewName(&ast.Name{Value: name}),
+			TypeCondition: ast.NewNamed(&ast.Named{Name: ast.NewName(&ast.Name{Value: typeCondition})}),
+			Directives:    make([]*ast.Directive, 0),
+			SelectionSet:  ast.NewSelectionSet(&ast.SelectionSet{}),
+		}),
+	}
+	for _, option := range options {
+		option(s)
+	}
+	return s
+}
+
+// Spread adds a spread of a fragment built with NewFragment to the current selection.
+// The fragment, and the fragments it spreads in turn, are printed once along with the operation.
+// ...name
+func (s *Selection) Spread(fragment *Selection, options ...SelectionOption) *Selection {
+	fragment = fragment.Root()
+	def, ok := fragment.node.(*ast.FragmentDefinition)
+	if !ok {
+		return s
+	}
+	s.FragmentSpread(def.Name.Value, options...)
+
+	root := s.Root()
+	for _, spread := range root.spreads {
+		if spread == fragment {
+			return s
+		}
+	}
+	root.spreads = append(root.spreads, fragment)
+	return s
+}
+
+// Document returns the selection as a GraphQL document, along with the definitions of
+// the fragments it spreads. An error is returned when fragments spread each other in
+// a cycle, or when different fragments share a name.
+func (s *Selection) Document() (*ast.Document, error) {
+	s, err := s.typed()
+	if err != nil {
+		return nil, err
+	}
+	definitions, err := s.definitions()
+	if err != nil {
+		return nil, err
+	}
+	return ast.NewDocument(&ast.Document{Definitions: definitions}), nil
+}
+
+// Validate validates the document of the selection against a schema, such as one built
+// with BuildSchema or loaded with LoadIntrospection. This catches fragments spread where
+// their type condition can never apply, along with everything else the GraphQL
+// specification validates.
+func (s *Selection) Validate(schema graphql.Schema) error {
+	doc, err := s.Document()
+	if err != nil {
+		return err
+	}
+	result := graphql.ValidateDocument(&schema, doc, nil)
+	if !result.IsValid {
+		return &ValidationError{Errors: result.Errors}
+	}
+	return nil
+}
+
+// ValidationError lists the reasons a document is invalid against a schema
+type ValidationError struct {
+	Errors []gqlerrors.FormattedError
+}
+
+func (e *ValidationError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, err := range e.Errors {
+		messages = append(messages, err.Message)
+	}
+	return "fluentgraphql: invalid document: " + strings.Join(messages, "; ")
+}
+
+// definitions returns the node of the selection followed by the fragment definitions it
+// depends on, each once, in the order they are first spread. It always returns every
+// definition it found, so that the document can be printed even when it is invalid.
+func (s *Selection) definitions() ([]ast.Node, error) {
+	var err error
+	// seen maps the names of the fragments to their definitions, parsed ones included
+	seen := make(map[string]ast.Node)
+	definitions := []ast.Node{s.node}
+	for _, f := range s.fragments {
+		if _, ok := seen[f.Name.Value]; ok && err == nil {
+			err = fmt.Errorf("fluentgraphql: different fragments named %s", f.Name.Value)
+		}
+		seen[f.Name.Value] = f
+		definitions = append(definitions, f)
+	}
+
+	var visit func(f *Selection, stack []string)
+	visit = func(f *Selection, stack []string) {
+		name := f.node.(*ast.FragmentDefinition).Name.Value
+		for i, spread := range stack {
+			if spread == name && err == nil {
+				err = fmt.Errorf("fluentgraphql: fragment cycle %s -> %s", strings.Join(stack[i:], " -> "), name)
+			}
+		}
+		if other, ok := seen[name]; ok {
+			if other != f.node && err == nil {
+				err = fmt.Errorf("fluentgraphql: different fragments named %s", name)
+			}
+			return
+		}
+		seen[name] = f.node
+		definitions = append(definitions, f.node)
+		stack = append(stack[:len(stack):len(stack)], name)
+		for _, spread := range f.spreads {
+			visit(spread, stack)
+		}
+	}
+	for _, f := range s.spreads {
+		visit(f, nil)
+	}
+
+	return definitions, err
+}
+
+// derive returns a root selection for op, an operation derived from the one of s, such as
+// a copy selecting fewer fields. It keeps the typenames asked for with WithTypenames, and
+// the fragments of s that op still spreads, directly or through other fragments.
+func (s *Selection) derive(op *ast.OperationDefinition) *Selection {
+	derived := &Selection{node: op, typenames: s.typenames}
+
+	fragments := make(map[string]ast.Node)
+	for _, f := range s.fragments {
+		fragments[f.Name.Value] = f
+	}
+	var register func(f *Selection)
+	register = func(f *Selection) {
+		name := f.node.(*ast.FragmentDefinition).Name.Value
+		if _, ok := fragments[name]; ok {
+			return
+		}
+		fragments[name] = f.node
+		for _, spread := range f.spreads {
+			register(spread)
+		}
+	}
+	for _, f := range s.spreads {
+		register(f)
+	}
+
+	spread := make(map[string]bool)
+	var collect func(set *ast.SelectionSet)
+	collect = func(set *ast.SelectionSet) {
+		if set == nil {
+			return
+		}
+		for _, selection := range set.Selections {
+			switch n := selection.(type) {
+			case *ast.Field:
+				collect(n.SelectionSet)
+			case *ast.InlineFragment:
+				collect(n.SelectionSet)
+			case *ast.FragmentSpread:
+				if spread[n.Name.Value] {
+					continue
+				}
+				spread[n.Name.Value] = true
+				if f, ok := fragments[n.Name.Value].(*ast.FragmentDefinition); ok {
+					collect(f.SelectionSet)
+				}
+			}
+		}
+	}
+	collect(op.SelectionSet)
+
+	for _, f := range s.fragments {
+		if spread[f.Name.Value] {
+			derived.fragments = append(derived.fragments, f)
+		}
+	}
+	for _, f := range s.spreads {
+		if spread[f.node.(*ast.FragmentDefinition).Name.Value] {
+			derived.spreads = append(derived.spreads, f)
+		}
+	}
+	return derived
+}
@@ -0,0 +1,122 @@
+package fluentgraphql
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func TestFragments(t *testing.T) {
+	friends := NewFragment("friendFields", "Character")
+	friends.Selection("friends").Scalar("name")
+
+	comparison := NewFragment("comparisonFields", "Character")
+	comparison.Scalar("name").Spread(friends)
+
+	q := NewQuery(WithName("HeroComparison")).
+		Selection("hero", WithAlias("leftComparison")).Spread(comparison).
+		Parent().
+		Selection("hero", WithAlias("rightComparison")).Spread(comparison).Spread(friends).
+		Root()
+
+	wanted, err := parser.Parse(parser.ParseParams{
+		Source: `query HeroComparison {
+			leftComparison: hero { ...comparisonFields }
+			rightComparison: hero { ...comparisonFields ...friendFields }
+		}
+		fragment comparisonFields on Character { name ...friendFields }
+		fragment friendFields on Character { friends { name } }`,
+		Options: parser.ParseOptions{NoSource: true, NoLocation: true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := q.Document()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(wanted, doc); diff != "" {
+		t.Fatal("produced document does not match what's wanted", diff)
+	}
+
+	// fragments are spread into other operations independently
+	other := NewQuery().Selection("hero").Spread(friends).Root().String()
+	if diff := cmp.Diff("{\n  hero {\n    ...friendFields\n  }\n}\n\nfragment friendFields on Character {\n  friends {\n    name\n  }\n}", other); diff != "" {
+		t.Fatal("produced query does not match what's wanted", diff)
+	}
+}
+
+func TestFragmentErrors(t *testing.T) {
+	a := NewFragment("a", "Character")
+	b := NewFragment("b", "Character")
+	a.Spread(b)
+	b.Selection("friends").Spread(a)
+
+	if _, err := NewQuery().Selection("hero").Spread(a).Root().Document(); err == nil || err.Error() != "fluentgraphql: fragment cycle a -> b -> a" {
+		t.Fatalf("expected a fragment cycle error, got %v", err)
+	}
+
+	_, err := NewQuery().
+		Selection("hero").Spread(NewFragment("fields", "Character")).
+		Parent().
+		Selection("villain").Spread(NewFragment("fields", "Character")).
+		Root().Document()
+	if err == nil || err.Error() != "fluentgraphql: different fragments named fields" {
+		t.Fatalf("expected a fragment name conflict error, got %v", err)
+	}
+
+	parsed, err := Parse(`{ hero { ...fields } } fragment fields on Character { name }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed.Selection("villain").Spread(NewFragment("fields", "Character"))
+	if _, err := parsed.Document(); err == nil || err.Error() != "fluentgraphql: different fragments named fields" {
+		t.Fatalf("expected a conflict with the parsed fragment, got %v", err)
+	}
+
+	duplicated, err := Parse(`{ hero { ...fields } } fragment fields on Character { name } fragment fields on Character { id }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := duplicated.Document(); err == nil || err.Error() != "fluentgraphql: different fragments named fields" {
+		t.Fatalf("expected a conflict between the parsed fragments, got %v", err)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	}))
+	defer server.Close()
+	if _, err := NewHTTPExecutor(server.URL).Execute(context.Background(), NewRequest(duplicated, nil)); err == nil || err.Error() != "fluentgraphql: different fragments named fields" {
+		t.Fatalf("expected the document not to be sent, got %v", err)
+	}
+}
+
+func TestFragmentTypeCondition(t *testing.T) {
+	s := NewSchema()
+	s.Type("Query").Field("repository", "Repository").Field("viewer", "User")
+	s.Type("Repository").Field("name", "String!")
+	s.Type("User").Field("login", "String!")
+	schema, err := s.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := NewFragment("repositoryFields", "Repository")
+	fields.Scalar("name")
+
+	if err := NewQuery().Selection("repository").Spread(fields).Root().Validate(schema); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	err = NewQuery().Selection("viewer").Spread(fields).Root().Validate(schema)
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || len(validationErr.Errors) != 1 {
+		t.Fatalf("expected a validation error for the spread, got %v", err)
+	}
+}
@@ -2,6 +2,13 @@ module github.com/mergestat/fluentgraphql
 
 go 1.17
 
-require github.com/graphql-go/graphql v0.8.0
+require github.com/graphql-go/graphql v0.8.1
 
-require github.com/google/go-cmp v0.5.8
+require (
+	github.com/google/go-cmp v0.5.8
+	github.com/gorilla/websocket v1.5.0
+	github.com/vektah/gqlparser/v2 v2.5.1
+	gopkg.in/yaml.v3 v3.0.1
+)
+
+require github.com/agnivade/levenshtein v1.0.1 // indirect
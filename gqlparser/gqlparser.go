@@ -0,0 +1,479 @@
+// Package gqlparser converts fluentgraphql selections to and from the query documents of
+// vektah/gqlparser, the parser gqlgen is built on, so that operations built with
+// fluentgraphql can be validated against gqlgen-loaded schemas and printed with the
+// gqlparser formatter.
+package gqlparser
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	gql "github.com/graphql-go/graphql/language/ast"
+	fgql "github.com/mergestat/fluentgraphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/validator"
+)
+
+// ToQueryDocument converts the document of a selection, the operation or fragment definition
+// it belongs to along with the fragments it spreads, to a gqlparser query document
+func ToQueryDocument(s *fgql.Selection) (*ast.QueryDocument, error) {
+	doc, err := s.Root().Document()
+	if err != nil {
+		return nil, err
+	}
+
+	queryDoc := &ast.QueryDocument{}
+	for _, def := range doc.Definitions {
+		switch def := def.(type) {
+		case *gql.OperationDefinition:
+			op, err := toOperation(def)
+			if err != nil {
+				return nil, err
+			}
+			queryDoc.Operations = append(queryDoc.Operations, op)
+		case *gql.FragmentDefinition:
+			selections, err := toSelectionSet(def.SelectionSet)
+			if err != nil {
+				return nil, err
+			}
+			directives, err := toDirectives(def.Directives)
+			if err != nil {
+				return nil, err
+			}
+			queryDoc.Fragments = append(queryDoc.Fragments, &ast.FragmentDefinition{
+				Name:          nameValue(def.Name),
+				TypeCondition: nameValue(def.TypeCondition.Name),
+				Directives:    directives,
+				SelectionSet:  selections,
+			})
+		default:
+			return nil, fmt.Errorf("fluentgraphql: cannot convert a %s to a query document", def.GetKind())
+		}
+	}
+	return queryDoc, nil
+}
+
+// FromQueryDocument returns a selection builder for the first operation of a gqlparser query
+// document, with the fragment definitions of the document printed along with it
+func FromQueryDocument(doc *ast.QueryDocument) (*fgql.Selection, error) {
+	if doc == nil {
+		return nil, errors.New("fluentgraphql: no query document to convert")
+	}
+
+	converted := gql.NewDocument(&gql.Document{})
+	for _, op := range doc.Operations {
+		def, err := fromOperation(op)
+		if err != nil {
+			return nil, err
+		}
+		converted.Definitions = append(converted.Definitions, def)
+	}
+	for _, f := range doc.Fragments {
+		selections, err := fromSelectionSet(f.SelectionSet)
+		if err != nil {
+			return nil, err
+		}
+		directives, err := fromDirectives(f.Directives)
+		if err != nil {
+			return nil, err
+		}
+		converted.Definitions = append(converted.Definitions, gql.NewFragmentDefinition(&gql.FragmentDefinition{
+			Name:          gql.NewName(&gql.Name{Value: f.Name}),
+			TypeCondition: gql.NewNamed(&gql.Named{Name: gql.NewName(&gql.Name{Value: f.TypeCondition})}),
+			Directives:    directives,
+			SelectionSet:  selections,
+		}))
+	}
+	return fgql.FromAST(converted)
+}
+
+// Validate validates the document of a selection against a gqlparser schema, such as
+// the one gqlgen loads, returning the gqlerror.List of the reasons it is invalid
+func Validate(s *fgql.Selection, schema *ast.Schema) error {
+	doc, err := ToQueryDocument(s)
+	if err != nil {
+		return err
+	}
+	if errs := validator.Validate(schema, doc); len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func toOperation(def *gql.OperationDefinition) (*ast.OperationDefinition, error) {
+	variables := make(ast.VariableDefinitionList, 0, len(def.VariableDefinitions))
+	for _, v := range def.VariableDefinitions {
+		t, err := toType(v.Type)
+		if err != nil {
+			return nil, err
+		}
+		defaultValue, err := toValue(v.DefaultValue)
+		if err != nil {
+			return nil, err
+		}
+		variables = append(variables, &ast.VariableDefinition{
+			Variable:     nameValue(v.Variable.Name),
+			Type:         t,
+			DefaultValue: defaultValue,
+		})
+	}
+	directives, err := toDirectives(def.Directives)
+	if err != nil {
+		return nil, err
+	}
+	selections, err := toSelectionSet(def.SelectionSet)
+	if err != nil {
+		return nil, err
+	}
+
+	operation := ast.Operation(def.Operation)
+	if operation == "" {
+		operation = ast.Query
+	}
+	return &ast.OperationDefinition{
+		Operation:           operation,
+		Name:                nameValue(def.Name),
+		VariableDefinitions: variables,
+		Directives:          directives,
+		SelectionSet:        selections,
+	}, nil
+}
+
+func toSelectionSet(set *gql.SelectionSet) (ast.SelectionSet, error) {
+	if set == nil {
+		return nil, nil
+	}
+	selections := make(ast.SelectionSet, 0, len(set.Selections))
+	for _, selection := range set.Selections {
+		switch n := selection.(type) {
+		case *gql.Field:
+			args, err := toArguments(n.Arguments)
+			if err != nil {
+				return nil, err
+			}
+			directives, err := toDirectives(n.Directives)
+			if err != nil {
+				return nil, err
+			}
+			sub, err := toSelectionSet(n.SelectionSet)
+			if err != nil {
+				return nil, err
+			}
+			// gqlparser sets the alias of a field without one to its name
+			f := &ast.Field{
+				Alias:        nameValue(n.Alias),
+				Name:         nameValue(n.Name),
+				Arguments:    args,
+				Directives:   directives,
+				SelectionSet: sub,
+			}
+			if f.Alias == "" {
+				f.Alias = f.Name
+			}
+			selections = append(selections, f)
+		case *gql.InlineFragment:
+			directives, err := toDirectives(n.Directives)
+			if err != nil {
+				return nil, err
+			}
+			sub, err := toSelectionSet(n.SelectionSet)
+			if err != nil {
+				return nil, err
+			}
+			f := &ast.InlineFragment{Directives: directives, SelectionSet: sub}
+			if n.TypeCondition != nil {
+				f.TypeCondition = nameValue(n.TypeCondition.Name)
+			}
+			selections = append(selections, f)
+		case *gql.FragmentSpread:
+			directives, err := toDirectives(n.Directives)
+			if err != nil {
+				return nil, err
+			}
+			selections = append(selections, &ast.FragmentSpread{
+				Name:       nameValue(n.Name),
+				Directives: directives,
+			})
+		default:
+			return nil, fmt.Errorf("fluentgraphql: cannot convert a %s selection", selection.(gql.Node).GetKind())
+		}
+	}
+	return selections, nil
+}
+
+func toArguments(args []*gql.Argument) (ast.ArgumentList, error) {
+	converted := make(ast.ArgumentList, 0, len(args))
+	for _, arg := range args {
+		value, err := toValue(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, &ast.Argument{Name: nameValue(arg.Name), Value: value})
+	}
+	return converted, nil
+}
+
+func toDirectives(directives []*gql.Directive) (ast.DirectiveList, error) {
+	converted := make(ast.DirectiveList, 0, len(directives))
+	for _, d := range directives {
+		args, err := toArguments(d.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, &ast.Directive{Name: nameValue(d.Name), Arguments: args})
+	}
+	return converted, nil
+}
+
+func toValue(value gql.Value) (*ast.Value, error) {
+	switch v := value.(type) {
+	case nil:
+		return nil, nil
+	case *gql.Variable:
+		return &ast.Value{Kind: ast.Variable, Raw: nameValue(v.Name)}, nil
+	case *gql.IntValue:
+		return &ast.Value{Kind: ast.IntValue, Raw: v.Value}, nil
+	case *gql.FloatValue:
+		return &ast.Value{Kind: ast.FloatValue, Raw: v.Value}, nil
+	case *gql.StringValue:
+		return &ast.Value{Kind: ast.StringValue, Raw: v.Value}, nil
+	case *gql.BooleanValue:
+		return &ast.Value{Kind: ast.BooleanValue, Raw: strconv.FormatBool(v.Value)}, nil
+	case *gql.EnumValue:
+		return &ast.Value{Kind: ast.EnumValue, Raw: v.Value}, nil
+	case *gql.ListValue:
+		converted := &ast.Value{Kind: ast.ListValue}
+		for _, item := range v.Values {
+			child, err := toValue(item)
+			if err != nil {
+				return nil, err
+			}
+			converted.Children = append(converted.Children, &ast.ChildValue{Value: child})
+		}
+		return converted, nil
+	case *gql.ObjectValue:
+		converted := &ast.Value{Kind: ast.ObjectValue}
+		for _, f := range v.Fields {
+			child, err := toValue(f.Value)
+			if err != nil {
+				return nil, err
+			}
+			converted.Children = append(converted.Children, &ast.ChildValue{Name: nameValue(f.Name), Value: child})
+		}
+		return converted, nil
+	}
+	return nil, fmt.Errorf("fluentgraphql: cannot convert a %s", value.GetKind())
+}
+
+func toType(t gql.Type) (*ast.Type, error) {
+	switch t := t.(type) {
+	case *gql.Named:
+		return ast.NamedType(nameValue(t.Name), nil), nil
+	case *gql.List:
+		elem, err := toType(t.Type)
+		if err != nil {
+			return nil, err
+		}
+		return ast.ListType(elem, nil), nil
+	case *gql.NonNull:
+		converted, err := toType(t.Type)
+		if err != nil {
+			return nil, err
+		}
+		converted.NonNull = true
+		return converted, nil
+	}
+	return nil, fmt.Errorf("fluentgraphql: cannot convert a %s type", t.GetKind())
+}
+
+func fromOperation(op *ast.OperationDefinition) (*gql.OperationDefinition, error) {
+	variables := make([]*gql.VariableDefinition, 0, len(op.VariableDefinitions))
+	for _, v := range op.VariableDefinitions {
+		defaultValue, err := fromValue(v.DefaultValue)
+		if err != nil {
+			return nil, err
+		}
+		variables = append(variables, gql.NewVariableDefinition(&gql.VariableDefinition{
+			Variable:     gql.NewVariable(&gql.Variable{Name: gql.NewName(&gql.Name{Value: v.Variable})}),
+			Type:         fromType(v.Type),
+			DefaultValue: defaultValue,
+		}))
+	}
+	directives, err := fromDirectives(op.Directives)
+	if err != nil {
+		return nil, err
+	}
+	selections, err := fromSelectionSet(op.SelectionSet)
+	if err != nil {
+		return nil, err
+	}
+
+	def := gql.NewOperationDefinition(&gql.OperationDefinition{
+		Operation:           string(op.Operation),
+		VariableDefinitions: variables,
+		Directives:          directives,
+		SelectionSet:        selections,
+	})
+	if def.Operation == "" {
+		def.Operation = gql.OperationTypeQuery
+	}
+	if op.Name != "" {
+		def.Name = gql.NewName(&gql.Name{Value: op.Name})
+	}
+	return def, nil
+}
+
+func fromSelectionSet(set ast.SelectionSet) (*gql.SelectionSet, error) {
+	if set == nil {
+		return nil, nil
+	}
+	selections := make([]gql.Selection, 0, len(set))
+	for _, selection := range set {
+		switch n := selection.(type) {
+		case *ast.Field:
+			args, err := fromArguments(n.Arguments)
+			if err != nil {
+				return nil, err
+			}
+			directives, err := fromDirectives(n.Directives)
+			if err != nil {
+				return nil, err
+			}
+			sub, err := fromSelectionSet(n.SelectionSet)
+			if err != nil {
+				return nil, err
+			}
+			f := gql.NewField(&gql.Field{
+				Name:         gql.NewName(&gql.Name{Value: n.Name}),
+				Arguments:    args,
+				Directives:   directives,
+				SelectionSet: sub,
+			})
+			if n.Alias != "" && n.Alias != n.Name {
+				f.Alias = gql.NewName(&gql.Name{Value: n.Alias})
+			}
+			selections = append(selections, f)
+		case *ast.InlineFragment:
+			directives, err := fromDirectives(n.Directives)
+			if err != nil {
+				return nil, err
+			}
+			sub, err := fromSelectionSet(n.SelectionSet)
+			if err != nil {
+				return nil, err
+			}
+			f := gql.NewInlineFragment(&gql.InlineFragment{Directives: directives, SelectionSet: sub})
+			if n.TypeCondition != "" {
+				f.TypeCondition = gql.NewNamed(&gql.Named{Name: gql.NewName(&gql.Name{Value: n.TypeCondition})})
+			}
+			selections = append(selections, f)
+		case *ast.FragmentSpread:
+			directives, err := fromDirectives(n.Directives)
+			if err != nil {
+				return nil, err
+			}
+			selections = append(selections, gql.NewFragmentSpread(&gql.FragmentSpread{
+				Name:       gql.NewName(&gql.Name{Value: n.Name}),
+				Directives: directives,
+			}))
+		}
+	}
+	return gql.NewSelectionSet(&gql.SelectionSet{Selections: selections}), nil
+}
+
+func fromArguments(args ast.ArgumentList) ([]*gql.Argument, error) {
+	converted := make([]*gql.Argument, 0, len(args))
+	for _, arg := range args {
+		value, err := fromValue(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, gql.NewArgument(&gql.Argument{
+			Name:  gql.NewName(&gql.Name{Value: arg.Name}),
+			Value: value,
+		}))
+	}
+	return converted, nil
+}
+
+func fromDirectives(directives ast.DirectiveList) ([]*gql.Directive, error) {
+	converted := make([]*gql.Directive, 0, len(directives))
+	for _, d := range directives {
+		args, err := fromArguments(d.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		converted = append(converted, gql.NewDirective(&gql.Directive{
+			Name:      gql.NewName(&gql.Name{Value: d.Name}),
+			Arguments: args,
+		}))
+	}
+	return converted, nil
+}
+
+// fromValue converts a gqlparser value. Block strings become plain strings, and null values,
+// which graphql-go has no node for, can't be converted.
+func fromValue(value *ast.Value) (gql.Value, error) {
+	if value == nil {
+		return nil, nil
+	}
+	switch value.Kind {
+	case ast.Variable:
+		return gql.NewVariable(&gql.Variable{Name: gql.NewName(&gql.Name{Value: value.Raw})}), nil
+	case ast.IntValue:
+		return gql.NewIntValue(&gql.IntValue{Value: value.Raw}), nil
+	case ast.FloatValue:
+		return gql.NewFloatValue(&gql.FloatValue{Value: value.Raw}), nil
+	case ast.StringValue, ast.BlockValue:
+		return gql.NewStringValue(&gql.StringValue{Value: value.Raw}), nil
+	case ast.BooleanValue:
+		return gql.NewBooleanValue(&gql.BooleanValue{Value: value.Raw == "true"}), nil
+	case ast.EnumValue:
+		return gql.NewEnumValue(&gql.EnumValue{Value: value.Raw}), nil
+	case ast.ListValue:
+		values := make([]gql.Value, 0, len(value.Children))
+		for _, child := range value.Children {
+			item, err := fromValue(child.Value)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, item)
+		}
+		return gql.NewListValue(&gql.ListValue{Values: values}), nil
+	case ast.ObjectValue:
+		fields := make([]*gql.ObjectField, 0, len(value.Children))
+		for _, child := range value.Children {
+			item, err := fromValue(child.Value)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, gql.NewObjectField(&gql.ObjectField{
+				Name:  gql.NewName(&gql.Name{Value: child.Name}),
+				Value: item,
+			}))
+		}
+		return gql.NewObjectValue(&gql.ObjectValue{Fields: fields}), nil
+	}
+	return nil, fmt.Errorf("fluentgraphql: cannot convert %s", value.String())
+}
+
+func fromType(t *ast.Type) gql.Type {
+	var converted gql.Type
+	if t.Elem != nil {
+		converted = gql.NewList(&gql.List{Type: fromType(t.Elem)})
+	} else {
+		converted = gql.NewNamed(&gql.Named{Name: gql.NewName(&gql.Name{Value: t.NamedType})})
+	}
+	if t.NonNull {
+		converted = gql.NewNonNull(&gql.NonNull{Type: converted})
+	}
+	return converted
+}
+
+func nameValue(name *gql.Name) string {
+	if name == nil {
+		return ""
+	}
+	return name.Value
+}
@@ -0,0 +1,159 @@
+package gqlparser
+
+import (
+	"bytes"
+	"sort"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	fgql "github.com/mergestat/fluentgraphql"
+	gqlparser "github.com/vektah/gqlparser/v2"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/formatter"
+	"github.com/vektah/gqlparser/v2/gqlerror"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+var schema = gqlparser.MustLoadSchema(&ast.Source{Name: "schema.graphql", Input: `
+	type Query {
+		repository(owner: String!, name: String!): Repository
+	}
+	type Repository {
+		name: String!
+		owner: Owner!
+		issues(first: Int, states: [IssueState!], filterBy: IssueFilters): [Issue!]!
+	}
+	interface Owner {
+		login: String!
+	}
+	type User implements Owner {
+		login: String!
+		email: String
+	}
+	enum IssueState { OPEN CLOSED }
+	input IssueFilters { labels: [String!], assignee: String }
+	type Issue {
+		title: String!
+	}
+`})
+
+func repositoryQuery() *fgql.Selection {
+	owner := fgql.NewFragment("ownerFields", "Owner")
+	owner.Scalar("login").InlineFragment("User").Scalar("email")
+
+	return fgql.NewQuery(fgql.WithName("Repository"), fgql.WithVariableDefinitions(
+		fgql.NewVariableDefinition("name", "String", true, nil),
+		fgql.NewVariableDefinition("first", "Int", false, fgql.NewIntValue(10)),
+	)).
+		Selection("repository", fgql.WithAlias("repo"), fgql.WithArguments(
+			fgql.NewArgument("owner", fgql.NewStringValue("mergestat")),
+			fgql.NewArgument("name", fgql.NewVariableValue("name")),
+		)).
+		Scalar("name").
+		Selection("owner").Spread(owner).Parent().
+		Selection("issues", fgql.WithArguments(
+			fgql.NewArgument("first", fgql.NewVariableValue("first")),
+			fgql.NewArgument("states", fgql.NewListValue(fgql.NewEnumValue("OPEN"))),
+			fgql.NewArgument("filterBy", fgql.NewObjectValue(
+				fgql.NewObjectValueField("labels", fgql.NewListValue(fgql.NewStringValue("bug"))),
+			)),
+		), fgql.WithDirectives(fgql.NewDirective("include", fgql.NewArgument("if", fgql.NewBooleanValue(true))))).
+		Scalar("title").
+		Root()
+}
+
+func TestToQueryDocument(t *testing.T) {
+	doc, err := ToQueryDocument(repositoryQuery())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	formatter.NewFormatter(&buf).FormatQueryDocument(doc)
+	wanted, err := parser.ParseQuery(&ast.Source{Input: repositoryQuery().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var wantedBuf bytes.Buffer
+	formatter.NewFormatter(&wantedBuf).FormatQueryDocument(wanted)
+	if diff := cmp.Diff(wantedBuf.String(), buf.String()); diff != "" {
+		t.Fatal("formatted query document does not match what's wanted", diff)
+	}
+}
+
+func TestFromQueryDocument(t *testing.T) {
+	doc, err := parser.ParseQuery(&ast.Source{Input: `
+		query Repository($name: String! = "fluentgraphql") {
+			repo: repository(owner: "mergestat", name: $name) {
+				name
+				owner { ...ownerFields }
+				issues(first: 10, states: [OPEN], filterBy: {labels: ["bug"]}) @include(if: true) { title }
+			}
+		}
+		fragment ownerFields on Owner { login ... on User { email } }`})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := FromQueryDocument(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wanted, err := fgql.Parse(`
+		query Repository($name: String! = "fluentgraphql") {
+			repo: repository(owner: "mergestat", name: $name) {
+				name
+				owner { ...ownerFields }
+				issues(first: 10, states: [OPEN], filterBy: {labels: ["bug"]}) @include(if: true) { title }
+			}
+		}
+		fragment ownerFields on Owner { login ... on User { email } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wanted.String(), s.String()); diff != "" {
+		t.Fatal("converted selection does not match what's wanted", diff)
+	}
+
+	for name, doc := range map[string]*ast.QueryDocument{
+		"Nil":         nil,
+		"NoOperation": {},
+		"Null": {Operations: ast.OperationList{{
+			Operation:    ast.Query,
+			SelectionSet: ast.SelectionSet{&ast.Field{Name: "f", Arguments: ast.ArgumentList{{Name: "a", Value: &ast.Value{Kind: ast.NullValue, Raw: "null"}}}}},
+		}}},
+	} {
+		if _, err := FromQueryDocument(doc); err == nil {
+			t.Fatalf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := Validate(repositoryQuery(), schema); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	q := fgql.NewQuery().
+		Selection("repository", fgql.WithArguments(fgql.NewArgument("owner", fgql.NewIntValue(1)))).
+		Scalar("stars").
+		Root()
+	err := Validate(q, schema)
+	errs, ok := err.(gqlerror.List)
+	if !ok {
+		t.Fatalf("expected a list of errors, got %v", err)
+	}
+	var messages []string
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	sort.Strings(messages)
+	wanted := []string{
+		`Cannot query field "stars" on type "Repository".`,
+		`Field "repository" argument "name" of type "String!" is required, but it was not provided.`,
+		`String cannot represent a non string value: 1`,
+	}
+	if diff := cmp.Diff(wanted, messages); diff != "" {
+		t.Fatal("validation errors do not match what's wanted", diff)
+	}
+}
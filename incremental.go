@@ -0,0 +1,362 @@
+package fluentgraphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+)
+
+// IncrementalResult is the state of an operation delivered incrementally, once one of
+// its payloads has been received
+type IncrementalResult struct {
+	// Data is the data received so far, with the deferred and streamed payloads merged in
+	Data json.RawMessage
+	// Errors are the errors received so far
+	Errors     ResponseErrors
+	Extensions map[string]interface{}
+	// Completed lists the deferred fragments and streams the payload completed
+	Completed []*IncrementalCompletion
+	// HasNext tells whether more payloads are to come
+	HasNext bool
+	// Err is the reason the response could not be read to its end, on the last result
+	Err error
+}
+
+// IncrementalCompletion tells that a deferred fragment or a stream was delivered in full
+type IncrementalCompletion struct {
+	Label  string
+	Path   []interface{}
+	Errors ResponseErrors
+}
+
+// ExecuteIncremental sends the request accepting an incremental response, for operations
+// using @defer or @stream, and delivers the state of the operation on the returned channel
+// after each payload. The channel is closed once the server sent the last payload.
+//
+// Both the multipart/mixed format of graphql-js 17, where pending payloads are announced
+// and completed by id, and the earlier one, where each payload carries its path and label,
+// are understood. With the earlier format, deferred fragments complete with their payload,
+// and streams with the response. Servers answering with a plain JSON response have it
+// delivered as a single result.
+func (e *HTTPExecutor) ExecuteIncremental(ctx context.Context, req *Request) (<-chan *IncrementalResult, error) {
+	httpReq, err := e.newRequest(ctx, req, false)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "multipart/mixed;deferSpec=20220824, application/json")
+
+	res, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	mediaType, params, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" || res.StatusCode >= http.StatusBadRequest {
+		defer res.Body.Close()
+		response, err := decodeResponse(res)
+		if err != nil {
+			return nil, err
+		}
+		results := make(chan *IncrementalResult, 1)
+		results <- &IncrementalResult{Data: response.Data, Errors: response.Errors, Extensions: response.Extensions}
+		close(results)
+		return results, nil
+	}
+
+	results := make(chan *IncrementalResult)
+	go func() {
+		defer close(results)
+		defer res.Body.Close()
+		send := func(result *IncrementalResult) bool {
+			select {
+			case results <- result:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		parts := multipart.NewReader(res.Body, params["boundary"])
+		response := newIncrementalResponse()
+		for {
+			part, err := parts.NextPart()
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil {
+				if ctx.Err() == nil {
+					send(&IncrementalResult{Err: fmt.Errorf("fluentgraphql: incremental response interrupted: %w", err)})
+				}
+				return
+			}
+			b, err := ioutil.ReadAll(part)
+			if err != nil {
+				if ctx.Err() == nil {
+					send(&IncrementalResult{Err: fmt.Errorf("fluentgraphql: incremental response interrupted: %w", err)})
+				}
+				return
+			}
+			result, err := response.apply(b)
+			if err != nil {
+				send(&IncrementalResult{Err: err})
+				return
+			}
+			if result == nil {
+				continue
+			}
+			if !send(result) || !result.HasNext {
+				return
+			}
+		}
+	}()
+	return results, nil
+}
+
+// incrementalResponse merges the payloads of a response delivered incrementally
+type incrementalResponse struct {
+	data       interface{}
+	errors     ResponseErrors
+	extensions map[string]interface{}
+	// pending are the deferred fragments and streams announced by id
+	pending map[string]*IncrementalCompletion
+}
+
+// incrementalPayload is a payload of an incremental response
+type incrementalPayload struct {
+	Data        json.RawMessage        `json:"data"`
+	Errors      ResponseErrors         `json:"errors"`
+	Extensions  map[string]interface{} `json:"extensions"`
+	HasNext     *bool                  `json:"hasNext"`
+	Pending     []pendingPayload       `json:"pending"`
+	Incremental []incrementalEntry     `json:"incremental"`
+	Completed   []completedPayload     `json:"completed"`
+}
+
+type pendingPayload struct {
+	ID    string        `json:"id"`
+	Path  []interface{} `json:"path"`
+	Label string        `json:"label"`
+}
+
+// incrementalEntry is the data of a deferred fragment or the items of a stream, either
+// for a pending id and a path relative to it, or at a path with a label
+type incrementalEntry struct {
+	ID         string                 `json:"id"`
+	SubPath    []interface{}          `json:"subPath"`
+	Path       []interface{}          `json:"path"`
+	Label      string                 `json:"label"`
+	Data       json.RawMessage        `json:"data"`
+	Items      json.RawMessage        `json:"items"`
+	Errors     ResponseErrors         `json:"errors"`
+	Extensions map[string]interface{} `json:"extensions"`
+}
+
+type completedPayload struct {
+	ID     string         `json:"id"`
+	Errors ResponseErrors `json:"errors"`
+}
+
+func newIncrementalResponse() *incrementalResponse {
+	return &incrementalResponse{pending: make(map[string]*IncrementalCompletion)}
+}
+
+// apply merges a payload into the response and returns the resulting state, or nil for
+// the empty payloads servers send to keep connections alive
+func (r *incrementalResponse) apply(b []byte) (*IncrementalResult, error) {
+	var payload incrementalPayload
+	if err := json.Unmarshal(b, &payload); err != nil {
+		return nil, fmt.Errorf("fluentgraphql: invalid incremental payload: %w", err)
+	}
+	if payload.Data == nil && payload.Errors == nil && payload.Extensions == nil && payload.HasNext == nil &&
+		payload.Pending == nil && payload.Incremental == nil && payload.Completed == nil {
+		return nil, nil
+	}
+
+	result := &IncrementalResult{HasNext: payload.HasNext != nil && *payload.HasNext}
+	if payload.Data != nil {
+		data, err := decodeData(payload.Data)
+		if err != nil {
+			return nil, err
+		}
+		r.data = data
+	}
+	r.errors = append(r.errors, payload.Errors...)
+	r.extend(payload.Extensions)
+
+	for _, p := range payload.Pending {
+		r.pending[p.ID] = &IncrementalCompletion{Label: p.Label, Path: p.Path}
+	}
+
+	for _, entry := range payload.Incremental {
+		path, label := entry.Path, entry.Label
+		if entry.ID != "" {
+			pending, ok := r.pending[entry.ID]
+			if !ok {
+				return nil, fmt.Errorf("fluentgraphql: incremental payload for unknown id %s", entry.ID)
+			}
+			path = append(append([]interface{}(nil), pending.Path...), entry.SubPath...)
+		}
+		r.errors = append(r.errors, entry.Errors...)
+		r.extend(entry.Extensions)
+
+		switch {
+		case entry.Items != nil:
+			var items []interface{}
+			if err := decodeJSON(entry.Items, &items); err != nil {
+				return nil, fmt.Errorf("fluentgraphql: invalid streamed items: %w", err)
+			}
+			// with the earlier format, the path ends with the index of the first item
+			listPath := path
+			if entry.ID == "" && len(path) > 0 {
+				listPath = path[:len(path)-1]
+			}
+			if err := r.appendItems(listPath, items); err != nil {
+				return nil, err
+			}
+		case entry.Data != nil:
+			data, err := decodeData(entry.Data)
+			if err != nil {
+				return nil, err
+			}
+			if err := r.merge(path, data); err != nil {
+				return nil, err
+			}
+			if entry.ID == "" {
+				result.Completed = append(result.Completed, &IncrementalCompletion{Label: label, Path: path, Errors: entry.Errors})
+			}
+		}
+	}
+
+	for _, c := range payload.Completed {
+		pending, ok := r.pending[c.ID]
+		if !ok {
+			return nil, fmt.Errorf("fluentgraphql: completion of unknown id %s", c.ID)
+		}
+		delete(r.pending, c.ID)
+		pending.Errors = c.Errors
+		r.errors = append(r.errors, c.Errors...)
+		result.Completed = append(result.Completed, pending)
+	}
+
+	if r.data != nil {
+		b, err := json.Marshal(r.data)
+		if err != nil {
+			return nil, err
+		}
+		result.Data = b
+	}
+	result.Errors = append(ResponseErrors(nil), r.errors...)
+	result.Extensions = r.extensions
+	return result, nil
+}
+
+func (r *incrementalResponse) extend(extensions map[string]interface{}) {
+	if len(extensions) == 0 {
+		return
+	}
+	if r.extensions == nil {
+		r.extensions = make(map[string]interface{})
+	}
+	for key, value := range extensions {
+		r.extensions[key] = value
+	}
+}
+
+// merge merges the data of a deferred fragment into the object at path. Paths that lead
+// to null, because an error nulled one of their fields, are skipped.
+func (r *incrementalResponse) merge(path []interface{}, data interface{}) error {
+	node, err := locate(r.data, path)
+	if err != nil || node == nil {
+		return err
+	}
+	object, ok := node.(map[string]interface{})
+	fields, isObject := data.(map[string]interface{})
+	if !ok || !isObject {
+		return fmt.Errorf("fluentgraphql: deferred data at %v is not an object", path)
+	}
+	mergeObjects(object, fields)
+	return nil
+}
+
+// appendItems appends the items of a stream to the list at path
+func (r *incrementalResponse) appendItems(path []interface{}, items []interface{}) error {
+	if len(path) == 0 {
+		return fmt.Errorf("fluentgraphql: streamed items without a path")
+	}
+	parent, err := locate(r.data, path[:len(path)-1])
+	if err != nil || parent == nil {
+		return err
+	}
+	node, err := locate(parent, path[len(path)-1:])
+	if err != nil || node == nil {
+		return err
+	}
+	list, ok := node.([]interface{})
+	if !ok {
+		return fmt.Errorf("fluentgraphql: streamed items at %v are not for a list", path)
+	}
+	list = append(list, items...)
+	switch parent := parent.(type) {
+	case map[string]interface{}:
+		parent[path[len(path)-1].(string)] = list
+	case []interface{}:
+		parent[int(path[len(path)-1].(float64))] = list
+	}
+	return nil
+}
+
+// locate returns the value at path in data, or nil when the path leads to null
+func locate(data interface{}, path []interface{}) (interface{}, error) {
+	node := data
+	for _, key := range path {
+		if node == nil {
+			return nil, nil
+		}
+		switch key := key.(type) {
+		case string:
+			object, ok := node.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("fluentgraphql: invalid path %v in incremental payload", path)
+			}
+			node = object[key]
+		case float64:
+			list, ok := node.([]interface{})
+			if !ok || int(key) < 0 || int(key) >= len(list) {
+				return nil, fmt.Errorf("fluentgraphql: invalid path %v in incremental payload", path)
+			}
+			node = list[int(key)]
+		default:
+			return nil, fmt.Errorf("fluentgraphql: invalid path %v in incremental payload", path)
+		}
+	}
+	return node, nil
+}
+
+// mergeObjects merges the fields of src into dst, merging the objects they both have, and
+// the lists of the same length item by item, as fragments deliver different fields of the
+// same items
+func mergeObjects(dst, src map[string]interface{}) {
+	for key, value := range src {
+		dst[key] = mergeValues(dst[key], value)
+	}
+}
+
+func decodeData(b json.RawMessage) (interface{}, error) {
+	var data interface{}
+	if err := decodeJSON(b, &data); err != nil {
+		return nil, fmt.Errorf("fluentgraphql: invalid incremental data: %w", err)
+	}
+	return data, nil
+}
+
+// decodeJSON decodes b keeping numbers as they are written
+func decodeJSON(b []byte, v interface{}) error {
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	return d.Decode(v)
+}
@@ -0,0 +1,249 @@
+package fluentgraphql
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIncrementalResponse(t *testing.T) {
+	type test struct {
+		payloads  []string
+		data      []string
+		completed []string
+		errors    string
+	}
+
+	var tests = map[string]test{
+		"Defer": {
+			payloads: []string{
+				`{"data":{"repository":{"name":"fluentgraphql","owner":{"login":"mergestat"}}},"pending":[{"id":"0","path":["repository"],"label":"issues"}],"hasNext":true}`,
+				`{"incremental":[{"id":"0","data":{"issues":{"totalCount":12}}},{"id":"0","subPath":["owner"],"data":{"id":"O_1"}}],"completed":[{"id":"0"}],"hasNext":false}`,
+			},
+			data: []string{
+				`{"repository":{"name":"fluentgraphql","owner":{"login":"mergestat"}}}`,
+				`{"repository":{"issues":{"totalCount":12},"name":"fluentgraphql","owner":{"id":"O_1","login":"mergestat"}}}`,
+			},
+			completed: []string{"issues [repository] "},
+		},
+		"Stream": {
+			payloads: []string{
+				`{"data":{"issues":[{"number":1}]},"pending":[{"id":"0","path":["issues"],"label":"rest"}],"hasNext":true}`,
+				`{}`,
+				`{"incremental":[{"id":"0","items":[{"number":2},{"number":3}]}],"hasNext":true}`,
+				`{"incremental":[{"id":"0","items":[{"number":4}],"errors":[{"message":"rate limited"}]}],"completed":[{"id":"0","errors":[{"message":"stream failed"}]}],"hasNext":false}`,
+			},
+			data: []string{
+				`{"issues":[{"number":1}]}`,
+				`{"issues":[{"number":1},{"number":2},{"number":3}]}`,
+				`{"issues":[{"number":1},{"number":2},{"number":3},{"number":4}]}`,
+			},
+			completed: []string{"rest [issues] stream failed"},
+			errors:    "rate limited; stream failed",
+		},
+		"EarlierFormat": {
+			payloads: []string{
+				`{"data":{"nodes":[{"id":"1"},{"id":"2"}],"tags":["a"]},"hasNext":true}`,
+				`{"incremental":[{"path":["nodes",0],"label":"details","data":{"name":"one"}},{"path":["nodes",1],"label":"details","data":{"name":"two"}}],"hasNext":true}`,
+				`{"incremental":[{"path":["tags",1],"items":["b","c"]}],"hasNext":false}`,
+			},
+			data: []string{
+				`{"nodes":[{"id":"1"},{"id":"2"}],"tags":["a"]}`,
+				`{"nodes":[{"id":"1","name":"one"},{"id":"2","name":"two"}],"tags":["a"]}`,
+				`{"nodes":[{"id":"1","name":"one"},{"id":"2","name":"two"}],"tags":["a","b","c"]}`,
+			},
+			completed: []string{"details [nodes 0] ", "details [nodes 1] "},
+		},
+		"DeferredListItems": {
+			payloads: []string{
+				`{"data":{"repository":{"issues":[{"number":1},{"number":2}]}},"pending":[{"id":"0","path":["repository"],"label":"titles"}],"hasNext":true}`,
+				`{"incremental":[{"id":"0","data":{"issues":[{"title":"first"},{"title":"second"}]}}],"completed":[{"id":"0"}],"hasNext":false}`,
+			},
+			data: []string{
+				`{"repository":{"issues":[{"number":1},{"number":2}]}}`,
+				`{"repository":{"issues":[{"number":1,"title":"first"},{"number":2,"title":"second"}]}}`,
+			},
+			completed: []string{"titles [repository] "},
+		},
+		"NulledPath": {
+			payloads: []string{
+				`{"data":{"viewer":null},"errors":[{"message":"unauthorized"}],"hasNext":true}`,
+				`{"incremental":[{"path":["viewer"],"data":{"login":"octocat"}}],"hasNext":false}`,
+			},
+			data: []string{
+				`{"viewer":null}`,
+				`{"viewer":null}`,
+			},
+			completed: []string{" [viewer] "},
+			errors:    "unauthorized",
+		},
+		"LargeNumbers": {
+			payloads: []string{
+				`{"data":{"id":9007199254740993},"hasNext":false}`,
+			},
+			data: []string{`{"id":9007199254740993}`},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := newIncrementalResponse()
+			var data, completed []string
+			var last *IncrementalResult
+			for _, payload := range test.payloads {
+				result, err := r.apply([]byte(payload))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if result == nil {
+					continue
+				}
+				data = append(data, string(result.Data))
+				for _, c := range result.Completed {
+					completed = append(completed, fmt.Sprintf("%s %v %v", c.Label, c.Path, c.Errors))
+				}
+				last = result
+			}
+			if diff := cmp.Diff(test.data, data); diff != "" {
+				t.Errorf("unexpected data (-want +got):\n%s", diff)
+			}
+			if diff := cmp.Diff(test.completed, completed); diff != "" {
+				t.Errorf("unexpected completions (-want +got):\n%s", diff)
+			}
+			if last.Errors.Error() != test.errors {
+				t.Errorf("expected errors %q, got %q", test.errors, last.Errors.Error())
+			}
+			if last.HasNext {
+				t.Error("expected the last result not to have a next one")
+			}
+		})
+	}
+}
+
+func TestIncrementalResponseErrors(t *testing.T) {
+	var tests = map[string]struct {
+		payloads []string
+		expected string
+	}{
+		"UnknownID": {
+			payloads: []string{`{"data":{},"hasNext":true}`, `{"incremental":[{"id":"1","data":{}}],"hasNext":false}`},
+			expected: "fluentgraphql: incremental payload for unknown id 1",
+		},
+		"NotAList": {
+			payloads: []string{`{"data":{"a":{}},"hasNext":true}`, `{"incremental":[{"path":["a",0],"items":[1]}],"hasNext":false}`},
+			expected: "fluentgraphql: streamed items at [a] are not for a list",
+		},
+		"InvalidPath": {
+			payloads: []string{`{"data":{"a":[]},"hasNext":true}`, `{"incremental":[{"path":["a",3],"data":{}}],"hasNext":false}`},
+			expected: "fluentgraphql: invalid path [a 3] in incremental payload",
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := newIncrementalResponse()
+			var err error
+			for _, payload := range test.payloads {
+				if _, err = r.apply([]byte(payload)); err != nil {
+					break
+				}
+			}
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("expected error %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
+
+func TestExecuteIncremental(t *testing.T) {
+	var tests = map[string]struct {
+		handler  http.HandlerFunc
+		expected []string
+	}{
+		"Multipart": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if !strings.HasPrefix(r.Header.Get("Accept"), "multipart/mixed") {
+					t.Errorf("unexpected Accept header %q", r.Header.Get("Accept"))
+				}
+				mw := multipart.NewWriter(w)
+				mw.SetBoundary("-")
+				w.Header().Set("Content-Type", `multipart/mixed; boundary="-"; deferSpec=20220824`)
+				for _, payload := range []string{
+					`{"data":{"repository":{"name":"fluentgraphql"}},"pending":[{"id":"0","path":["repository"],"label":"issues"}],"hasNext":true}`,
+					`{"incremental":[{"id":"0","data":{"issues":{"totalCount":12}}}],"completed":[{"id":"0"}],"hasNext":false}`,
+				} {
+					part, _ := mw.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=utf-8"}})
+					io.WriteString(part, payload)
+				}
+				mw.Close()
+			},
+			expected: []string{
+				`true {"repository":{"name":"fluentgraphql"}} []`,
+				`false {"repository":{"issues":{"totalCount":12},"name":"fluentgraphql"}} [issues]`,
+			},
+		},
+		"Interrupted": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", `multipart/mixed; boundary="graphql"`)
+				io.WriteString(w, "\r\n--graphql\r\nContent-Type: application/json\r\n\r\n{\"data\":{\"a\":1},\"hasNext\":true}\r\n--graphql\r\n")
+			},
+			expected: []string{
+				`true {"a":1} []`,
+				"fluentgraphql: incremental response interrupted: unexpected EOF",
+			},
+		},
+		"TruncatedPart": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", `multipart/mixed; boundary="graphql"`)
+				io.WriteString(w, "\r\n--graphql\r\nContent-Type: application/json\r\n\r\n{\"data\":{\"a\":1},\"hasNext\":true}\r\n--graphql\r\nContent-Type: application/json\r\n\r\n{\"incremental\":")
+			},
+			expected: []string{
+				`true {"a":1} []`,
+				"fluentgraphql: incremental response interrupted: unexpected EOF",
+			},
+		},
+		"JSON": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				io.WriteString(w, `{"data":{"a":1}}`)
+			},
+			expected: []string{`false {"a":1} []`},
+		},
+	}
+
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(test.handler)
+			defer server.Close()
+
+			q := NewQuery().Selection("repository").Scalar("name").InlineFragment("", WithDefer("issues", nil)).Selection("issues").Scalar("totalCount").Root()
+			results, err := NewHTTPExecutor(server.URL).ExecuteIncremental(context.Background(), NewRequest(q, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for result := range results {
+				if result.Err != nil {
+					got = append(got, result.Err.Error())
+					continue
+				}
+				var labels []string
+				for _, c := range result.Completed {
+					labels = append(labels, c.Label)
+				}
+				got = append(got, fmt.Sprintf("%t %s %v", result.HasNext, result.Data, labels))
+			}
+			if diff := cmp.Diff(test.expected, got); diff != "" {
+				t.Errorf("unexpected results (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
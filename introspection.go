@@ -0,0 +1,558 @@
+package fluentgraphql
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+type introspectionConfig struct {
+	typeRefDepth   int
+	descriptions   bool
+	deprecated     bool
+	isRepeatable   bool
+	specifiedByURL bool
+}
+
+type introspectionOption func(*introspectionConfig)
+
+// WithTypeRefDepth specifies how many levels of ofType are selected for type references (7 by default)
+func WithTypeRefDepth(depth int) introspectionOption {
+	return func(c *introspectionConfig) {
+		c.typeRefDepth = depth
+	}
+}
+
+// WithoutDescriptions leaves descriptions out of the introspection query
+func WithoutDescriptions() introspectionOption {
+	return func(c *introspectionConfig) {
+		c.descriptions = false
+	}
+}
+
+// WithoutDeprecated leaves deprecated fields and enum values, and the deprecation flags, out of the introspection query
+func WithoutDeprecated() introspectionOption {
+	return func(c *introspectionConfig) {
+		c.deprecated = false
+	}
+}
+
+// WithDirectiveIsRepeatable selects whether directives are repeatable, which older servers don't support
+func WithDirectiveIsRepeatable() introspectionOption {
+	return func(c *introspectionConfig) {
+		c.isRepeatable = true
+	}
+}
+
+// WithSpecifiedByURL selects the specification URL of scalars, which older servers don't support
+func WithSpecifiedByURL() introspectionOption {
+	return func(c *introspectionConfig) {
+		c.specifiedByURL = true
+	}
+}
+
+// NewIntrospectionQuery returns the introspection query of a schema.
+// query IntrospectionQuery { __schema { ... } }
+func NewIntrospectionQuery(options ...introspectionOption) *Selection {
+	c := &introspectionConfig{
+		typeRefDepth: 7,
+		descriptions: true,
+		deprecated:   true,
+	}
+	for _, option := range options {
+		option(c)
+	}
+
+	q := NewQuery(WithName("IntrospectionQuery"))
+	schema := q.Selection("__schema")
+	schema.Selection("queryType").Scalar("name")
+	schema.Selection("mutationType").Scalar("name")
+	schema.Selection("subscriptionType").Scalar("name")
+	c.fullType(schema.Selection("types"))
+
+	directives := schema.Selection("directives").Scalar("name")
+	c.description(directives)
+	if c.isRepeatable {
+		directives.Scalar("isRepeatable")
+	}
+	directives.Scalar("locations")
+	c.inputValue(directives.Selection("args"))
+
+	return q
+}
+
+func (c *introspectionConfig) fullType(s *Selection) {
+	s.Scalar("kind").Scalar("name")
+	c.description(s)
+	if c.specifiedByURL {
+		s.Scalar("specifiedByURL")
+	}
+
+	fields := s.Selection("fields", c.includeDeprecated()...).Scalar("name")
+	c.description(fields)
+	c.inputValue(fields.Selection("args"))
+	c.typeRef(fields.Selection("type"), c.typeRefDepth)
+	c.deprecation(fields)
+
+	c.inputValue(s.Selection("inputFields"))
+	c.typeRef(s.Selection("interfaces"), c.typeRefDepth)
+
+	enumValues := s.Selection("enumValues", c.includeDeprecated()...).Scalar("name")
+	c.description(enumValues)
+	c.deprecation(enumValues)
+
+	c.typeRef(s.Selection("possibleTypes"), c.typeRefDepth)
+}
+
+func (c *introspectionConfig) inputValue(s *Selection) {
+	s.Scalar("name")
+	c.description(s)
+	c.typeRef(s.Selection("type"), c.typeRefDepth)
+	s.Scalar("defaultValue")
+}
+
+func (c *introspectionConfig) typeRef(s *Selection, depth int) {
+	s.Scalar("kind").Scalar("name")
+	if depth > 0 {
+		c.typeRef(s.Selection("ofType"), depth-1)
+	}
+}
+
+func (c *introspectionConfig) description(s *Selection) {
+	if c.descriptions {
+		s.Scalar("description")
+	}
+}
+
+func (c *introspectionConfig) deprecation(s *Selection) {
+	if c.deprecated {
+		s.Scalar("isDeprecated").Scalar("deprecationReason")
+	}
+}
+
+func (c *introspectionConfig) includeDeprecated() []SelectionOption {
+	if !c.deprecated {
+		return nil
+	}
+	return []SelectionOption{WithArguments(NewArgument("includeDeprecated", NewBooleanValue(true)))}
+}
+
+// IntrospectedSchema is a schema loaded from the result of an introspection query
+type IntrospectedSchema struct {
+	// Document holds the type system definitions of the schema
+	Document *ast.Document
+	// Schema can validate and execute operations with graphql-go
+	Schema graphql.Schema
+
+	repeatable map[string]bool
+}
+
+// LoadIntrospection loads a schema from the JSON result of an introspection query,
+// either the whole response or its data.
+func LoadIntrospection(r io.Reader) (*IntrospectedSchema, error) {
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := parseIntrospection(b)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := result.document()
+	if err != nil {
+		return nil, err
+	}
+	schema, err := BuildSchema(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	repeatable := make(map[string]bool)
+	for _, d := range result.Directives {
+		repeatable[d.Name] = d.IsRepeatable
+	}
+	return &IntrospectedSchema{Document: doc, Schema: schema, repeatable: repeatable}, nil
+}
+
+// ParseIntrospection converts the JSON result of an introspection query into type system definitions
+func ParseIntrospection(b []byte) (*ast.Document, error) {
+	result, err := parseIntrospection(b)
+	if err != nil {
+		return nil, err
+	}
+	return result.document()
+}
+
+// SDL returns the schema in the GraphQL schema definition language
+func (s *IntrospectedSchema) SDL() string {
+	return printSDL(s.Document.Definitions, s.repeatable)
+}
+
+type introspectionResult struct {
+	Data *struct {
+		Schema *introspectionSchema `json:"__schema"`
+	} `json:"data"`
+	Schema *introspectionSchema `json:"__schema"`
+}
+
+type introspectionSchema struct {
+	QueryType        *introspectionTypeRef    `json:"queryType"`
+	MutationType     *introspectionTypeRef    `json:"mutationType"`
+	SubscriptionType *introspectionTypeRef    `json:"subscriptionType"`
+	Types            []introspectionType      `json:"types"`
+	Directives       []introspectionDirective `json:"directives"`
+}
+
+type introspectionType struct {
+	Kind           string                    `json:"kind"`
+	Name           string                    `json:"name"`
+	Description    *string                   `json:"description"`
+	SpecifiedByURL *string                   `json:"specifiedByURL"`
+	Fields         []introspectionField      `json:"fields"`
+	InputFields    []introspectionInputValue `json:"inputFields"`
+	Interfaces     []introspectionTypeRef    `json:"interfaces"`
+	EnumValues     []introspectionEnumValue  `json:"enumValues"`
+	PossibleTypes  []introspectionTypeRef    `json:"possibleTypes"`
+}
+
+type introspectionField struct {
+	Name              string                    `json:"name"`
+	Description       *string                   `json:"description"`
+	Args              []introspectionInputValue `json:"args"`
+	Type              introspectionTypeRef      `json:"type"`
+	IsDeprecated      bool                      `json:"isDeprecated"`
+	DeprecationReason *string                   `json:"deprecationReason"`
+}
+
+type introspectionInputValue struct {
+	Name         string               `json:"name"`
+	Description  *string              `json:"description"`
+	Type         introspectionTypeRef `json:"type"`
+	DefaultValue *string              `json:"defaultValue"`
+}
+
+type introspectionEnumValue struct {
+	Name              string  `json:"name"`
+	Description       *string `json:"description"`
+	IsDeprecated      bool    `json:"isDeprecated"`
+	DeprecationReason *string `json:"deprecationReason"`
+}
+
+type introspectionDirective struct {
+	Name         string                    `json:"name"`
+	Description  *string                   `json:"description"`
+	IsRepeatable bool                      `json:"isRepeatable"`
+	Locations    []string                  `json:"locations"`
+	Args         []introspectionInputValue `json:"args"`
+}
+
+type introspectionTypeRef struct {
+	Kind   string                `json:"kind"`
+	Name   *string               `json:"name"`
+	OfType *introspectionTypeRef `json:"ofType"`
+}
+
+func parseIntrospection(b []byte) (*introspectionSchema, error) {
+	var result introspectionResult
+	if err := json.Unmarshal(b, &result); err != nil {
+		return nil, err
+	}
+	schema := result.Schema
+	if result.Data != nil {
+		schema = result.Data.Schema
+	}
+	if schema == nil {
+		return nil, errors.New("fluentgraphql: no __schema in introspection result")
+	}
+	return schema, nil
+}
+
+// document converts the introspected schema into type system definitions
+func (schema *introspectionSchema) document() (*ast.Document, error) {
+	doc := ast.NewDocument(&ast.Document{})
+
+	operationTypes := make([]*ast.OperationTypeDefinition, 0, 3)
+	for _, root := range []struct {
+		operation string
+		ref       *introspectionTypeRef
+	}{
+		{ast.OperationTypeQuery, schema.QueryType},
+		{ast.OperationTypeMutation, schema.MutationType},
+		{ast.OperationTypeSubscription, schema.SubscriptionType},
+	} {
+		if root.ref != nil && root.ref.Name != nil {
+			operationTypes = append(operationTypes, ast.NewOperationTypeDefinition(&ast.OperationTypeDefinition{
+				Operation: root.operation,
+				Type:      namedType(*root.ref.Name),
+			}))
+		}
+	}
+	doc.Definitions = append(doc.Definitions, ast.NewSchemaDefinition(&ast.SchemaDefinition{
+		OperationTypes: operationTypes,
+		Directives:     []*ast.Directive{},
+	}))
+
+	for _, d := range schema.Directives {
+		if isSpecifiedDirective(d.Name) {
+			continue
+		}
+		args, err := inputValueDefinitions(d.Args)
+		if err != nil {
+			return nil, err
+		}
+		locations := make([]*ast.Name, 0, len(d.Locations))
+		for _, l := range d.Locations {
+			locations = append(locations, astName(l))
+		}
+		doc.Definitions = append(doc.Definitions, ast.NewDirectiveDefinition(&ast.DirectiveDefinition{
+			Name:        astName(d.Name),
+			Description: astDescription(d.Description),
+			Arguments:   args,
+			Locations:   locations,
+		}))
+	}
+
+	for _, t := range schema.Types {
+		if strings.HasPrefix(t.Name, "__") {
+			continue
+		}
+		def, err := t.definition()
+		if err != nil {
+			return nil, err
+		}
+		if def != nil {
+			doc.Definitions = append(doc.Definitions, def)
+		}
+	}
+
+	return doc, nil
+}
+
+func (t *introspectionType) definition() (ast.Node, error) {
+	switch t.Kind {
+	case "SCALAR":
+		if isBuiltinScalar(t.Name) {
+			return nil, nil
+		}
+		directives := []*ast.Directive{}
+		if t.SpecifiedByURL != nil {
+			directives = append(directives, ast.NewDirective(&ast.Directive{
+				Name: astName("specifiedBy"),
+				Arguments: []*ast.Argument{ast.NewArgument(&ast.Argument{
+					Name:  astName("url"),
+					Value: NewStringValue(*t.SpecifiedByURL).astValue,
+				})},
+			}))
+		}
+		return ast.NewScalarDefinition(&ast.ScalarDefinition{
+			Name:        astName(t.Name),
+			Description: astDescription(t.Description),
+			Directives:  directives,
+		}), nil
+	case "OBJECT", "INTERFACE":
+		fields := make([]*ast.FieldDefinition, 0, len(t.Fields))
+		for _, f := range t.Fields {
+			args, err := inputValueDefinitions(f.Args)
+			if err != nil {
+				return nil, err
+			}
+			typ, err := f.Type.astType()
+			if err != nil {
+				return nil, fmt.Errorf("fluentgraphql: field %s.%s: %v", t.Name, f.Name, err)
+			}
+			fields = append(fields, ast.NewFieldDefinition(&ast.FieldDefinition{
+				Name:        astName(f.Name),
+				Description: astDescription(f.Description),
+				Arguments:   args,
+				Type:        typ,
+				Directives:  deprecatedDirectives(f.IsDeprecated, f.DeprecationReason),
+			}))
+		}
+		if t.Kind == "INTERFACE" {
+			return ast.NewInterfaceDefinition(&ast.InterfaceDefinition{
+				Name:        astName(t.Name),
+				Description: astDescription(t.Description),
+				Fields:      fields,
+				Directives:  []*ast.Directive{},
+			}), nil
+		}
+		interfaces := make([]*ast.Named, 0, len(t.Interfaces))
+		for _, iface := range t.Interfaces {
+			named, err := iface.named()
+			if err != nil {
+				return nil, fmt.Errorf("fluentgraphql: interface of %s: %v", t.Name, err)
+			}
+			interfaces = append(interfaces, named)
+		}
+		return ast.NewObjectDefinition(&ast.ObjectDefinition{
+			Name:        astName(t.Name),
+			Description: astDescription(t.Description),
+			Interfaces:  interfaces,
+			Fields:      fields,
+			Directives:  []*ast.Directive{},
+		}), nil
+	case "UNION":
+		types := make([]*ast.Named, 0, len(t.PossibleTypes))
+		for _, possible := range t.PossibleTypes {
+			named, err := possible.named()
+			if err != nil {
+				return nil, fmt.Errorf("fluentgraphql: member of %s: %v", t.Name, err)
+			}
+			types = append(types, named)
+		}
+		return ast.NewUnionDefinition(&ast.UnionDefinition{
+			Name:        astName(t.Name),
+			Description: astDescription(t.Description),
+			Types:       types,
+			Directives:  []*ast.Directive{},
+		}), nil
+	case "ENUM":
+		values := make([]*ast.EnumValueDefinition, 0, len(t.EnumValues))
+		for _, v := range t.EnumValues {
+			values = append(values, ast.NewEnumValueDefinition(&ast.EnumValueDefinition{
+				Name:        astName(v.Name),
+				Description: astDescription(v.Description),
+				Directives:  deprecatedDirectives(v.IsDeprecated, v.DeprecationReason),
+			}))
+		}
+		return ast.NewEnumDefinition(&ast.EnumDefinition{
+			Name:        astName(t.Name),
+			Description: astDescription(t.Description),
+			Values:      values,
+			Directives:  []*ast.Directive{},
+		}), nil
+	case "INPUT_OBJECT":
+		fields, err := inputValueDefinitions(t.InputFields)
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewInputObjectDefinition(&ast.InputObjectDefinition{
+			Name:        astName(t.Name),
+			Description: astDescription(t.Description),
+			Fields:      fields,
+			Directives:  []*ast.Directive{},
+		}), nil
+	}
+	return nil, fmt.Errorf("fluentgraphql: unknown kind %s of type %s", t.Kind, t.Name)
+}
+
+// astType returns the type a reference refers to, or an error when the reference is
+// truncated, as happens when the introspection query didn't nest ofType deep enough
+func (r *introspectionTypeRef) astType() (ast.Type, error) {
+	switch r.Kind {
+	case "NON_NULL", "LIST":
+		if r.OfType == nil {
+			return nil, fmt.Errorf("truncated type reference: %s without ofType", r.Kind)
+		}
+		ofType, err := r.OfType.astType()
+		if err != nil {
+			return nil, err
+		}
+		if r.Kind == "LIST" {
+			return ast.NewList(&ast.List{Type: ofType}), nil
+		}
+		return ast.NewNonNull(&ast.NonNull{Type: ofType}), nil
+	}
+	if r.Name == nil {
+		return nil, fmt.Errorf("type reference of kind %s without a name", r.Kind)
+	}
+	return namedType(*r.Name), nil
+}
+
+// named returns the named type a reference refers to, for interfaces and union members
+func (r *introspectionTypeRef) named() (*ast.Named, error) {
+	typ, err := r.astType()
+	if err != nil {
+		return nil, err
+	}
+	named, ok := typ.(*ast.Named)
+	if !ok {
+		return nil, fmt.Errorf("expected a named type, got %s", typ.GetKind())
+	}
+	return named, nil
+}
+
+func inputValueDefinitions(values []introspectionInputValue) ([]*ast.InputValueDefinition, error) {
+	defs := make([]*ast.InputValueDefinition, 0, len(values))
+	for _, v := range values {
+		typ, err := v.Type.astType()
+		if err != nil {
+			return nil, fmt.Errorf("fluentgraphql: input value %s: %v", v.Name, err)
+		}
+		def := ast.NewInputValueDefinition(&ast.InputValueDefinition{
+			Name:        astName(v.Name),
+			Description: astDescription(v.Description),
+			Type:        typ,
+			Directives:  []*ast.Directive{},
+		})
+		if v.DefaultValue != nil {
+			value, err := parseValue(*v.DefaultValue)
+			if err != nil {
+				return nil, fmt.Errorf("fluentgraphql: default value of %s: %v", v.Name, err)
+			}
+			def.DefaultValue = value
+		}
+		defs = append(defs, def)
+	}
+	return defs, nil
+}
+
+// parseValue parses a GraphQL value literal, such as the default values found in introspection results
+func parseValue(literal string) (ast.Value, error) {
+	return parser.ParseValue(parser.ParseParams{
+		Source:  literal,
+		Options: parser.ParseOptions{NoSource: true, NoLocation: true},
+	})
+}
+
+func deprecatedDirectives(isDeprecated bool, reason *string) []*ast.Directive {
+	if !isDeprecated {
+		return []*ast.Directive{}
+	}
+	directive := ast.NewDirective(&ast.Directive{
+		Name:      astName("deprecated"),
+		Arguments: []*ast.Argument{},
+	})
+	if reason != nil {
+		directive.Arguments = append(directive.Arguments, ast.NewArgument(&ast.Argument{
+			Name:  astName("reason"),
+			Value: NewStringValue(*reason).astValue,
+		}))
+	}
+	return []*ast.Directive{directive}
+}
+
+func isBuiltinScalar(name string) bool {
+	switch name {
+	case "Int", "Float", "String", "Boolean", "ID":
+		return true
+	}
+	return false
+}
+
+func isSpecifiedDirective(name string) bool {
+	switch name {
+	case "include", "skip", "deprecated", "specifiedBy":
+		return true
+	}
+	return false
+}
+
+func astDescription(d *string) *ast.StringValue {
+	if d == nil || *d == "" {
+		return nil
+	}
+	return ast.NewStringValue(&ast.StringValue{Value: *d})
+}
+
+func astName(value string) *ast.Name {
+	return ast.NewName(&ast.Name{Value: value})
+}
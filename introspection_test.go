@@ -0,0 +1,254 @@
+package fluentgraphql
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/printer"
+)
+
+func TestIntrospectionQuery(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		wanted    string
+		selection *Selection
+	}{
+		"Minimal": {
+			wanted: `query IntrospectionQuery {
+				__schema {
+					queryType { name }
+					mutationType { name }
+					subscriptionType { name }
+					types {
+						kind name
+						fields { name args { name type { kind name } defaultValue } type { kind name } }
+						inputFields { name type { kind name } defaultValue }
+						interfaces { kind name }
+						enumValues { name }
+						possibleTypes { kind name }
+					}
+					directives { name locations args { name type { kind name } defaultValue } }
+				}
+			}`,
+			selection: NewIntrospectionQuery(WithTypeRefDepth(0), WithoutDescriptions(), WithoutDeprecated()),
+		},
+		"Full": {
+			wanted: `query IntrospectionQuery {
+				__schema {
+					queryType { name }
+					mutationType { name }
+					subscriptionType { name }
+					types {
+						kind name description specifiedByURL
+						fields(includeDeprecated: true) {
+							name description
+							args { name description type { kind name ofType { kind name } } defaultValue }
+							type { kind name ofType { kind name } }
+							isDeprecated deprecationReason
+						}
+						inputFields { name description type { kind name ofType { kind name } } defaultValue }
+						interfaces { kind name ofType { kind name } }
+						enumValues(includeDeprecated: true) { name description isDeprecated deprecationReason }
+						possibleTypes { kind name ofType { kind name } }
+					}
+					directives {
+						name description isRepeatable locations
+						args { name description type { kind name ofType { kind name } } defaultValue }
+					}
+				}
+			}`,
+			selection: NewIntrospectionQuery(WithTypeRefDepth(1), WithDirectiveIsRepeatable(), WithSpecifiedByURL()),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if diff := queryMatchesTree(t, testCase.wanted, testCase.selection.Root().node); diff != "" {
+				t.Log("produced GraphQL query does not match what's wanted", diff)
+				t.Fatal()
+			}
+		})
+	}
+}
+
+func TestLoadIntrospection(t *testing.T) {
+	f, err := os.Open("testdata/introspection.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	schema, err := LoadIntrospection(f)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wanted := `schema {
+  query: Query
+}
+
+directive @tag(name: String!) repeatable on FIELD | FRAGMENT_SPREAD
+
+type Query {
+  """Looks up a repository by owner and name"""
+  repository(owner: String!, name: String!): Repository
+  search(filter: SearchFilter): [SearchResult!]!
+}
+
+interface Node {
+  id: ID!
+}
+
+"""A repository of code"""
+type Repository implements Node {
+  id: ID!
+  nameWithOwner: String
+  owner: String @deprecated(reason: "Use nameWithOwner")
+  createdAt: DateTime
+  issues(first: Int = 10, states: [IssueState!] = [OPEN]): [Issue]
+}
+
+type Issue implements Node {
+  id: ID!
+  title: String!
+  state: IssueState
+}
+
+union SearchResult = Repository | Issue
+
+enum IssueState {
+  OPEN
+  CLOSED
+}
+
+input SearchFilter {
+  query: String!
+  state: IssueState = OPEN
+}
+
+"""An ISO-8601 encoded UTC date string"""
+scalar DateTime @specifiedBy(url: "https://tools.ietf.org/html/rfc3339")
+`
+	if diff := cmp.Diff(wanted, schema.SDL()); diff != "" {
+		t.Fatal("SDL does not match what's wanted", diff)
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema.Schema,
+		RequestString: `{ search(filter: { query: "fluent" }) { __typename ... on Repository { nameWithOwner issues { title } } ... on Issue { title state } } }`,
+		RootObject: map[string]interface{}{
+			"search": []interface{}{
+				map[string]interface{}{
+					"__typename":    "Repository",
+					"nameWithOwner": "mergestat/fluentgraphql",
+					"issues":        []interface{}{map[string]interface{}{"title": "Add introspection"}},
+				},
+				map[string]interface{}{"__typename": "Issue", "title": "Build schemas", "state": "OPEN"},
+			},
+		},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	got, _ := json.Marshal(result.Data)
+	if diff := cmp.Diff(`{"search":[{"__typename":"Repository","issues":[{"title":"Add introspection"}],"nameWithOwner":"mergestat/fluentgraphql"},{"__typename":"Issue","state":"OPEN","title":"Build schemas"}]}`, string(got)); diff != "" {
+		t.Fatal("execution result does not match what's wanted", diff)
+	}
+
+	invalid := graphql.Do(graphql.Params{Schema: schema.Schema, RequestString: `{ repository(owner: "mergestat") { stars } }`})
+	if len(invalid.Errors) != 2 {
+		t.Fatalf("expected a missing argument and an unknown field error, got %v", invalid.Errors)
+	}
+}
+
+func TestIntrospectionRoundTrip(t *testing.T) {
+	doc, err := parser.Parse(parser.ParseParams{Source: `
+		schema { query: Root }
+		type Root { user(id: ID!): User }
+		type User { id: ID! name: String friends(first: Int = 10): [User!] }
+	`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	built, err := BuildSchema(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := graphql.Do(graphql.Params{Schema: built, RequestString: NewIntrospectionQuery().String()})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	b, err := json.Marshal(result)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	schema, err := LoadIntrospection(bytes.NewReader(b))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := schema.Schema.QueryType().Name(); got != "Root" {
+		t.Fatalf("expected query type Root, got %s", got)
+	}
+	friends := schema.Schema.Type("User").(*graphql.Object).Fields()["friends"]
+	if len(friends.Args) != 1 || friends.Args[0].DefaultValue != 10 {
+		t.Fatalf("expected friends to keep its first argument defaulting to 10, got %+v", friends.Args)
+	}
+}
+
+func TestParseIntrospectionTruncated(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		wanted string
+		types  string
+	}{
+		"FieldType": {
+			wanted: "field Query.tags: truncated type reference: LIST without ofType",
+			types:  `{"kind": "OBJECT", "name": "Query", "fields": [{"name": "tags", "args": [], "type": {"kind": "NON_NULL", "name": null, "ofType": {"kind": "LIST", "name": null, "ofType": null}}}], "interfaces": []}`,
+		},
+		"Argument": {
+			wanted: "input value ids: truncated type reference: NON_NULL without ofType",
+			types:  `{"kind": "OBJECT", "name": "Query", "fields": [{"name": "tags", "args": [{"name": "ids", "type": {"kind": "NON_NULL", "name": null}}], "type": {"kind": "SCALAR", "name": "String"}}], "interfaces": []}`,
+		},
+		"Interface": {
+			wanted: "interface of Query: expected a named type, got NonNull",
+			types:  `{"kind": "OBJECT", "name": "Query", "fields": [], "interfaces": [{"kind": "NON_NULL", "name": null, "ofType": {"kind": "INTERFACE", "name": "Node"}}]}`,
+		},
+		"PossibleType": {
+			wanted: "member of Result: type reference of kind OBJECT without a name",
+			types:  `{"kind": "UNION", "name": "Result", "possibleTypes": [{"kind": "OBJECT", "name": null}]}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := ParseIntrospection([]byte(`{"__schema": {"queryType": {"name": "Query"}, "types": [` + testCase.types + `], "directives": []}}`))
+			if err == nil || err.Error() != "fluentgraphql: "+testCase.wanted {
+				t.Fatalf("expected error %q, got %v", testCase.wanted, err)
+			}
+		})
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		literal string
+		wanted  string
+	}{
+		"String":          {literal: `"a) { b }"`, wanted: `"a) { b }"`},
+		"BlockString":     {literal: `"""quoted "string")"""`, wanted: `"quoted \"string\")"`},
+		"Object":          {literal: `{query: "}", first: 1}`, wanted: `{query: "}", first: 1}`},
+		"List":            {literal: `[OPEN, CLOSED]`, wanted: `[OPEN, CLOSED]`},
+		"TrailingComment": {literal: `10 # the page size`, wanted: `10`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			value, err := parseValue(testCase.literal)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(testCase.wanted, printer.Print(value)); diff != "" {
+				t.Fatal("value does not match what's wanted", diff)
+			}
+		})
+	}
+}
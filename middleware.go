@@ -0,0 +1,133 @@
+package fluentgraphql
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Middleware wraps an executor to act on the requests it executes and their responses.
+// Requests reach middleware with their selection not printed yet, so that middleware can
+// rewrite the operation, and should be copied rather than modified:
+//
+//	func(next fgql.Executor) fgql.Executor {
+//		return fgql.ExecutorFunc(func(ctx context.Context, req *fgql.Request) (*fgql.Response, error) {
+//			inlined, err := req.Selection.InlineFragments()
+//			if err != nil {
+//				return nil, err
+//			}
+//			r := *req
+//			r.Selection = inlined
+//			return next.Execute(ctx, &r)
+//		})
+//	}
+type Middleware func(next Executor) Executor
+
+// Chain returns the executor wrapped in middleware, the first middleware being the
+// outermost, which sees requests first and responses last
+func Chain(executor Executor, middleware ...Middleware) Executor {
+	for i := len(middleware) - 1; i >= 0; i-- {
+		executor = middleware[i](executor)
+	}
+	return executor
+}
+
+// SetHeader is a middleware setting a header on every request
+func SetHeader(key, value string) Middleware {
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			return next.Execute(ctx, req.withHeader(key, value))
+		})
+	}
+}
+
+// Observe is a middleware calling observe after every request, with its response or error
+// and how long it took, for logging and metrics
+func Observe(observe func(ctx context.Context, req *Request, res *Response, err error, elapsed time.Duration)) Middleware {
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			start := time.Now()
+			res, err := next.Execute(ctx, req)
+			observe(ctx, req, res, err, time.Since(start))
+			return res, err
+		})
+	}
+}
+
+// TokenFunc returns the token to authenticate requests with. It is asked to refresh the
+// token when the server rejected the current one.
+type TokenFunc func(ctx context.Context, refresh bool) (string, error)
+
+// BearerAuth is a middleware authenticating requests with a bearer token. The token is
+// fetched once and shared by requests, until the server rejects it, with a 401 status or
+// an UNAUTHENTICATED error, in which case it is refreshed and the request sent again once.
+// Requests with uploads aren't sent again, as their files are read as they are sent:
+// their rejection is returned, with the token refreshed for the requests that follow.
+func BearerAuth(token TokenFunc) Middleware {
+	var mu sync.Mutex
+	var current string
+	get := func(ctx context.Context, rejected string) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		// another request may have refreshed the rejected token already
+		if current != "" && current != rejected {
+			return current, nil
+		}
+		t, err := token(ctx, rejected != "")
+		if err != nil {
+			return "", err
+		}
+		current = t
+		return t, nil
+	}
+
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			t, err := get(ctx, "")
+			if err != nil {
+				return nil, err
+			}
+			res, err := next.Execute(ctx, req.withHeader("Authorization", "Bearer "+t))
+			if !unauthenticated(res, err) {
+				return res, err
+			}
+			refreshed, refreshErr := get(ctx, t)
+			if refreshErr != nil {
+				return nil, refreshErr
+			}
+			if len(findUploads(req.Variables).uploads) > 0 {
+				return res, err
+			}
+			return next.Execute(ctx, req.withHeader("Authorization", "Bearer "+refreshed))
+		})
+	}
+}
+
+// unauthenticated tells whether a request failed for lack of valid credentials
+func unauthenticated(res *Response, err error) bool {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode == http.StatusUnauthorized
+	}
+	if res != nil {
+		for _, e := range res.Errors {
+			if e.Extensions["code"] == "UNAUTHENTICATED" {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// withHeader returns a copy of the request with a header set
+func (r *Request) withHeader(key, value string) *Request {
+	req := *r
+	req.Header = r.Header.Clone()
+	if req.Header == nil {
+		req.Header = make(http.Header)
+	}
+	req.Header.Set(key, value)
+	return &req
+}
@@ -0,0 +1,146 @@
+package fluentgraphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestChain(t *testing.T) {
+	var calls []string
+	trace := func(name string) Middleware {
+		return func(next Executor) Executor {
+			return ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+				calls = append(calls, name+" "+req.Header.Get("X-Trace"))
+				res, err := next.Execute(ctx, req)
+				calls = append(calls, name+" done")
+				return res, err
+			})
+		}
+	}
+	executor := ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		calls = append(calls, "execute "+req.Header.Get("X-Trace"))
+		return &Response{}, nil
+	})
+
+	req := NewRequest(NewQuery().Scalar("hello"), nil)
+	if _, err := Chain(executor, trace("outer"), SetHeader("X-Trace", "1"), trace("inner")).Execute(context.Background(), req); err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"outer ", "inner 1", "execute 1", "inner done", "outer done"}
+	if diff := cmp.Diff(expected, calls); diff != "" {
+		t.Errorf("unexpected calls (-want +got):\n%s", diff)
+	}
+	if req.Header != nil {
+		t.Errorf("expected the request to be left as is, got header %v", req.Header)
+	}
+}
+
+func TestObserve(t *testing.T) {
+	var observed []string
+	executor := Chain(
+		ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			return &Response{Errors: ResponseErrors{{Message: "not found"}}}, nil
+		}),
+		Observe(func(ctx context.Context, req *Request, res *Response, err error, elapsed time.Duration) {
+			observed = append(observed, fmt.Sprintf("%s %v %v %t", req.OperationName, res.Errors, err, elapsed >= 0))
+		}),
+	)
+	executor.Execute(context.Background(), NewRequest(NewQuery(WithName("Repo")).Scalar("hello"), nil))
+	if diff := cmp.Diff([]string{"Repo not found <nil> true"}, observed); diff != "" {
+		t.Errorf("unexpected observations (-want +got):\n%s", diff)
+	}
+}
+
+func TestBearerAuth(t *testing.T) {
+	type test struct {
+		name   string
+		reject func(w http.ResponseWriter)
+	}
+
+	var tests = []test{
+		{
+			name:   "Status",
+			reject: func(w http.ResponseWriter) { w.WriteHeader(http.StatusUnauthorized) },
+		},
+		{
+			name: "GraphQLError",
+			reject: func(w http.ResponseWriter) {
+				io.WriteString(w, `{"errors":[{"message":"token expired","extensions":{"code":"UNAUTHENTICATED"}}]}`)
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var received []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				auth := r.Header.Get("Authorization")
+				received = append(received, auth)
+				if auth != "Bearer token-2" {
+					test.reject(w)
+					return
+				}
+				io.WriteString(w, `{"data":{"ok":true}}`)
+			}))
+			defer server.Close()
+
+			var tokens []string
+			executor := Chain(NewHTTPExecutor(server.URL), BearerAuth(func(ctx context.Context, refresh bool) (string, error) {
+				tokens = append(tokens, fmt.Sprint(refresh))
+				return fmt.Sprintf("token-%d", len(tokens)), nil
+			}))
+			for i := 0; i < 2; i++ {
+				res, err := executor.Execute(context.Background(), NewRequest(NewQuery().Scalar("ok"), nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(res.Data) != `{"ok":true}` {
+					t.Errorf("unexpected data %s", res.Data)
+				}
+			}
+			if diff := cmp.Diff([]string{"Bearer token-1", "Bearer token-2", "Bearer token-2"}, received); diff != "" {
+				t.Errorf("unexpected Authorization headers (-want +got):\n%s", diff)
+			}
+			if strings.Join(tokens, " ") != "false true" {
+				t.Errorf("expected the token to be fetched then refreshed, got refresh %v", tokens)
+			}
+		})
+	}
+}
+
+func TestBearerAuthUpload(t *testing.T) {
+	var received []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = append(received, r.Header.Get("Authorization"))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	var tokens []string
+	executor := Chain(NewHTTPExecutor(server.URL), BearerAuth(func(ctx context.Context, refresh bool) (string, error) {
+		tokens = append(tokens, fmt.Sprint(refresh))
+		return fmt.Sprintf("token-%d", len(tokens)), nil
+	}))
+	q := NewMutation().Selection("upload", WithArguments(NewArgument("file", NewVariableValue("file")))).Scalar("id").Root()
+	_, err := executor.Execute(context.Background(), NewRequest(q, map[string]interface{}{"file": NewUpload(strings.NewReader("content"), "a.txt")}))
+
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected the rejection of the request, got %v", err)
+	}
+	if diff := cmp.Diff([]string{"Bearer token-1"}, received); diff != "" {
+		t.Errorf("expected the request with an upload to be sent once (-want +got):\n%s", diff)
+	}
+	if strings.Join(tokens, " ") != "false true" {
+		t.Errorf("expected the token to be refreshed for the requests that follow, got refresh %v", tokens)
+	}
+}
@@ -0,0 +1,138 @@
+package fluentgraphql
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Minify removes the whitespace, commas and comments GraphQL ignores from a document,
+// keeping a single space only where two names or numbers would otherwise run together
+func Minify(document string) (string, error) {
+	// the graphql-go lexer mixes byte and rune offsets after non-ASCII comments, so the
+	// document is scanned here, copying tokens from it as they are written
+	var b strings.Builder
+	var previousWord bool
+	for position := 0; ; {
+		start, end, word, err := nextToken(document, position)
+		if err != nil {
+			return "", err
+		}
+		if start == len(document) {
+			return b.String(), nil
+		}
+		if previousWord && word {
+			b.WriteByte(' ')
+		}
+		b.WriteString(document[start:end])
+		previousWord = word
+		position = end
+	}
+}
+
+// nextToken returns the bounds of the first token of the document from position, or
+// the end of the document when there is none, and whether it is a name or a number
+func nextToken(document string, position int) (start, end int, word bool, err error) {
+	for position < len(document) {
+		r, size := utf8.DecodeRuneInString(document[position:])
+		switch {
+		case r == '\uFEFF' || r == ' ' || r == '\t' || r == '\n' || r == '\r' || r == ',':
+			position += size
+			continue
+		case r == '#':
+			for position < len(document) && document[position] != '\n' && document[position] != '\r' {
+				position++
+			}
+			continue
+		}
+		break
+	}
+	if position == len(document) {
+		return position, position, false, nil
+	}
+
+	start = position
+	switch c := document[position]; {
+	case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+		return start, start + 1, false, nil
+	case c == '.':
+		if strings.HasPrefix(document[start:], "...") {
+			return start, start + 3, false, nil
+		}
+	case c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z':
+		end = start + 1
+		for end < len(document) && isNameByte(document[end]) {
+			end++
+		}
+		return start, end, true, nil
+	case c == '-' || c >= '0' && c <= '9':
+		end = start + 1
+		for end < len(document) {
+			c := document[end]
+			exponentSign := (c == '+' || c == '-') && (document[end-1] == 'e' || document[end-1] == 'E')
+			if !(c >= '0' && c <= '9' || c == '.' || c == 'e' || c == 'E' || exponentSign) {
+				break
+			}
+			end++
+		}
+		return start, end, true, nil
+	case strings.HasPrefix(document[start:], `"""`):
+		for end = start + 3; end < len(document); end++ {
+			if strings.HasPrefix(document[end:], `\"""`) {
+				end += 3
+				continue
+			}
+			if strings.HasPrefix(document[end:], `"""`) {
+				return start, end + 3, false, nil
+			}
+		}
+		return 0, 0, false, syntaxError(document, start, "unterminated block string")
+	case c == '"':
+		for end = start + 1; end < len(document); end++ {
+			switch document[end] {
+			case '\\':
+				end++
+			case '\n', '\r':
+				return 0, 0, false, syntaxError(document, start, "unterminated string")
+			case '"':
+				return start, end + 1, false, nil
+			}
+		}
+		return 0, 0, false, syntaxError(document, start, "unterminated string")
+	}
+	r, _ := utf8.DecodeRuneInString(document[start:])
+	return 0, 0, false, syntaxError(document, start, fmt.Sprintf("unexpected character %q", r))
+}
+
+func isNameByte(c byte) bool {
+	return c == '_' || c >= '0' && c <= '9' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// syntaxError reports an error at a byte offset of the document, by line and column
+func syntaxError(document string, offset int, message string) error {
+	before := document[:offset]
+	line := strings.Count(before, "\n") + 1
+	column := utf8.RuneCountInString(before[strings.LastIndexByte(before, '\n')+1:]) + 1
+	return fmt.Errorf("fluentgraphql: syntax error at %d:%d: %s", line, column, message)
+}
+
+// Minified returns the document of the selection, as printed by String, on a single
+// line without the whitespace GraphQL ignores
+func (s *Selection) Minified() string {
+	printed := s.String()
+	minified, err := Minify(printed)
+	if err != nil {
+		// the printer only prints documents that lex, so this is never reached
+		return printed
+	}
+	return minified
+}
+
+// Hash returns the SHA-256 of the minified document of the selection as a hex string,
+// the hash automatic persisted queries identify a document by
+func (s *Selection) Hash() string {
+	sum := sha256.Sum256([]byte(s.Minified()))
+	return hex.EncodeToString(sum[:])
+}
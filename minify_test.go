@@ -0,0 +1,123 @@
+package fluentgraphql
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestMinify(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		wanted   string
+		document string
+	}{
+		"Whitespace": {
+			wanted: `query Repo($first:Int=10){repository(owner:"mergestat"name:"fluent graphql"){issues(first:$first states:[OPEN CLOSED]){totalCount}}}`,
+			document: `query Repo($first: Int = 10) {
+				repository(owner: "mergestat", name: "fluent graphql") {
+					issues(first: $first, states: [OPEN, CLOSED]) {
+						totalCount
+					}
+				}
+			}`,
+		},
+		"Comments": {
+			wanted: `{hero{name}}`,
+			document: `# the hero
+				{ hero { name } } # and its name`,
+		},
+		"Fragments": {
+			wanted:   `{hero{...heroFields...on Droid{primaryFunction}}}fragment heroFields on Character{name}`,
+			document: `{ hero { ...heroFields ... on Droid { primaryFunction } } } fragment heroFields on Character { name }`,
+		},
+		"Strings": {
+			wanted:   `{search(query:"a \"b\"  c"text:"""  block  """numbers:[1 2.5 -3])}`,
+			document: `{ search(query: "a \"b\"  c", text: """  block  """, numbers: [1, 2.5, -3]) }`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			minified, err := Minify(testCase.document)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(testCase.wanted, minified); diff != "" {
+				t.Fatal("minified document does not match what's wanted", diff)
+			}
+
+			original, err := Parse(testCase.document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reparsed, err := Parse(minified)
+			if err != nil {
+				t.Fatalf("unexpected error parsing the minified document: %v", err)
+			}
+			if diff := cmp.Diff(original.String(), reparsed.String()); diff != "" {
+				t.Fatal("minified document does not parse back to the original", diff)
+			}
+		})
+	}
+
+	for _, document := range []string{`{ hero(name: "unterminated) }`, `{ hero(text: """unterminated) }`, `{ hero ? }`} {
+		if _, err := Minify(document); err == nil {
+			t.Fatalf("expected a syntax error minifying %s", document)
+		}
+	}
+}
+
+func TestMinifyNonASCII(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		wanted   string
+		document string
+	}{
+		"CommentStart": {
+			wanted:   `query{a}`,
+			document: "#é\nquery { a }",
+		},
+		"Comment": {
+			wanted:   `query{a}`,
+			document: "# é\nquery { a }",
+		},
+		"ByteOrderMark": {
+			wanted:   `{hero{name}}`,
+			document: "\uFEFF{ hero { name } }",
+		},
+		"Strings": {
+			wanted:   `{search(query:"héros \"ü\""text:"""日本 \""" """){name}}`,
+			document: "# 検索\n{ search(query: \"héros \\\"ü\\\"\", text: \"\"\"日本 \\\"\"\" \"\"\") { name } } # ✓",
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			minified, err := Minify(testCase.document)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if diff := cmp.Diff(testCase.wanted, minified); diff != "" {
+				t.Fatal("minified document does not match what's wanted", diff)
+			}
+
+			original, err := Parse(testCase.document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			reparsed, err := Parse(minified)
+			if err != nil {
+				t.Fatalf("unexpected error parsing the minified document: %v", err)
+			}
+			if diff := cmp.Diff(original.String(), reparsed.String()); diff != "" {
+				t.Fatal("minified document does not parse back to the original", diff)
+			}
+		})
+	}
+}
+
+func TestHash(t *testing.T) {
+	q := NewQuery().Selection("repository", WithArguments(NewArgument("owner", NewStringValue("mergestat")))).Scalar("name").Root()
+
+	if diff := cmp.Diff(`{repository(owner:"mergestat"){name}}`, q.Minified()); diff != "" {
+		t.Fatal("minified GraphQL query does not match what's wanted", diff)
+	}
+	if diff := cmp.Diff("506baa5d4316d33b552ff56ef003de51f00cfbcdba36740091821ec6f8690bb4", q.Hash()); diff != "" {
+		t.Fatal("hash does not match what's wanted", diff)
+	}
+}
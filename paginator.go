@@ -0,0 +1,297 @@
+package fluentgraphql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Paginator pages through every connection of an operation at once, such as a
+// batch of aliased selections each holding a connection. Each connection keeps
+// its own cursor, and once a connection has no more pages it's left out of the
+// operations that follow, which only select the paths to the remaining connections.
+type Paginator struct {
+	root      *Selection
+	executor  Executor
+	variables map[string]interface{}
+	cursors   map[*connection]string
+	active    []*connection
+	started   bool
+	pages     []*Page
+	err       error
+	failed    ConnectionErrors
+}
+
+// ConnectionError is the error that stopped the pagination of a single connection of a
+// Paginator, which keeps paging through the other connections
+type ConnectionError struct {
+	// Path holds the response keys leading to the connection
+	Path []string
+	Err  error
+}
+
+func (e *ConnectionError) Error() string {
+	return fmt.Sprintf("fluentgraphql: connection %s: %v", strings.Join(e.Path, "."), e.Err)
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+// ConnectionErrors lists the connections of a Paginator that failed
+type ConnectionErrors []*ConnectionError
+
+func (e ConnectionErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// errCursorNotAdvanced is the error of a connection whose page ends with the cursor it started from
+var errCursorNotAdvanced = errors.New("cursor did not advance")
+
+// AllPages returns a paginator for the connections in the operation of the selection.
+// Connections within the nodes of another connection are not paginated on their own.
+// A connection whose data is missing from a response (e.g. its parent is null) is
+// considered to have no more pages, unless the response has errors located where the
+// data is missing. These errors, like a cursor that doesn't advance, stop the pagination
+// of that connection only, and are returned by Err as ConnectionErrors once the others
+// are done. The other errors of a response are found in the Response of its pages.
+//
+//	p := q.AllPages(executor, nil)
+//	for p.Next(ctx) {
+//		for _, page := range p.Pages() {
+//			// page.Path identifies the connection, e.g. [repo_0 issues]
+//		}
+//	}
+//	if err := p.Err(); err != nil { ... }
+func (s *Selection) AllPages(executor Executor, variables map[string]interface{}) *Paginator {
+	root := s.Root()
+	p := &Paginator{
+		root:      root,
+		executor:  executor,
+		variables: variables,
+		cursors:   make(map[*connection]string),
+	}
+	for _, conn := range root.connections {
+		if !conn.nested {
+			p.active = append(p.active, conn)
+		}
+	}
+	return p
+}
+
+// Next fetches the next page of every connection that still has pages,
+// returning false when none have or an error occurred
+func (p *Paginator) Next(ctx context.Context) bool {
+	if p.err != nil || len(p.active) == 0 {
+		return false
+	}
+
+	selection := p.root
+	if p.started {
+		selection = p.remaining()
+	}
+	p.started = true
+
+	variables := make(map[string]interface{}, len(p.variables)+2*len(p.active))
+	for name, value := range p.variables {
+		variables[name] = value
+	}
+	for _, conn := range p.active {
+		variables[conn.sizeVar] = conn.pageSize
+		if cursor := p.cursors[conn]; cursor != "" {
+			variables[conn.cursorVar] = cursor
+		}
+	}
+
+	res, err := p.executor.Execute(ctx, NewRequest(selection, declaredVariables(selection, variables)))
+	if err != nil {
+		p.err = err
+		return false
+	}
+	if len(res.Errors) > 0 && (len(res.Data) == 0 || string(res.Data) == "null") {
+		p.err = res.Errors
+		return false
+	}
+
+	p.pages = make([]*Page, 0, len(p.active))
+	active := p.active[:0]
+	for _, conn := range p.active {
+		page, err := conn.page(res)
+		if err != nil {
+			if _, ok := err.(*noDataError); !ok {
+				p.failed = append(p.failed, &ConnectionError{Path: conn.path, Err: err})
+			}
+			continue
+		}
+		// as with Pages, a page ending with the cursor it started from is not returned
+		next := conn.nextCursor(page.PageInfo)
+		if next != "" && next == p.cursors[conn] {
+			p.failed = append(p.failed, &ConnectionError{Path: conn.path, Err: errCursorNotAdvanced})
+			continue
+		}
+		p.pages = append(p.pages, page)
+		if next != "" {
+			p.cursors[conn] = next
+			active = append(active, conn)
+		}
+	}
+	p.active = active
+
+	return true
+}
+
+// Pages returns the pages fetched by the last call to Next, one per connection
+func (p *Paginator) Pages() []*Page {
+	return p.pages
+}
+
+// Page returns the page fetched by the last call to Next for the connection at the
+// given dot separated path of response keys (e.g. "repo_0.issues"), or nil if there's none
+func (p *Paginator) Page(path string) *Page {
+	for _, page := range p.pages {
+		if strings.Join(page.Path, ".") == path {
+			return page
+		}
+	}
+	return nil
+}
+
+// Err returns the error that stopped the pagination, if any, or else the ConnectionErrors
+// of the connections that failed
+func (p *Paginator) Err() error {
+	if p.err != nil {
+		return p.err
+	}
+	if len(p.failed) > 0 {
+		return p.failed
+	}
+	return nil
+}
+
+// remaining returns a copy of the operation that only selects the paths to the active connections
+func (p *Paginator) remaining() *Selection {
+	op := p.root.node.(*ast.OperationDefinition)
+	keep := make(map[*ast.Field]bool, len(p.active))
+	for _, conn := range p.active {
+		keep[conn.field] = true
+	}
+
+	pruned := *op
+	pruned.SelectionSet = pruneSelectionSet(op.SelectionSet, keep)
+	pruned.VariableDefinitions = usedVariableDefinitions(op.VariableDefinitions, pruned.SelectionSet)
+	return p.root.derive(&pruned)
+}
+
+// pruneSelectionSet returns a copy of set holding only the kept fields and their ancestors.
+// The fragment spreads of a set that still holds a kept field or ancestor are kept along with it.
+func pruneSelectionSet(set *ast.SelectionSet, keep map[*ast.Field]bool) *ast.SelectionSet {
+	pruned := ast.NewSelectionSet(&ast.SelectionSet{})
+	spreads := 0
+	for _, selection := range set.Selections {
+		switch n := selection.(type) {
+		case *ast.Field:
+			if keep[n] {
+				pruned.Selections = append(pruned.Selections, n)
+			} else if n.SelectionSet != nil {
+				if s := pruneSelectionSet(n.SelectionSet, keep); len(s.Selections) > 0 {
+					field := *n
+					field.SelectionSet = s
+					pruned.Selections = append(pruned.Selections, &field)
+				}
+			}
+		case *ast.InlineFragment:
+			if s := pruneSelectionSet(n.SelectionSet, keep); len(s.Selections) > 0 {
+				fragment := *n
+				fragment.SelectionSet = s
+				pruned.Selections = append(pruned.Selections, &fragment)
+			}
+		case *ast.FragmentSpread:
+			pruned.Selections = append(pruned.Selections, n)
+			spreads++
+		}
+	}
+	if len(pruned.Selections) == spreads {
+		pruned.Selections = nil
+	}
+	return pruned
+}
+
+// usedVariableDefinitions returns the definitions of the variables referenced within node
+func usedVariableDefinitions(defs []*ast.VariableDefinition, node ast.Node) []*ast.VariableDefinition {
+	used := make(map[string]bool)
+	collectVariables(node, used)
+
+	kept := make([]*ast.VariableDefinition, 0, len(defs))
+	for _, def := range defs {
+		if used[def.Variable.Name.Value] {
+			kept = append(kept, def)
+		}
+	}
+	return kept
+}
+
+// declaredVariables returns the variables that are declared by the operation of the selection
+func declaredVariables(s *Selection, variables map[string]interface{}) map[string]interface{} {
+	op, ok := s.Root().node.(*ast.OperationDefinition)
+	if !ok {
+		return variables
+	}
+	declared := make(map[string]interface{}, len(variables))
+	for _, def := range op.VariableDefinitions {
+		if value, ok := variables[def.Variable.Name.Value]; ok {
+			declared[def.Variable.Name.Value] = value
+		}
+	}
+	return declared
+}
+
+// collectVariables records the names of the variables referenced within node
+func collectVariables(node interface{}, used map[string]bool) {
+	switch n := node.(type) {
+	case *ast.SelectionSet:
+		if n == nil {
+			return
+		}
+		for _, selection := range n.Selections {
+			collectVariables(selection, used)
+		}
+	case *ast.Field:
+		for _, arg := range n.Arguments {
+			collectVariables(arg.Value, used)
+		}
+		for _, directive := range n.Directives {
+			collectVariables(directive, used)
+		}
+		collectVariables(n.SelectionSet, used)
+	case *ast.InlineFragment:
+		for _, directive := range n.Directives {
+			collectVariables(directive, used)
+		}
+		collectVariables(n.SelectionSet, used)
+	case *ast.FragmentSpread:
+		for _, directive := range n.Directives {
+			collectVariables(directive, used)
+		}
+	case *ast.Directive:
+		for _, arg := range n.Arguments {
+			collectVariables(arg.Value, used)
+		}
+	case *ast.Variable:
+		used[n.Name.Value] = true
+	case *ast.ListValue:
+		for _, value := range n.Values {
+			collectVariables(value, used)
+		}
+	case *ast.ObjectValue:
+		for _, field := range n.Fields {
+			collectVariables(field.Value, used)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+package fluentgraphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+func TestAllPages(t *testing.T) {
+	q := NewQuery(WithName("Issues"))
+	for i, pages := range []int{1, 3} {
+		q.Selection("repository", WithAlias(fmt.Sprintf("repo_%d", i)), WithArguments(NewArgument("pages", NewIntValue(pages)))).
+			Scalar("name").
+			Connection("issues", 10, func(node *Selection) {
+				node.Scalar("number")
+			})
+	}
+
+	var queries []string
+	executor := ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		queries = append(queries, req.Query())
+
+		data := make(map[string]interface{})
+		for i, pages := range []int{1, 3} {
+			alias := fmt.Sprintf("repo_%d", i)
+			page := 1
+			if cursor, ok := req.Variables[alias+"_issues_after"]; ok {
+				fmt.Sscanf(cursor.(string), "c%d", &page)
+				page++
+			} else if len(queries) > 1 {
+				continue
+			}
+			data[alias] = map[string]interface{}{
+				"issues": map[string]interface{}{
+					"edges":      []interface{}{map[string]interface{}{"node": map[string]interface{}{"number": page}}},
+					"pageInfo":   map[string]interface{}{"hasNextPage": page < pages, "endCursor": fmt.Sprintf("c%d", page)},
+					"totalCount": pages,
+				},
+			}
+		}
+		b, _ := json.Marshal(data)
+		return &Response{Data: b}, nil
+	})
+
+	seen := make(map[string]int)
+	p := q.AllPages(executor, nil)
+	for p.Next(context.Background()) {
+		for _, page := range p.Pages() {
+			seen[fmt.Sprint(page.Path)] += len(page.Nodes)
+		}
+	}
+	if err := p.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if seen["[repo_0 issues]"] != 1 || seen["[repo_1 issues]"] != 3 {
+		t.Fatalf("unexpected pages: %v", seen)
+	}
+	if len(queries) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(queries))
+	}
+
+	wanted := `query Issues($repo_1_issues_first: Int = 10, $repo_1_issues_after: String) {
+		repo_1: repository(pages: 3) {
+			issues(first: $repo_1_issues_first, after: $repo_1_issues_after) {
+				edges { node { number } }
+				pageInfo { hasNextPage endCursor }
+				totalCount
+			}
+		}
+	}`
+	remaining, err := parser.Parse(parser.ParseParams{Source: queries[1], Options: parser.ParseOptions{NoSource: true, NoLocation: true}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := queryMatchesTree(t, wanted, remaining.Definitions[0]); diff != "" {
+		t.Log("remaining GraphQL query does not match what's wanted", diff)
+		t.Fatal()
+	}
+}
+
+func TestAllPagesErrors(t *testing.T) {
+	q := NewQuery()
+	for _, alias := range []string{"repo_0", "repo_1", "repo_2", "repo_3"} {
+		q.Selection("repository", WithAlias(alias)).Connection("issues", 10, func(node *Selection) {
+			node.Scalar("number")
+		})
+	}
+
+	requests := 0
+	executor := ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		requests++
+		if requests > 1 {
+			// repo_0 has a second and last page, while repo_3 is stuck on its first one
+			return &Response{Data: json.RawMessage(`{
+				"repo_0": {"issues": {"edges": [{"node": {"number": 2}}], "pageInfo": {"hasNextPage": false, "endCursor": "c2"}, "totalCount": 2}},
+				"repo_3": {"issues": {"edges": [{"node": {"number": 1}}], "pageInfo": {"hasNextPage": true, "endCursor": "c1"}, "totalCount": 2}}
+			}`)}, nil
+		}
+		return &Response{
+			Data: json.RawMessage(`{
+				"repo_0": {"issues": {"edges": [{"node": {"number": 1}}], "pageInfo": {"hasNextPage": true, "endCursor": "c1"}, "totalCount": 2}},
+				"repo_1": null,
+				"repo_2": null,
+				"repo_3": {"issues": {"edges": [{"node": {"number": 1}}], "pageInfo": {"hasNextPage": true, "endCursor": "c1"}, "totalCount": 2}}
+			}`),
+			Errors: ResponseErrors{
+				{Message: "issue hidden", Path: []interface{}{"repo_0", "issues", "edges", 0.0, "node"}},
+				{Message: "repository not found", Path: []interface{}{"repo_2"}},
+			},
+		}, nil
+	})
+
+	seen := make(map[string]int)
+	p := q.AllPages(executor, nil)
+	for p.Next(context.Background()) {
+		for _, page := range p.Pages() {
+			seen[fmt.Sprint(page.Path)] += len(page.Nodes)
+		}
+	}
+	if seen["[repo_0 issues]"] != 2 || seen["[repo_3 issues]"] != 1 || len(seen) != 2 {
+		t.Fatalf("unexpected pages: %v", seen)
+	}
+
+	errs, ok := p.Err().(ConnectionErrors)
+	if !ok || len(errs) != 2 {
+		t.Fatalf("expected the errors of 2 connections, got %v", p.Err())
+	}
+	if responseErrs, ok := errs[0].Err.(ResponseErrors); fmt.Sprint(errs[0].Path) != "[repo_2 issues]" || !ok || len(responseErrs) != 1 || responseErrs[0].Message != "repository not found" {
+		t.Errorf("expected the error of the missing repository, got %v", errs[0])
+	}
+	if fmt.Sprint(errs[1].Path) != "[repo_3 issues]" || !errors.Is(errs[1], errCursorNotAdvanced) {
+		t.Errorf("expected the cursor of repo_3 not to advance, got %v", errs[1])
+	}
+}
+
+func TestAllPagesFragments(t *testing.T) {
+	issueFields := NewFragment("issueFields", "Issue").Scalar("number").Scalar("title")
+	repositoryFields := NewFragment("repositoryFields", "Repository").Scalar("name")
+	q := NewQuery(WithName("Issues"), WithTypenames())
+	q.Selection("viewer").Spread(NewFragment("userFields", "User").Scalar("login"))
+	for i, pages := range []int{1, 3} {
+		q.Selection("repository", WithAlias(fmt.Sprintf("repo_%d", i)), WithArguments(NewArgument("pages", NewIntValue(pages)))).
+			Spread(repositoryFields).
+			Connection("issues", 10, func(node *Selection) {
+				node.Spread(issueFields)
+			})
+	}
+
+	var queries []string
+	executor := ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+		queries = append(queries, req.Query())
+
+		cursor, _ := req.Variables["repo_1_issues_after"].(string)
+		page := 1
+		fmt.Sscanf(cursor, "c%d", &page)
+		if cursor != "" {
+			page++
+		}
+		return &Response{Data: json.RawMessage(fmt.Sprintf(`{
+			"repo_0": {"issues": {"edges": [], "pageInfo": {"hasNextPage": false, "endCursor": "c1"}, "totalCount": 0}},
+			"repo_1": {"issues": {"edges": [], "pageInfo": {"hasNextPage": %t, "endCursor": "c%d"}, "totalCount": 0}}
+		}`, page < 3, page))}, nil
+	})
+
+	p := q.AllPages(executor, nil)
+	for p.Next(context.Background()) {
+	}
+	if err := p.Err(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(queries) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(queries))
+	}
+
+	wanted := `query Issues($repo_1_issues_first: Int = 10, $repo_1_issues_after: String) {
+		repo_1: repository(pages: 3) {
+			__typename
+			...repositoryFields
+			issues(first: $repo_1_issues_first, after: $repo_1_issues_after) {
+				__typename
+				edges { __typename node { __typename ...issueFields } }
+				pageInfo { __typename hasNextPage endCursor }
+				totalCount
+			}
+		}
+	}
+
+	fragment repositoryFields on Repository { __typename name }
+
+	fragment issueFields on Issue { __typename number title }`
+	opts := parser.ParseOptions{NoSource: true, NoLocation: true}
+	wantedDocument, err := parser.Parse(parser.ParseParams{Source: wanted, Options: opts})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, query := range queries[1:] {
+		document, err := parser.Parse(parser.ParseParams{Source: query, Options: opts})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if diff := cmp.Diff(wantedDocument, document); diff != "" {
+			t.Fatal("remaining GraphQL query does not match what's wanted", diff)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package fluentgraphql
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+// Parse returns a selection builder for the first operation of a GraphQL document.
+// The fragment definitions of the document are kept and printed along with the operation.
+func Parse(document string) (*Selection, error) {
+	doc, err := parser.Parse(parser.ParseParams{
+		Source:  maskIgnored(document),
+		Options: parser.ParseOptions{NoSource: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return FromAST(doc)
+}
+
+// ParseDocument parses a GraphQL document with graphql-go, keeping the locations of its
+// nodes. Unlike parsing with graphql-go directly, documents with non-ASCII characters in
+// their comments are read correctly.
+func ParseDocument(document string) (*ast.Document, error) {
+	return parser.Parse(parser.ParseParams{Source: maskIgnored(document)})
+}
+
+// maskIgnored replaces the non-ASCII characters outside of tokens, in comments or byte
+// order marks, with a space each, keeping the lines and columns of tokens. graphql-go
+// mixes up byte and character offsets after such characters, misreading the next tokens.
+func maskIgnored(document string) string {
+	var b strings.Builder
+	for position := 0; position < len(document); {
+		start, end, _, err := nextToken(document, position)
+		if err != nil {
+			// the parser reports the error
+			return document
+		}
+		for _, r := range document[position:start] {
+			if r >= utf8.RuneSelf {
+				r = ' '
+			}
+			b.WriteRune(r)
+		}
+		b.WriteString(document[start:end])
+		position = end
+	}
+	return b.String()
+}
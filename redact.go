@@ -0,0 +1,232 @@
+package fluentgraphql
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// redactedPlaceholder replaces the strings and enums of redacted documents and variables
+const redactedPlaceholder = "REDACTED"
+
+// Redacted returns the document of the selection, as printed by String, with the string,
+// number and enum literals of arguments, object values and variable defaults replaced
+// with placeholders, for logs and traces. The structure of the document is kept: strings
+// become "REDACTED", numbers zero and enums REDACTED, while booleans and variables are
+// left as they are, as are lists and objects, with their items and fields redacted.
+func (s *Selection) Redacted() string {
+	typed, err := s.typed()
+	if err != nil {
+		typed = s
+	}
+	definitions, _ := typed.definitions()
+
+	redacted := &Selection{node: redactNode(typed.node)}
+	for _, def := range definitions[1:] {
+		redacted.fragments = append(redacted.fragments, redactNode(def).(*ast.FragmentDefinition))
+	}
+	return redacted.String()
+}
+
+// RedactVariables returns a copy of the variables of an operation with the values of the
+// variables given to the arguments and input object fields with one of the names replaced,
+// as well as the fields with one of the names of the input objects given as variables.
+// Names match regardless of case. Strings become "REDACTED" and numbers 0, while the
+// structure of lists and objects is kept, along with booleans, nulls and uploads.
+func RedactVariables(s *Selection, variables map[string]interface{}, names ...string) map[string]interface{} {
+	denied := make(map[string]bool, len(names))
+	for _, name := range names {
+		denied[strings.ToLower(name)] = true
+	}
+
+	sensitive := make(map[string]bool)
+	definitions, _ := s.Root().definitions()
+	for _, def := range definitions {
+		Walk(&Selection{node: def}, VisitorFunc(func(c *Cursor) WalkAction {
+			v, ok := c.node.(*ast.Variable)
+			if !ok {
+				return Continue
+			}
+			// the argument or object field the variable is given to, past any list indexes
+			for i := len(c.path) - 1; i >= 0; i-- {
+				if _, err := strconv.Atoi(c.path[i]); err != nil {
+					if denied[strings.ToLower(c.path[i])] {
+						sensitive[v.Name.Value] = true
+					}
+					break
+				}
+			}
+			return Continue
+		}))
+	}
+
+	redacted := make(map[string]interface{}, len(variables))
+	for name, value := range variables {
+		if sensitive[name] {
+			redacted[name] = redactGoValue(value)
+		} else {
+			redacted[name] = redactFields(value, denied)
+		}
+	}
+	return redacted
+}
+
+// redactNode returns a copy of an operation or fragment definition, or of a selection,
+// with the literals of its arguments and variable defaults redacted
+func redactNode(node ast.Node) ast.Node {
+	switch n := node.(type) {
+	case *ast.OperationDefinition:
+		copied := *n
+		copied.VariableDefinitions = make([]*ast.VariableDefinition, 0, len(n.VariableDefinitions))
+		for _, def := range n.VariableDefinitions {
+			d := *def
+			if def.DefaultValue != nil {
+				d.DefaultValue = redactValue(def.DefaultValue)
+			}
+			copied.VariableDefinitions = append(copied.VariableDefinitions, &d)
+		}
+		copied.Directives = redactDirectives(n.Directives)
+		copied.SelectionSet = redactSelectionSet(n.SelectionSet)
+		return &copied
+	case *ast.FragmentDefinition:
+		copied := *n
+		copied.Directives = redactDirectives(n.Directives)
+		copied.SelectionSet = redactSelectionSet(n.SelectionSet)
+		return &copied
+	case *ast.Field:
+		copied := *n
+		copied.Arguments = redactArguments(n.Arguments)
+		copied.Directives = redactDirectives(n.Directives)
+		copied.SelectionSet = redactSelectionSet(n.SelectionSet)
+		return &copied
+	case *ast.InlineFragment:
+		copied := *n
+		copied.Directives = redactDirectives(n.Directives)
+		copied.SelectionSet = redactSelectionSet(n.SelectionSet)
+		return &copied
+	case *ast.FragmentSpread:
+		copied := *n
+		copied.Directives = redactDirectives(n.Directives)
+		return &copied
+	}
+	return node
+}
+
+func redactSelectionSet(set *ast.SelectionSet) *ast.SelectionSet {
+	if set == nil {
+		return nil
+	}
+	selections := make([]ast.Selection, 0, len(set.Selections))
+	for _, selection := range set.Selections {
+		selections = append(selections, redactNode(selection.(ast.Node)).(ast.Selection))
+	}
+	return ast.NewSelectionSet(&ast.SelectionSet{Selections: selections})
+}
+
+func redactArguments(args []*ast.Argument) []*ast.Argument {
+	redacted := make([]*ast.Argument, 0, len(args))
+	for _, arg := range args {
+		copied := *arg
+		copied.Value = redactValue(arg.Value)
+		redacted = append(redacted, &copied)
+	}
+	return redacted
+}
+
+func redactDirectives(directives []*ast.Directive) []*ast.Directive {
+	redacted := make([]*ast.Directive, 0, len(directives))
+	for _, d := range directives {
+		copied := *d
+		copied.Arguments = redactArguments(d.Arguments)
+		redacted = append(redacted, &copied)
+	}
+	return redacted
+}
+
+// redactValue returns a copy of a value with its string, number and enum literals replaced
+func redactValue(value ast.Value) ast.Value {
+	switch v := value.(type) {
+	case *ast.StringValue:
+		return ast.NewStringValue(&ast.StringValue{Value: redactedPlaceholder})
+	case *ast.IntValue:
+		return ast.NewIntValue(&ast.IntValue{Value: "0"})
+	case *ast.FloatValue:
+		return ast.NewFloatValue(&ast.FloatValue{Value: "0.0"})
+	case *ast.EnumValue:
+		return ast.NewEnumValue(&ast.EnumValue{Value: redactedPlaceholder})
+	case *ast.ListValue:
+		values := make([]ast.Value, 0, len(v.Values))
+		for _, item := range v.Values {
+			values = append(values, redactValue(item))
+		}
+		return ast.NewListValue(&ast.ListValue{Values: values})
+	case *ast.ObjectValue:
+		fields := make([]*ast.ObjectField, 0, len(v.Fields))
+		for _, f := range v.Fields {
+			fields = append(fields, ast.NewObjectField(&ast.ObjectField{Name: f.Name, Value: redactValue(f.Value)}))
+		}
+		return ast.NewObjectValue(&ast.ObjectValue{Fields: fields})
+	}
+	return value
+}
+
+// redactGoValue returns a copy of a variable value with everything but its booleans, nulls
+// and uploads replaced, keeping the structure of lists and objects
+func redactGoValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		return redactedPlaceholder
+	case json.Number:
+		return json.Number("0")
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return 0
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, redactGoValue(item))
+		}
+		return list
+	case []string:
+		list := make([]string, 0, len(v))
+		for range v {
+			list = append(list, redactedPlaceholder)
+		}
+		return list
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			object[key] = redactGoValue(item)
+		}
+		return object
+	case bool, nil, *Upload:
+		return value
+	}
+	// values of other types may hold anything
+	return redactedPlaceholder
+}
+
+// redactFields returns a copy of a variable value with the fields of its objects that have
+// one of the denied names redacted
+func redactFields(value interface{}, denied map[string]bool) interface{} {
+	switch v := value.(type) {
+	case []interface{}:
+		list := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			list = append(list, redactFields(item, denied))
+		}
+		return list
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if denied[strings.ToLower(key)] {
+				object[key] = redactGoValue(item)
+			} else {
+				object[key] = redactFields(item, denied)
+			}
+		}
+		return object
+	}
+	return value
+}
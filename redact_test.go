@@ -0,0 +1,108 @@
+package fluentgraphql
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRedacted(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		document string
+		wanted   string
+	}{
+		"Arguments": {
+			document: `{ user(email: "octocat@github.com", first: 10, ratio: 0.5, state: OPEN, archived: false) { login } }`,
+			wanted:   `{ user(email: "REDACTED", first: 0, ratio: 0.0, state: REDACTED, archived: false) { login } }`,
+		},
+		"ObjectsAndLists": {
+			document: `mutation ($id: ID!) { updateUser(input: { id: $id, emails: ["a@b.c", "d@e.f"], profile: { age: 30, public: true } }) { id } }`,
+			wanted:   `mutation ($id: ID!) { updateUser(input: { id: $id, emails: ["REDACTED", "REDACTED"], profile: { age: 0, public: true } }) { id } }`,
+		},
+		"VariableDefaults": {
+			document: `query ($token: String = "secret", $states: [State!] = [OPEN]) { viewer(token: $token) { issues(states: $states) { title } } }`,
+			wanted:   `query ($token: String = "REDACTED", $states: [State!] = [REDACTED]) { viewer(token: $token) { issues(states: $states) { title } } }`,
+		},
+		"DirectivesAndFragments": {
+			document: `{ search(query: "secret") { ...results @include(if: true) } } fragment results on SearchResult { ... on User @cached(key: "user") { avatar(size: 64) } }`,
+			wanted:   `{ search(query: "REDACTED") { ...results @include(if: true) } } fragment results on SearchResult { ... on User @cached(key: "REDACTED") { avatar(size: 0) } }`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, err := Parse(testCase.document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			printed := s.String()
+			wanted, err := Parse(testCase.wanted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(wanted.String(), s.Redacted()); diff != "" {
+				t.Fatal("redacted GraphQL query does not match what's wanted", diff)
+			}
+			if s.String() != printed {
+				t.Fatal("expected the original selection to be left as is")
+			}
+		})
+	}
+}
+
+func TestRedactedBuilt(t *testing.T) {
+	q := NewQuery(WithTypenames()).
+		Selection("user", WithArguments(NewArgument("email", NewStringValue("octocat@github.com")))).
+		Scalar("login").
+		Root()
+	wanted := `{
+  user(email: "REDACTED") {
+    __typename
+    login
+  }
+}`
+	if diff := cmp.Diff(wanted, q.Redacted()); diff != "" {
+		t.Fatal("redacted GraphQL query does not match what's wanted", diff)
+	}
+	if !strings.Contains(q.String(), "octocat@github.com") {
+		t.Fatal("expected the original selection to be left as is")
+	}
+}
+
+func TestRedactVariables(t *testing.T) {
+	q, err := Parse(`mutation ($email: String!, $tokens: [String!]!, $input: UserInput!, $limit: Int) {
+		login(email: $email, apiKeys: [$tokens]) { id }
+		updateUser(input: $input, first: $limit) { id }
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	variables := map[string]interface{}{
+		"email":  "octocat@github.com",
+		"tokens": []interface{}{"t1", "t2"},
+		"input": map[string]interface{}{
+			"name":     "The Octocat",
+			"Password": "hunter2",
+			"profile":  map[string]interface{}{"email": "octocat@github.com", "age": json.Number("30"), "public": true},
+		},
+		"limit": 10,
+	}
+
+	redacted := RedactVariables(q, variables, "email", "apiKeys", "password")
+	wanted := map[string]interface{}{
+		"email":  "REDACTED",
+		"tokens": []interface{}{"REDACTED", "REDACTED"},
+		"input": map[string]interface{}{
+			"name":     "The Octocat",
+			"Password": "REDACTED",
+			"profile":  map[string]interface{}{"email": "REDACTED", "age": json.Number("30"), "public": true},
+		},
+		"limit": 10,
+	}
+	if diff := cmp.Diff(wanted, redacted); diff != "" {
+		t.Errorf("unexpected variables (-want +got):\n%s", diff)
+	}
+	if variables["email"] != "octocat@github.com" {
+		t.Error("expected the variables to be left as is")
+	}
+}
@@ -0,0 +1,205 @@
+package fluentgraphql
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// retrier retries the requests failing for reasons that may go away
+type retrier struct {
+	attempts   int
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	maxWait    time.Duration
+	codes      map[string]bool
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+type retryOption func(*retrier)
+
+// Retry is a middleware sending requests again when they fail for reasons that may go
+// away: transport errors, 429 and 5xx statuses, 403 statuses asking to retry later, and
+// GraphQL errors whose code or type is RATE_LIMITED, or one given with WithRetryCodes.
+// Statuses are retried whether or not their body is a GraphQL response.
+//
+// It waits for as long as the server asks, with a Retry-After header, or until its rate
+// limit resets when none remains, according to X-RateLimit-Reset. Otherwise, it backs off
+// exponentially, with jitter. Requests are sent at most 3 times, waiting from 500ms up to
+// 30 seconds, and aren't retried when the server asks to wait for more than a minute.
+//
+// Mutations are only retried when their request is marked Idempotent, and requests with
+// uploads are never retried, as their files are consumed once sent.
+func Retry(options ...retryOption) Middleware {
+	r := &retrier{
+		attempts:   3,
+		minBackoff: 500 * time.Millisecond,
+		maxBackoff: 30 * time.Second,
+		maxWait:    time.Minute,
+		codes:      map[string]bool{"RATE_LIMITED": true},
+		sleep:      sleep,
+	}
+	for _, option := range options {
+		option(r)
+	}
+
+	return func(next Executor) Executor {
+		return ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+			retryable := (!isMutation(req.Selection) || req.Idempotent) && len(findUploads(req.Variables).uploads) == 0
+			for attempt := 1; ; attempt++ {
+				res, err := next.Execute(ctx, req)
+				if !retryable || attempt >= r.attempts || ctx.Err() != nil {
+					return res, err
+				}
+				wait, retry := r.wait(attempt, res, err)
+				if !retry {
+					return res, err
+				}
+				if err := r.sleep(ctx, wait); err != nil {
+					return res, err
+				}
+			}
+		})
+	}
+}
+
+// WithRetryAttempts specifies how many times a request is sent at most
+func WithRetryAttempts(attempts int) retryOption {
+	return func(r *retrier) {
+		r.attempts = attempts
+	}
+}
+
+// WithRetryBackoff specifies how long to wait before the first retry, doubled for each
+// of the next ones up to maxBackoff, when the server doesn't tell
+func WithRetryBackoff(minBackoff, maxBackoff time.Duration) retryOption {
+	return func(r *retrier) {
+		r.minBackoff = minBackoff
+		r.maxBackoff = maxBackoff
+	}
+}
+
+// WithMaxRetryWait specifies the longest the server may ask to wait before a retry,
+// beyond which the request fails rather than wait
+func WithMaxRetryWait(maxWait time.Duration) retryOption {
+	return func(r *retrier) {
+		r.maxWait = maxWait
+	}
+}
+
+// WithRetryCodes adds GraphQL error codes, found in the code extension or the type of
+// errors, worth retrying the request for
+func WithRetryCodes(codes ...string) retryOption {
+	return func(r *retrier) {
+		for _, code := range codes {
+			r.codes[code] = true
+		}
+	}
+}
+
+// wait tells whether a failed attempt is worth retrying, and how long to wait before
+func (r *retrier) wait(attempt int, res *Response, err error) (time.Duration, bool) {
+	var header http.Header
+	switch {
+	case err != nil:
+		var httpErr *HTTPError
+		if !errors.As(err, &httpErr) {
+			// the server may not even have received the request
+			break
+		}
+		header = httpErr.Header
+		if !retryableStatus(httpErr.StatusCode, header) {
+			return 0, false
+		}
+	case res == nil:
+		return 0, false
+	// servers may fail with a GraphQL response as the body
+	case retryableStatus(res.StatusCode, res.Header) || r.retryableErrors(res.Errors):
+		header = res.Header
+	default:
+		return 0, false
+	}
+
+	if wait, ok := serverWait(header, time.Now()); ok {
+		return wait, wait <= r.maxWait
+	}
+	backoff := r.minBackoff << (attempt - 1)
+	if backoff > r.maxBackoff || backoff <= 0 {
+		backoff = r.maxBackoff
+	}
+	// equal jitter keeps retries at least half the backoff apart
+	return backoff/2 + time.Duration(rand.Int63n(int64(backoff/2)+1)), true
+}
+
+// retryableStatus tells whether an HTTP status is worth retrying the request for: 429 and
+// 5xx statuses, and 403 statuses asking to retry later
+func retryableStatus(code int, header http.Header) bool {
+	switch {
+	case code == http.StatusTooManyRequests || code >= http.StatusInternalServerError:
+		return true
+	case code == http.StatusForbidden && (header.Get("Retry-After") != "" || header.Get("X-RateLimit-Remaining") == "0"):
+		return true
+	}
+	return false
+}
+
+func (r *retrier) retryableErrors(errs ResponseErrors) bool {
+	for _, e := range errs {
+		if code, ok := e.Extensions["code"].(string); ok && r.codes[code] {
+			return true
+		}
+		if r.codes[e.Type] {
+			return true
+		}
+	}
+	return false
+}
+
+// serverWait returns how long the server asks to wait before a retry, with a Retry-After
+// header, in seconds or as a date, or by running out of its rate limit until a reset time
+func serverWait(header http.Header, now time.Time) (time.Duration, bool) {
+	if retryAfter := header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+		if date, err := http.ParseTime(retryAfter); err == nil {
+			return nonNegative(date.Sub(now)), true
+		}
+	}
+	if header.Get("X-RateLimit-Remaining") == "0" {
+		if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+			return nonNegative(time.Unix(reset, 0).Sub(now)), true
+		}
+	}
+	return 0, false
+}
+
+func nonNegative(d time.Duration) time.Duration {
+	if d < 0 {
+		return 0
+	}
+	return d
+}
+
+// sleep waits for d, unless ctx is done first
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// isMutation tells whether the selection is rooted in a mutation
+func isMutation(s *Selection) bool {
+	n, ok := s.Root().node.(*ast.OperationDefinition)
+	return ok && n.Operation == ast.OperationTypeMutation
+}
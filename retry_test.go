@@ -0,0 +1,227 @@
+package fluentgraphql
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRetry(t *testing.T) {
+	query := NewRequest(NewQuery().Scalar("viewer"), nil)
+	mutation := NewRequest(NewMutation().Scalar("addStar"), nil)
+	idempotent := NewRequest(NewMutation().Scalar("setTopics"), nil)
+	idempotent.Idempotent = true
+
+	rateLimited := &Response{
+		Errors: ResponseErrors{{Type: "RATE_LIMITED", Message: "API rate limit exceeded"}},
+		Header: http.Header{"X-Ratelimit-Remaining": {"0"}, "X-Ratelimit-Reset": {strconv.FormatInt(time.Now().Add(10*time.Second).Unix(), 10)}},
+	}
+	ok := &Response{Data: []byte(`{"ok":true}`)}
+
+	type result struct {
+		res *Response
+		err error
+	}
+
+	type test struct {
+		name    string
+		req     *Request
+		options []retryOption
+		results []result
+		// attempts is how many times the request is expected to be sent
+		attempts int
+		// minWait and maxWait bound every wait between attempts
+		minWait, maxWait time.Duration
+		err              string
+	}
+
+	var tests = []test{
+		{
+			name:     "ServerError",
+			req:      query,
+			results:  []result{{err: &HTTPError{StatusCode: http.StatusBadGateway}}, {res: ok}},
+			attempts: 2,
+			minWait:  250 * time.Millisecond,
+			maxWait:  500 * time.Millisecond,
+		},
+		{
+			name:     "ServerErrorResponse",
+			req:      query,
+			results:  []result{{res: &Response{StatusCode: http.StatusServiceUnavailable, Errors: ResponseErrors{{Message: "service unavailable"}}}}, {res: ok}},
+			attempts: 2,
+			minWait:  250 * time.Millisecond,
+			maxWait:  500 * time.Millisecond,
+		},
+		{
+			name:     "TransportError",
+			req:      query,
+			options:  []retryOption{WithRetryBackoff(time.Second, 3*time.Second)},
+			results:  []result{{err: errors.New("connection reset")}, {err: errors.New("connection reset")}, {res: ok}},
+			attempts: 3,
+			minWait:  500 * time.Millisecond,
+			maxWait:  2 * time.Second,
+		},
+		{
+			name:     "RateLimited",
+			req:      query,
+			results:  []result{{res: rateLimited}, {res: ok}},
+			attempts: 2,
+			minWait:  8 * time.Second,
+			maxWait:  10 * time.Second,
+		},
+		{
+			name:     "SecondaryRateLimit",
+			req:      query,
+			results:  []result{{err: &HTTPError{StatusCode: http.StatusForbidden, Header: http.Header{"Retry-After": {"30"}}}}, {res: ok}},
+			attempts: 2,
+			minWait:  30 * time.Second,
+			maxWait:  30 * time.Second,
+		},
+		{
+			name:     "WaitTooLong",
+			req:      query,
+			options:  []retryOption{WithMaxRetryWait(5 * time.Second)},
+			results:  []result{{res: rateLimited}},
+			attempts: 1,
+			err:      "API rate limit exceeded",
+		},
+		{
+			name:     "Forbidden",
+			req:      query,
+			results:  []result{{err: &HTTPError{StatusCode: http.StatusForbidden}}},
+			attempts: 1,
+			err:      "fluentgraphql: unexpected HTTP status 403",
+		},
+		{
+			name:     "Exhausted",
+			req:      query,
+			options:  []retryOption{WithRetryAttempts(2)},
+			results:  []result{{err: &HTTPError{StatusCode: http.StatusServiceUnavailable}}, {err: &HTTPError{StatusCode: http.StatusInternalServerError}}},
+			attempts: 2,
+			maxWait:  500 * time.Millisecond,
+			err:      "fluentgraphql: unexpected HTTP status 500",
+		},
+		{
+			name:     "ErrorCode",
+			req:      query,
+			options:  []retryOption{WithRetryCodes("SERVICE_UNAVAILABLE")},
+			results:  []result{{res: &Response{Errors: ResponseErrors{{Message: "try again", Extensions: map[string]interface{}{"code": "SERVICE_UNAVAILABLE"}}}}}, {res: ok}},
+			attempts: 2,
+			maxWait:  500 * time.Millisecond,
+		},
+		{
+			name:     "OtherErrors",
+			req:      query,
+			results:  []result{{res: &Response{Errors: ResponseErrors{{Message: "not found", Type: "NOT_FOUND"}}}}},
+			attempts: 1,
+			err:      "not found",
+		},
+		{
+			name:     "Mutation",
+			req:      mutation,
+			results:  []result{{err: &HTTPError{StatusCode: http.StatusBadGateway}}},
+			attempts: 1,
+			err:      "fluentgraphql: unexpected HTTP status 502",
+		},
+		{
+			name:     "IdempotentMutation",
+			req:      idempotent,
+			results:  []result{{err: &HTTPError{StatusCode: http.StatusBadGateway}}, {res: ok}},
+			attempts: 2,
+			maxWait:  500 * time.Millisecond,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var attempts int
+			executor := ExecutorFunc(func(ctx context.Context, req *Request) (*Response, error) {
+				r := test.results[attempts]
+				attempts++
+				return r.res, r.err
+			})
+			var waits []time.Duration
+			options := append(test.options, func(r *retrier) {
+				r.sleep = func(ctx context.Context, d time.Duration) error {
+					waits = append(waits, d)
+					return nil
+				}
+			})
+
+			res, err := Chain(executor, Retry(options...)).Execute(context.Background(), test.req)
+			if attempts != test.attempts {
+				t.Errorf("expected %d attempts, got %d", test.attempts, attempts)
+			}
+			for _, wait := range waits {
+				if wait < test.minWait || wait > test.maxWait {
+					t.Errorf("expected waits between %s and %s, got %s", test.minWait, test.maxWait, wait)
+				}
+			}
+
+			switch {
+			case err != nil:
+				if err.Error() != test.err {
+					t.Errorf("expected error %q, got %q", test.err, err)
+				}
+			case len(res.Errors) > 0:
+				if res.Errors.Error() != test.err {
+					t.Errorf("expected errors %q, got %q", test.err, res.Errors)
+				}
+			case test.err != "":
+				t.Errorf("expected error %q, got data %s", test.err, res.Data)
+			}
+		})
+	}
+}
+
+func TestRetryHTTPExecutor(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		io.WriteString(w, `{"data":{"ok":true}}`)
+	}))
+	defer server.Close()
+
+	executor := Chain(NewHTTPExecutor(server.URL), Retry())
+	res, err := executor.Execute(context.Background(), NewRequest(NewQuery().Scalar("ok"), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res.Data) != `{"ok":true}` || attempts != 2 {
+		t.Errorf("unexpected data %s after %d attempts", res.Data, attempts)
+	}
+}
+
+func TestRetryHTTPExecutorErrorBody(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, `{"errors":[{"message":"service unavailable"}]}`)
+			return
+		}
+		io.WriteString(w, `{"data":{"ok":true}}`)
+	}))
+	defer server.Close()
+
+	executor := Chain(NewHTTPExecutor(server.URL), Retry())
+	res, err := executor.Execute(context.Background(), NewRequest(NewQuery().Scalar("ok"), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(res.Data) != `{"ok":true}` || attempts != 2 {
+		t.Errorf("unexpected data %s after %d attempts", res.Data, attempts)
+	}
+}
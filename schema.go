@@ -0,0 +1,579 @@
+package fluentgraphql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/printer"
+)
+
+// Schema builds the type system definitions of a GraphQL schema
+type Schema struct {
+	doc              *ast.Document
+	schemaDefinition *ast.SchemaDefinition
+	// err is the first error met while adding definitions, such as an invalid type reference
+	err error
+}
+
+// Definition is a type system definition of a schema, such as an object type or an enum
+type Definition struct {
+	schema *Schema
+	node   ast.Node
+}
+
+// DefinitionOption is an option for type system definitions and their fields, arguments and values
+type DefinitionOption func(*Definition)
+
+// NewSchema returns a new, empty schema
+func NewSchema() *Schema {
+	return &Schema{
+		doc: ast.NewDocument(&ast.Document{Definitions: make([]ast.Node, 0)}),
+	}
+}
+
+// Operation sets the root type of an operation, one of query, mutation or subscription.
+// The root types default to Query, Mutation and Subscription.
+func (s *Schema) Operation(operation, typeName string) *Schema {
+	if s.schemaDefinition == nil {
+		s.schemaDefinition = ast.NewSchemaDefinition(&ast.SchemaDefinition{
+			OperationTypes: make([]*ast.OperationTypeDefinition, 0),
+			Directives:     make([]*ast.Directive, 0),
+		})
+		s.doc.Definitions = append([]ast.Node{s.schemaDefinition}, s.doc.Definitions...)
+	}
+	s.schemaDefinition.OperationTypes = append(s.schemaDefinition.OperationTypes, ast.NewOperationTypeDefinition(&ast.OperationTypeDefinition{
+		Operation: operation,
+		Type:      namedType(typeName),
+	}))
+	return s
+}
+
+// Type adds an object type to the schema
+func (s *Schema) Type(name string, options ...DefinitionOption) *Definition {
+	return s.define(ast.NewObjectDefinition(&ast.ObjectDefinition{
+		Name:       ast.NewName(&ast.Name{Value: name}),
+		Interfaces: make([]*ast.Named, 0),
+		Fields:     make([]*ast.FieldDefinition, 0),
+		Directives: make([]*ast.Directive, 0),
+	}), options)
+}
+
+// ExtendType adds an extension of an object type to the schema.
+// extend type name { ... }
+func (s *Schema) ExtendType(name string, options ...DefinitionOption) *Definition {
+	return s.define(ast.NewTypeExtensionDefinition(&ast.TypeExtensionDefinition{
+		Definition: ast.NewObjectDefinition(&ast.ObjectDefinition{
+			Name:       ast.NewName(&ast.Name{Value: name}),
+			Interfaces: make([]*ast.Named, 0),
+			Fields:     make([]*ast.FieldDefinition, 0),
+			Directives: make([]*ast.Directive, 0),
+		}),
+	}), options)
+}
+
+// Interface adds an interface type to the schema
+func (s *Schema) Interface(name string, options ...DefinitionOption) *Definition {
+	return s.define(ast.NewInterfaceDefinition(&ast.InterfaceDefinition{
+		Name:       ast.NewName(&ast.Name{Value: name}),
+		Fields:     make([]*ast.FieldDefinition, 0),
+		Directives: make([]*ast.Directive, 0),
+	}), options)
+}
+
+// Union adds a union of object types to the schema
+func (s *Schema) Union(name string, types []string, options ...DefinitionOption) *Definition {
+	members := make([]*ast.Named, 0, len(types))
+	for _, t := range types {
+		members = append(members, namedType(t))
+	}
+	return s.define(ast.NewUnionDefinition(&ast.UnionDefinition{
+		Name:       ast.NewName(&ast.Name{Value: name}),
+		Types:      members,
+		Directives: make([]*ast.Directive, 0),
+	}), options)
+}
+
+// Enum adds an enum type to the schema, whose values are added with Value
+func (s *Schema) Enum(name string, options ...DefinitionOption) *Definition {
+	return s.define(ast.NewEnumDefinition(&ast.EnumDefinition{
+		Name:       ast.NewName(&ast.Name{Value: name}),
+		Values:     make([]*ast.EnumValueDefinition, 0),
+		Directives: make([]*ast.Directive, 0),
+	}), options)
+}
+
+// InputObject adds an input object type to the schema
+func (s *Schema) InputObject(name string, options ...DefinitionOption) *Definition {
+	return s.define(ast.NewInputObjectDefinition(&ast.InputObjectDefinition{
+		Name:       ast.NewName(&ast.Name{Value: name}),
+		Fields:     make([]*ast.InputValueDefinition, 0),
+		Directives: make([]*ast.Directive, 0),
+	}), options)
+}
+
+// Scalar adds a custom scalar type to the schema
+func (s *Schema) Scalar(name string, options ...DefinitionOption) *Definition {
+	return s.define(ast.NewScalarDefinition(&ast.ScalarDefinition{
+		Name:       ast.NewName(&ast.Name{Value: name}),
+		Directives: make([]*ast.Directive, 0),
+	}), options)
+}
+
+// DirectiveDefinition adds a directive to the schema, usable at the given locations, such as FIELD_DEFINITION
+func (s *Schema) DirectiveDefinition(name string, locations []string, options ...DefinitionOption) *Definition {
+	names := make([]*ast.Name, 0, len(locations))
+	for _, l := range locations {
+		names = append(names, ast.NewName(&ast.Name{Value: l}))
+	}
+	return s.define(ast.NewDirectiveDefinition(&ast.DirectiveDefinition{
+		Name:      ast.NewName(&ast.Name{Value: name}),
+		Arguments: make([]*ast.InputValueDefinition, 0),
+		Locations: names,
+	}), options)
+}
+
+// Document returns the type system definitions of the schema
+func (s *Schema) Document() *ast.Document {
+	return s.doc
+}
+
+// Build builds an executable schema from the definitions, see BuildSchema.
+// It fails with the first error met while adding definitions, such as an invalid type reference.
+func (s *Schema) Build() (graphql.Schema, error) {
+	if s.err != nil {
+		return graphql.Schema{}, s.err
+	}
+	return BuildSchema(s.doc)
+}
+
+// String returns the schema in the GraphQL schema definition language.
+// Fields and arguments with an invalid type reference are left out, see Build.
+func (s *Schema) String() string {
+	return printSDL(s.doc.Definitions, nil)
+}
+
+func (s *Schema) define(node ast.Node, options []DefinitionOption) *Definition {
+	s.doc.Definitions = append(s.doc.Definitions, node)
+	d := &Definition{schema: s, node: node}
+	for _, option := range options {
+		option(d)
+	}
+	return d
+}
+
+func (s *Schema) fail(err error) {
+	if s != nil && s.err == nil {
+		s.err = err
+	}
+}
+
+// Field adds a field to an object, interface or input object type, or to a type extension.
+// fieldType is a type reference such as String!, [Issue!] or Repository; an invalid one
+// leaves the field out and makes Build fail.
+func (d *Definition) Field(name, fieldType string, options ...DefinitionOption) *Definition {
+	t, err := typeReference(fieldType)
+	if err != nil {
+		d.schema.fail(fmt.Errorf("%v of field %s", err, name))
+		return d
+	}
+	var field *Definition
+	switch n := d.node.(type) {
+	case *ast.ObjectDefinition:
+		f := newFieldDefinition(name, t)
+		n.Fields = append(n.Fields, f)
+		field = &Definition{schema: d.schema, node: f}
+	case *ast.TypeExtensionDefinition:
+		f := newFieldDefinition(name, t)
+		n.Definition.Fields = append(n.Definition.Fields, f)
+		field = &Definition{schema: d.schema, node: f}
+	case *ast.InterfaceDefinition:
+		f := newFieldDefinition(name, t)
+		n.Fields = append(n.Fields, f)
+		field = &Definition{schema: d.schema, node: f}
+	case *ast.InputObjectDefinition:
+		f := newInputValueDefinition(name, t)
+		n.Fields = append(n.Fields, f)
+		field = &Definition{schema: d.schema, node: f}
+	default:
+		return d
+	}
+
+	for _, option := range options {
+		option(field)
+	}
+	return d
+}
+
+// Value adds a value to an enum type
+func (d *Definition) Value(name string, options ...DefinitionOption) *Definition {
+	n, ok := d.node.(*ast.EnumDefinition)
+	if !ok {
+		return d
+	}
+	value := ast.NewEnumValueDefinition(&ast.EnumValueDefinition{
+		Name:       ast.NewName(&ast.Name{Value: name}),
+		Directives: make([]*ast.Directive, 0),
+	})
+	n.Values = append(n.Values, value)
+
+	for _, option := range options {
+		option(&Definition{schema: d.schema, node: value})
+	}
+	return d
+}
+
+// Schema returns the schema the definition belongs to
+func (d *Definition) Schema() *Schema {
+	return d.schema
+}
+
+// argumentDefinition represents an argument of a field or directive definition
+type argumentDefinition struct {
+	astDef *ast.InputValueDefinition
+	err    error
+}
+
+// NewArgumentDefinition constructs a new argument definition of the given type, such as Int or [String!]!.
+// An invalid type leaves the argument out of the definitions it is given to, and makes Build fail.
+func NewArgumentDefinition(name, argType string, options ...DefinitionOption) *argumentDefinition {
+	t, err := typeReference(argType)
+	if err != nil {
+		return &argumentDefinition{err: fmt.Errorf("%v of argument %s", err, name)}
+	}
+	def := newInputValueDefinition(name, t)
+	for _, option := range options {
+		option(&Definition{node: def})
+	}
+	return &argumentDefinition{astDef: def}
+}
+
+// WithArgumentDefinitions is a definition option for specifying the arguments of a field or directive
+func WithArgumentDefinitions(args ...*argumentDefinition) DefinitionOption {
+	return func(d *Definition) {
+		for _, arg := range args {
+			if arg.err != nil {
+				d.schema.fail(arg.err)
+				continue
+			}
+			switch n := d.node.(type) {
+			case *ast.FieldDefinition:
+				n.Arguments = append(n.Arguments, arg.astDef)
+			case *ast.DirectiveDefinition:
+				n.Arguments = append(n.Arguments, arg.astDef)
+			}
+		}
+	}
+}
+
+// WithDefaultValue is a definition option for specifying the default value of an argument or input field
+func WithDefaultValue(value *Value) DefinitionOption {
+	return func(d *Definition) {
+		if n, ok := d.node.(*ast.InputValueDefinition); ok {
+			n.DefaultValue = value.astValue
+		}
+	}
+}
+
+// WithDescription is a definition option for specifying a description
+func WithDescription(description string) DefinitionOption {
+	return func(d *Definition) {
+		desc := ast.NewStringValue(&ast.StringValue{Value: description})
+		switch n := d.node.(type) {
+		case *ast.ObjectDefinition:
+			n.Description = desc
+		case *ast.InterfaceDefinition:
+			n.Description = desc
+		case *ast.UnionDefinition:
+			n.Description = desc
+		case *ast.EnumDefinition:
+			n.Description = desc
+		case *ast.InputObjectDefinition:
+			n.Description = desc
+		case *ast.ScalarDefinition:
+			n.Description = desc
+		case *ast.DirectiveDefinition:
+			n.Description = desc
+		case *ast.FieldDefinition:
+			n.Description = desc
+		case *ast.InputValueDefinition:
+			n.Description = desc
+		case *ast.EnumValueDefinition:
+			n.Description = desc
+		}
+	}
+}
+
+// WithInterfaces is a definition option for specifying the interfaces an object type implements
+func WithInterfaces(interfaces ...string) DefinitionOption {
+	return func(d *Definition) {
+		var n *ast.ObjectDefinition
+		switch node := d.node.(type) {
+		case *ast.ObjectDefinition:
+			n = node
+		case *ast.TypeExtensionDefinition:
+			n = node.Definition
+		default:
+			return
+		}
+		for _, iface := range interfaces {
+			n.Interfaces = append(n.Interfaces, namedType(iface))
+		}
+	}
+}
+
+// WithDeprecationReason is a definition option for deprecating a field, argument, input field
+// or enum value. Deprecating other definitions makes Build fail.
+// @deprecated(reason: "...")
+func WithDeprecationReason(reason string) DefinitionOption {
+	deprecated := WithAppliedDirectives(NewDirective("deprecated", NewArgument("reason", NewStringValue(reason))))
+	return func(d *Definition) {
+		switch d.node.(type) {
+		case *ast.FieldDefinition, *ast.InputValueDefinition, *ast.EnumValueDefinition:
+			deprecated(d)
+		default:
+			d.schema.fail(fmt.Errorf("fluentgraphql: cannot deprecate %s %s", d.node.GetKind(), definitionName(d.node)))
+		}
+	}
+}
+
+// definitionName returns the name of a type or directive definition
+func definitionName(node ast.Node) string {
+	switch n := node.(type) {
+	case *ast.ObjectDefinition:
+		return n.Name.Value
+	case *ast.TypeExtensionDefinition:
+		return n.Definition.Name.Value
+	case *ast.InterfaceDefinition:
+		return n.Name.Value
+	case *ast.UnionDefinition:
+		return n.Name.Value
+	case *ast.EnumDefinition:
+		return n.Name.Value
+	case *ast.InputObjectDefinition:
+		return n.Name.Value
+	case *ast.ScalarDefinition:
+		return n.Name.Value
+	case *ast.DirectiveDefinition:
+		return n.Name.Value
+	}
+	return ""
+}
+
+// WithAppliedDirectives is a definition option for applying directives to a definition
+func WithAppliedDirectives(directives ...*directive) DefinitionOption {
+	return func(d *Definition) {
+		for _, dir := range directives {
+			switch n := d.node.(type) {
+			case *ast.ObjectDefinition:
+				n.Directives = append(n.Directives, dir.astDirective)
+			case *ast.TypeExtensionDefinition:
+				n.Definition.Directives = append(n.Definition.Directives, dir.astDirective)
+			case *ast.InterfaceDefinition:
+				n.Directives = append(n.Directives, dir.astDirective)
+			case *ast.UnionDefinition:
+				n.Directives = append(n.Directives, dir.astDirective)
+			case *ast.EnumDefinition:
+				n.Directives = append(n.Directives, dir.astDirective)
+			case *ast.InputObjectDefinition:
+				n.Directives = append(n.Directives, dir.astDirective)
+			case *ast.ScalarDefinition:
+				n.Directives = append(n.Directives, dir.astDirective)
+			case *ast.FieldDefinition:
+				n.Directives = append(n.Directives, dir.astDirective)
+			case *ast.InputValueDefinition:
+				n.Directives = append(n.Directives, dir.astDirective)
+			case *ast.EnumValueDefinition:
+				n.Directives = append(n.Directives, dir.astDirective)
+			}
+		}
+	}
+}
+
+func newFieldDefinition(name string, fieldType ast.Type) *ast.FieldDefinition {
+	return ast.NewFieldDefinition(&ast.FieldDefinition{
+		Name:       ast.NewName(&ast.Name{Value: name}),
+		Type:       fieldType,
+		Arguments:  make([]*ast.InputValueDefinition, 0),
+		Directives: make([]*ast.Directive, 0),
+	})
+}
+
+func newInputValueDefinition(name string, valueType ast.Type) *ast.InputValueDefinition {
+	return ast.NewInputValueDefinition(&ast.InputValueDefinition{
+		Name:       ast.NewName(&ast.Name{Value: name}),
+		Type:       valueType,
+		Directives: make([]*ast.Directive, 0),
+	})
+}
+
+// typeReference parses a type reference such as [String!]!
+func typeReference(ref string) (ast.Type, error) {
+	t := parseTypeReference(ref)
+	if t == nil {
+		return nil, fmt.Errorf("fluentgraphql: invalid type reference %q", ref)
+	}
+	return t, nil
+}
+
+// parseTypeReference parses a type reference, returning nil when its brackets are
+// unbalanced or it doesn't name a type with a valid name
+func parseTypeReference(ref string) ast.Type {
+	ref = strings.TrimSpace(ref)
+	switch {
+	case strings.HasSuffix(ref, "!"):
+		inner := strings.TrimSuffix(ref, "!")
+		if strings.HasSuffix(strings.TrimSpace(inner), "!") {
+			return nil
+		}
+		if t := parseTypeReference(inner); t != nil {
+			return ast.NewNonNull(&ast.NonNull{Type: t})
+		}
+		return nil
+	case strings.HasPrefix(ref, "[") && strings.HasSuffix(ref, "]"):
+		if t := parseTypeReference(ref[1 : len(ref)-1]); t != nil {
+			return ast.NewList(&ast.List{Type: t})
+		}
+		return nil
+	case !nameExpression.MatchString(ref):
+		return nil
+	}
+	return namedType(ref)
+}
+
+func namedType(name string) *ast.Named {
+	return ast.NewNamed(&ast.Named{Name: ast.NewName(&ast.Name{Value: name})})
+}
+
+// printSDL prints type system definitions, marking the directives named in repeatable as repeatable
+func printSDL(definitions []ast.Node, repeatable map[string]bool) string {
+	printed := make([]string, 0, len(definitions))
+	for _, def := range definitions {
+		p := printDefinition(def)
+		if d, ok := def.(*ast.DirectiveDefinition); ok && repeatable[d.Name.Value] {
+			i := strings.LastIndex(p, " on ")
+			p = p[:i] + " repeatable" + p[i:]
+		}
+		printed = append(printed, p)
+	}
+	return strings.Join(printed, "\n\n") + "\n"
+}
+
+// printDefinition prints a type system definition, laying out the blocks of its fields, values
+// and arguments itself, as the printer puts a blank line before described members, even the
+// first one, and prints types without fields with empty braces
+func printDefinition(def ast.Node) string {
+	switch n := def.(type) {
+	case *ast.TypeExtensionDefinition:
+		return "extend " + printDefinition(n.Definition)
+	case *ast.ObjectDefinition:
+		header := *n
+		header.Fields = nil
+		members := make([]string, 0, len(n.Fields))
+		for _, f := range n.Fields {
+			members = append(members, printField(f))
+		}
+		return printBlock(&header, members)
+	case *ast.InterfaceDefinition:
+		header := *n
+		header.Fields = nil
+		members := make([]string, 0, len(n.Fields))
+		for _, f := range n.Fields {
+			members = append(members, printField(f))
+		}
+		return printBlock(&header, members)
+	case *ast.InputObjectDefinition:
+		header := *n
+		header.Fields = nil
+		members := make([]string, 0, len(n.Fields))
+		for _, f := range n.Fields {
+			members = append(members, printer.Print(f).(string))
+		}
+		return printBlock(&header, members)
+	case *ast.EnumDefinition:
+		header := *n
+		header.Values = nil
+		members := make([]string, 0, len(n.Values))
+		for _, v := range n.Values {
+			members = append(members, printer.Print(v).(string))
+		}
+		return printBlock(&header, members)
+	case *ast.DirectiveDefinition:
+		directive := *n
+		directive.Arguments = nil
+		return printWithArguments(&directive, "directive @"+n.Name.Value, n.Arguments)
+	}
+	return printer.Print(def).(string)
+}
+
+// printBlock prints a definition whose members were left out, followed by the block of its
+// members, or by nothing when it has none
+func printBlock(header ast.Node, members []string) string {
+	p := strings.TrimSuffix(printer.Print(header).(string), " {}")
+	if len(members) == 0 {
+		return p
+	}
+	return p + " {" + printMembers(members) + "\n}"
+}
+
+// printField prints a field definition, laying out its arguments with printArguments
+func printField(f *ast.FieldDefinition) string {
+	field := *f
+	field.Arguments = nil
+	return printWithArguments(&field, f.Name.Value, f.Arguments)
+}
+
+// printWithArguments prints a definition whose arguments were left out, inserting them
+// after name, the beginning of the definition once its description is printed
+func printWithArguments(def ast.Node, name string, args []*ast.InputValueDefinition) string {
+	described := printer.Print(def).(string)
+	var p string
+	switch n := def.(type) {
+	case *ast.FieldDefinition:
+		n.Description = nil
+		p = printer.Print(n).(string)
+	case *ast.DirectiveDefinition:
+		n.Description = nil
+		p = printer.Print(n).(string)
+	}
+	return strings.TrimSuffix(described, p) + name + printArguments(args) + strings.TrimPrefix(p, name)
+}
+
+// printArguments prints a list of arguments, on a single line unless some are described,
+// in which case they are laid out one per line like the members of a block
+func printArguments(args []*ast.InputValueDefinition) string {
+	if len(args) == 0 {
+		return ""
+	}
+	described := false
+	printed := make([]string, 0, len(args))
+	for _, arg := range args {
+		if arg.Description != nil && arg.Description.Value != "" {
+			described = true
+		}
+		printed = append(printed, printer.Print(arg).(string))
+	}
+	if !described {
+		return "(" + strings.Join(printed, ", ") + ")"
+	}
+	return "(" + printMembers(printed) + "\n)"
+}
+
+// printMembers indents printed members on lines of their own, separating described members
+// but the first one with the blank line the printer puts before them
+func printMembers(members []string) string {
+	var b strings.Builder
+	for i, m := range members {
+		if i == 0 {
+			m = strings.TrimPrefix(m, "\n")
+		}
+		for _, line := range strings.Split(m, "\n") {
+			b.WriteString("\n")
+			if line != "" {
+				b.WriteString("  " + line)
+			}
+		}
+	}
+	return b.String()
+}
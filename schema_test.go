@@ -0,0 +1,320 @@
+package fluentgraphql
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/graphql-go/graphql"
+	"github.com/vektah/gqlparser/v2/ast"
+	"github.com/vektah/gqlparser/v2/parser"
+)
+
+// parseSDL parses a schema following the current specification, which unlike graphql-go's
+// parser allows types without fields
+func parseSDL(sdl string) error {
+	_, err := parser.ParseSchema(&ast.Source{Input: sdl})
+	// a nil *gqlerror.Error is not a nil error
+	if err != nil {
+		return err
+	}
+	return nil
+}
+
+func TestSchema(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		wanted string
+		schema *Schema
+	}{
+		"ObjectTypes": {
+			wanted: `type Repository {
+  name: String!
+  owner: String @deprecated(reason: "Use nameWithOwner")
+  issues(first: Int = 10, states: [IssueState!] = [OPEN]): [Issue!]!
+}
+
+type Issue {
+  """The title of the issue"""
+  title: String!
+}
+`,
+			schema: func() *Schema {
+				s := NewSchema()
+				s.Type("Repository").
+					Field("name", "String!").
+					Field("owner", "String", WithDeprecationReason("Use nameWithOwner")).
+					Field("issues", "[Issue!]!", WithArgumentDefinitions(
+						NewArgumentDefinition("first", "Int", WithDefaultValue(NewIntValue(10))),
+						NewArgumentDefinition("states", "[IssueState!]", WithDefaultValue(NewListValue(NewEnumValue("OPEN")))),
+					))
+				s.Type("Issue").Field("title", "String!", WithDescription("The title of the issue"))
+				return s
+			}(),
+		},
+		"AbstractTypes": {
+			wanted: `interface Node {
+  id: ID!
+}
+
+type User implements Node {
+  id: ID!
+}
+
+union Actor = User | Bot
+`,
+			schema: func() *Schema {
+				s := NewSchema()
+				s.Interface("Node").Field("id", "ID!")
+				s.Type("User", WithInterfaces("Node")).Field("id", "ID!")
+				s.Union("Actor", []string{"User", "Bot"})
+				return s
+			}(),
+		},
+		"EnumsInputsAndScalars": {
+			wanted: `"""An ISO-8601 encoded UTC date string"""
+scalar DateTime
+
+enum IssueState {
+  OPEN
+  CLOSED @deprecated(reason: "Use RESOLVED")
+  RESOLVED
+}
+
+input IssueFilter {
+  states: [IssueState!] = [OPEN]
+  since: DateTime
+}
+`,
+			schema: func() *Schema {
+				s := NewSchema()
+				s.Scalar("DateTime", WithDescription("An ISO-8601 encoded UTC date string"))
+				s.Enum("IssueState").
+					Value("OPEN").
+					Value("CLOSED", WithDeprecationReason("Use RESOLVED")).
+					Value("RESOLVED")
+				s.InputObject("IssueFilter").
+					Field("states", "[IssueState!]", WithDefaultValue(NewListValue(NewEnumValue("OPEN")))).
+					Field("since", "DateTime")
+				return s
+			}(),
+		},
+		"DirectivesAndExtensions": {
+			wanted: `schema {
+  query: RootQuery
+}
+
+directive @cacheControl(maxAge: Int = 60) on FIELD_DEFINITION | OBJECT
+
+type RootQuery @cacheControl(maxAge: 30) {
+  viewer: String
+}
+
+extend type RootQuery {
+  node(id: ID!): String @cacheControl
+}
+`,
+			schema: func() *Schema {
+				s := NewSchema().Operation("query", "RootQuery")
+				s.DirectiveDefinition("cacheControl", []string{"FIELD_DEFINITION", "OBJECT"},
+					WithArgumentDefinitions(NewArgumentDefinition("maxAge", "Int", WithDefaultValue(NewIntValue(60)))),
+				)
+				s.Type("RootQuery", WithAppliedDirectives(NewDirective("cacheControl", NewArgument("maxAge", NewIntValue(30))))).
+					Field("viewer", "String")
+				s.ExtendType("RootQuery").
+					Field("node", "String", WithArgumentDefinitions(NewArgumentDefinition("id", "ID!")), WithAppliedDirectives(NewDirective("cacheControl")))
+				return s
+			}(),
+		},
+		"DescribedMembers": {
+			wanted: `type Query {
+  """
+  A JSON object, such as {
+
+  }
+  """
+  config: String
+
+  """The version of the server"""
+  version: String
+}
+
+enum Format {
+  """
+  Formatted as {
+
+  }
+  """
+  JSON
+  YAML
+}
+`,
+			schema: func() *Schema {
+				s := NewSchema()
+				s.Type("Query").
+					Field("config", "String", WithDescription("A JSON object, such as {\n\n}")).
+					Field("version", "String", WithDescription("The version of the server"))
+				s.Enum("Format").
+					Value("JSON", WithDescription("Formatted as {\n\n}")).
+					Value("YAML")
+				return s
+			}(),
+		},
+		"DescribedArguments": {
+			wanted: `"""Limits the cost of a field"""
+directive @cost(
+  """The cost of the field"""
+  weight: Int!
+
+  """Whether the cost is multiplied by the page size"""
+  perItem: Boolean = false
+) on FIELD_DEFINITION
+
+type Query {
+  """Lists the issues of a repository"""
+  issues(
+    """The name of the repository"""
+    repository: String!
+    first: Int = 10
+  ): [Issue!]!
+  viewer(login: String): String @deprecated(reason: "Use user")
+}
+`,
+			schema: func() *Schema {
+				s := NewSchema()
+				s.DirectiveDefinition("cost", []string{"FIELD_DEFINITION"}, WithDescription("Limits the cost of a field"), WithArgumentDefinitions(
+					NewArgumentDefinition("weight", "Int!", WithDescription("The cost of the field")),
+					NewArgumentDefinition("perItem", "Boolean", WithDescription("Whether the cost is multiplied by the page size"), WithDefaultValue(NewBooleanValue(false))),
+				))
+				s.Type("Query").
+					Field("issues", "[Issue!]!", WithDescription("Lists the issues of a repository"), WithArgumentDefinitions(
+						NewArgumentDefinition("repository", "String!", WithDescription("The name of the repository")),
+						NewArgumentDefinition("first", "Int", WithDefaultValue(NewIntValue(10))),
+					)).
+					Field("viewer", "String", WithArgumentDefinitions(NewArgumentDefinition("login", "String")), WithDeprecationReason("Use user"))
+				return s
+			}(),
+		},
+		"EmptyTypes": {
+			wanted: `"""A type for extensions to add fields to"""
+type Empty
+
+interface Node @key
+
+extend type Empty implements Node
+
+input NoFilter
+
+enum NoValues
+`,
+			schema: func() *Schema {
+				s := NewSchema()
+				s.Type("Empty", WithDescription("A type for extensions to add fields to"))
+				s.Interface("Node", WithAppliedDirectives(NewDirective("key")))
+				s.ExtendType("Empty", WithInterfaces("Node"))
+				s.InputObject("NoFilter")
+				s.Enum("NoValues")
+				return s
+			}(),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			sdl := testCase.schema.String()
+			if diff := cmp.Diff(testCase.wanted, sdl); diff != "" {
+				t.Fatal("produced SDL does not match what's wanted", diff)
+			}
+			if err := parseSDL(sdl); err != nil {
+				t.Fatalf("produced SDL does not parse: %v", err)
+			}
+		})
+	}
+}
+
+func TestSchemaBuild(t *testing.T) {
+	s := NewSchema()
+	s.Type("Query").Field("repository", "Repository", WithArgumentDefinitions(NewArgumentDefinition("name", "String!")))
+	s.Type("Repository").Field("name", "String!")
+	s.ExtendType("Repository").Field("stargazerCount", "Int!")
+
+	schema, err := s.Build()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:        schema,
+		RequestString: `{ repository(name: "fluentgraphql") { name stargazerCount } }`,
+		RootObject: map[string]interface{}{
+			"repository": map[string]interface{}{"name": "fluentgraphql", "stargazerCount": 42},
+		},
+	})
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected errors: %v", result.Errors)
+	}
+	got, _ := json.Marshal(result.Data)
+	if diff := cmp.Diff(`{"repository":{"name":"fluentgraphql","stargazerCount":42}}`, string(got)); diff != "" {
+		t.Fatal("execution result does not match what's wanted", diff)
+	}
+}
+
+func TestSchemaErrors(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		schema *Schema
+		wanted string
+	}{
+		"UnclosedList": {
+			schema: NewSchema().Type("Query").Field("tags", "[String").Schema(),
+			wanted: `fluentgraphql: invalid type reference "[String" of field tags`,
+		},
+		"UnopenedList": {
+			schema: NewSchema().Type("Query").Field("tags", "String!]").Schema(),
+			wanted: `fluentgraphql: invalid type reference "String!]" of field tags`,
+		},
+		"NestedList": {
+			schema: NewSchema().InputObject("Filter").Field("tags", "[[String]").Schema(),
+			wanted: `fluentgraphql: invalid type reference "[[String]" of field tags`,
+		},
+		"EmptyList": {
+			schema: NewSchema().Type("Query").Field("tags", "[]").Schema(),
+			wanted: `fluentgraphql: invalid type reference "[]" of field tags`,
+		},
+		"DoubleNonNull": {
+			schema: NewSchema().Type("Query").Field("tags", "String!!").Schema(),
+			wanted: `fluentgraphql: invalid type reference "String!!" of field tags`,
+		},
+		"InvalidName": {
+			schema: NewSchema().Type("Query").Field("name", "String Int").Schema(),
+			wanted: `fluentgraphql: invalid type reference "String Int" of field name`,
+		},
+		"InvalidNameInList": {
+			schema: NewSchema().Type("Query").Field("tags", "[Tag-Name!]").Schema(),
+			wanted: `fluentgraphql: invalid type reference "[Tag-Name!]" of field tags`,
+		},
+		"DeprecatedType": {
+			schema: NewSchema().Type("Query", WithDeprecationReason("Use RootQuery")).Field("viewer", "String").Schema(),
+			wanted: `fluentgraphql: cannot deprecate ObjectDefinition Query`,
+		},
+		"DeprecatedDirective": {
+			schema: NewSchema().DirectiveDefinition("cost", []string{"FIELD_DEFINITION"}, WithDeprecationReason("Use complexity")).Schema(),
+			wanted: `fluentgraphql: cannot deprecate DirectiveDefinition cost`,
+		},
+		"Argument": {
+			schema: NewSchema().Type("Query").Field("tags", "[String!]!", WithArgumentDefinitions(
+				NewArgumentDefinition("first", "[Int"),
+			)).Schema(),
+			wanted: `fluentgraphql: invalid type reference "[Int" of argument first`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := testCase.schema.Build()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if diff := cmp.Diff(testCase.wanted, err.Error()); diff != "" {
+				t.Fatal("error does not match what's wanted", diff)
+			}
+			if err := parseSDL(testCase.schema.String()); err != nil {
+				t.Fatalf("produced SDL does not parse: %v", err)
+			}
+		})
+	}
+}
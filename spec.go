@@ -0,0 +1,790 @@
+package fluentgraphql
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/printer"
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is a declarative description of an operation, read by FromSpec as JSON or YAML
+// and exported by ToSpec. Argument values are JSON values: integers, floats, strings,
+// booleans, lists and objects. Values GraphQL distinguishes but JSON doesn't are written
+// as an object with a single key: {"$enum": "OPEN"}, {"$var": "first"} and {"$float": 1}.
+type Spec struct {
+	// Operation is query, mutation or subscription, query when empty
+	Operation  string          `json:"operation,omitempty" yaml:"operation,omitempty"`
+	Name       string          `json:"name,omitempty" yaml:"name,omitempty"`
+	Variables  []VariableSpec  `json:"variables,omitempty" yaml:"variables,omitempty"`
+	Directives []DirectiveSpec `json:"directives,omitempty" yaml:"directives,omitempty"`
+	Fields     []SelectionSpec `json:"fields" yaml:"fields"`
+	Fragments  []FragmentSpec  `json:"fragments,omitempty" yaml:"fragments,omitempty"`
+}
+
+// VariableSpec declares a variable of a Spec, with a type reference such as [String!]!
+type VariableSpec struct {
+	Name    string      `json:"name" yaml:"name"`
+	Type    string      `json:"type" yaml:"type"`
+	Default interface{} `json:"default,omitempty" yaml:"default,omitempty"`
+}
+
+// DirectiveSpec applies a directive in a Spec
+type DirectiveSpec struct {
+	Name      string                 `json:"name" yaml:"name"`
+	Arguments map[string]interface{} `json:"arguments,omitempty" yaml:"arguments,omitempty"`
+}
+
+// SelectionSpec is a field when Field is set, a fragment spread when Spread is set, and an
+// inline fragment on the type On otherwise. FromSpec also accepts a plain string as a field
+// without arguments, directives or fields of its own.
+type SelectionSpec struct {
+	Field      string                 `json:"field,omitempty" yaml:"field,omitempty"`
+	Alias      string                 `json:"alias,omitempty" yaml:"alias,omitempty"`
+	Arguments  map[string]interface{} `json:"arguments,omitempty" yaml:"arguments,omitempty"`
+	Spread     string                 `json:"spread,omitempty" yaml:"spread,omitempty"`
+	On         string                 `json:"on,omitempty" yaml:"on,omitempty"`
+	Directives []DirectiveSpec        `json:"directives,omitempty" yaml:"directives,omitempty"`
+	Fields     []SelectionSpec        `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// FragmentSpec defines a fragment of a Spec
+type FragmentSpec struct {
+	Name       string          `json:"name" yaml:"name"`
+	On         string          `json:"on" yaml:"on"`
+	Directives []DirectiveSpec `json:"directives,omitempty" yaml:"directives,omitempty"`
+	Fields     []SelectionSpec `json:"fields" yaml:"fields"`
+}
+
+// SpecError reports where a spec is invalid, as the path of the invalid entry, such as
+// fields[0].arguments.first, along with its line and column in the source
+type SpecError struct {
+	Path    string
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *SpecError) Error() string {
+	path := e.Path
+	if path == "" {
+		path = "the root"
+	}
+	return fmt.Sprintf("fluentgraphql: invalid spec at %s (line %d, column %d): %s", path, e.Line, e.Column, e.Message)
+}
+
+// FromSpec builds an operation from a Spec written as JSON or YAML. The spec is checked
+// as it is read, and a *SpecError tells where it is invalid.
+func FromSpec(r io.Reader) (*Selection, error) {
+	var root yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&root); err != nil {
+		if errors.Is(err, io.EOF) {
+			return nil, errors.New("fluentgraphql: empty spec")
+		}
+		return nil, fmt.Errorf("fluentgraphql: invalid spec: %w", err)
+	}
+
+	p := &specParser{fragments: make(map[string]bool)}
+	doc, err := p.document(root.Content[0])
+	if err != nil {
+		return nil, err
+	}
+	return FromAST(doc)
+}
+
+var nameExpression = regexp.MustCompile(`^[_A-Za-z][_0-9A-Za-z]*$`)
+
+var numberExpression = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+type specParser struct {
+	// fragments holds the names of the fragments defined by the spec
+	fragments map[string]bool
+	// spreads holds the spreads, checked once every fragment is known
+	spreads []specSpread
+}
+
+type specSpread struct {
+	name string
+	node *yaml.Node
+	path string
+}
+
+func specErrorf(node *yaml.Node, path, format string, args ...interface{}) error {
+	return &SpecError{Path: path, Line: node.Line, Column: node.Column, Message: fmt.Sprintf(format, args...)}
+}
+
+func joinSpecPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func indexSpecPath(path string, i int) string {
+	return fmt.Sprintf("%s[%d]", path, i)
+}
+
+func resolveAlias(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	return node
+}
+
+// mapping returns the values of a mapping by key, failing on keys other than the given ones
+func (p *specParser) mapping(node *yaml.Node, path string, keys ...string) (map[string]*yaml.Node, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, specErrorf(node, path, "expected an object")
+	}
+	values := make(map[string]*yaml.Node, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolveAlias(node.Content[i+1])
+		known := false
+		for _, k := range keys {
+			known = known || k == key.Value
+		}
+		if !known {
+			return nil, specErrorf(key, path, "unknown key %q, expected one of %s", key.Value, strings.Join(keys, ", "))
+		}
+		values[key.Value] = value
+	}
+	return values, nil
+}
+
+func (p *specParser) sequence(node *yaml.Node, path string) ([]*yaml.Node, error) {
+	if node.Kind != yaml.SequenceNode {
+		return nil, specErrorf(node, path, "expected a list")
+	}
+	items := make([]*yaml.Node, 0, len(node.Content))
+	for _, item := range node.Content {
+		items = append(items, resolveAlias(item))
+	}
+	return items, nil
+}
+
+func (p *specParser) string(node *yaml.Node, path string) (string, error) {
+	if node.Kind != yaml.ScalarNode || node.ShortTag() != "!!str" {
+		return "", specErrorf(node, path, "expected a string")
+	}
+	return node.Value, nil
+}
+
+func (p *specParser) name(node *yaml.Node, path string) (*ast.Name, error) {
+	name, err := p.string(node, path)
+	if err != nil {
+		return nil, err
+	}
+	if !nameExpression.MatchString(name) {
+		return nil, specErrorf(node, path, "%q is not a valid GraphQL name", name)
+	}
+	return ast.NewName(&ast.Name{Value: name}), nil
+}
+
+func (p *specParser) document(node *yaml.Node) (*ast.Document, error) {
+	m, err := p.mapping(node, "", "operation", "name", "variables", "directives", "fields", "fragments")
+	if err != nil {
+		return nil, err
+	}
+
+	op := ast.NewOperationDefinition(&ast.OperationDefinition{
+		Operation:           ast.OperationTypeQuery,
+		VariableDefinitions: make([]*ast.VariableDefinition, 0),
+		Directives:          make([]*ast.Directive, 0),
+	})
+	if n, ok := m["operation"]; ok {
+		operation, err := p.string(n, "operation")
+		if err != nil {
+			return nil, err
+		}
+		switch operation {
+		case ast.OperationTypeQuery, ast.OperationTypeMutation, ast.OperationTypeSubscription:
+			op.Operation = operation
+		default:
+			return nil, specErrorf(n, "operation", "expected query, mutation or subscription, got %q", operation)
+		}
+	}
+	if n, ok := m["name"]; ok {
+		if op.Name, err = p.name(n, "name"); err != nil {
+			return nil, err
+		}
+	}
+	if n, ok := m["variables"]; ok {
+		if op.VariableDefinitions, err = p.variables(n, "variables"); err != nil {
+			return nil, err
+		}
+	}
+	if n, ok := m["directives"]; ok {
+		if op.Directives, err = p.directives(n, "directives"); err != nil {
+			return nil, err
+		}
+	}
+	n, ok := m["fields"]
+	if !ok {
+		return nil, specErrorf(node, "", "missing fields")
+	}
+	if op.SelectionSet, err = p.selectionSet(n, "fields"); err != nil {
+		return nil, err
+	}
+
+	doc := ast.NewDocument(&ast.Document{Definitions: []ast.Node{op}})
+	if n, ok := m["fragments"]; ok {
+		items, err := p.sequence(n, "fragments")
+		if err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			f, err := p.fragment(item, indexSpecPath("fragments", i))
+			if err != nil {
+				return nil, err
+			}
+			doc.Definitions = append(doc.Definitions, f)
+		}
+	}
+
+	for _, spread := range p.spreads {
+		if !p.fragments[spread.name] {
+			return nil, specErrorf(spread.node, spread.path, "unknown fragment %s", spread.name)
+		}
+	}
+	return doc, nil
+}
+
+func (p *specParser) variables(node *yaml.Node, path string) ([]*ast.VariableDefinition, error) {
+	items, err := p.sequence(node, path)
+	if err != nil {
+		return nil, err
+	}
+	variables := make([]*ast.VariableDefinition, 0, len(items))
+	for i, item := range items {
+		itemPath := indexSpecPath(path, i)
+		m, err := p.mapping(item, itemPath, "name", "type", "default")
+		if err != nil {
+			return nil, err
+		}
+		n, ok := m["name"]
+		if !ok {
+			return nil, specErrorf(item, itemPath, "missing name")
+		}
+		name, err := p.name(n, joinSpecPath(itemPath, "name"))
+		if err != nil {
+			return nil, err
+		}
+		n, ok = m["type"]
+		if !ok {
+			return nil, specErrorf(item, itemPath, "missing type")
+		}
+		t, err := p.typeReference(n, joinSpecPath(itemPath, "type"))
+		if err != nil {
+			return nil, err
+		}
+		v := ast.NewVariableDefinition(&ast.VariableDefinition{
+			Variable: ast.NewVariable(&ast.Variable{Name: name}),
+			Type:     t,
+		})
+		if n, ok := m["default"]; ok {
+			if v.DefaultValue, err = p.value(n, joinSpecPath(itemPath, "default")); err != nil {
+				return nil, err
+			}
+		}
+		variables = append(variables, v)
+	}
+	return variables, nil
+}
+
+// typeReference reads a type reference such as [String!]!
+func (p *specParser) typeReference(node *yaml.Node, path string) (ast.Type, error) {
+	ref, err := p.string(node, path)
+	if err != nil {
+		return nil, err
+	}
+	t, err := typeReference(ref)
+	if err != nil {
+		return nil, specErrorf(node, path, "%q is not a valid type reference", ref)
+	}
+	return t, nil
+}
+
+func (p *specParser) fragment(node *yaml.Node, path string) (*ast.FragmentDefinition, error) {
+	m, err := p.mapping(node, path, "name", "on", "directives", "fields")
+	if err != nil {
+		return nil, err
+	}
+	n, ok := m["name"]
+	if !ok {
+		return nil, specErrorf(node, path, "missing name")
+	}
+	name, err := p.name(n, joinSpecPath(path, "name"))
+	if err != nil {
+		return nil, err
+	}
+	if p.fragments[name.Value] {
+		return nil, specErrorf(n, joinSpecPath(path, "name"), "fragment %s is defined more than once", name.Value)
+	}
+	p.fragments[name.Value] = true
+
+	n, ok = m["on"]
+	if !ok {
+		return nil, specErrorf(node, path, "missing on")
+	}
+	on, err := p.name(n, joinSpecPath(path, "on"))
+	if err != nil {
+		return nil, err
+	}
+	f := ast.NewFragmentDefinition(&ast.FragmentDefinition{
+		Name:          name,
+		TypeCondition: ast.NewNamed(&ast.Named{Name: on}),
+		Directives:    make([]*ast.Directive, 0),
+	})
+	if n, ok := m["directives"]; ok {
+		if f.Directives, err = p.directives(n, joinSpecPath(path, "directives")); err != nil {
+			return nil, err
+		}
+	}
+	n, ok = m["fields"]
+	if !ok {
+		return nil, specErrorf(node, path, "missing fields")
+	}
+	if f.SelectionSet, err = p.selectionSet(n, joinSpecPath(path, "fields")); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (p *specParser) selectionSet(node *yaml.Node, path string) (*ast.SelectionSet, error) {
+	items, err := p.sequence(node, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, specErrorf(node, path, "expected at least one field")
+	}
+	selections := make([]ast.Selection, 0, len(items))
+	for i, item := range items {
+		selection, err := p.selection(item, indexSpecPath(path, i))
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	return ast.NewSelectionSet(&ast.SelectionSet{Selections: selections}), nil
+}
+
+func (p *specParser) selection(node *yaml.Node, path string) (ast.Selection, error) {
+	if node.Kind == yaml.ScalarNode {
+		name, err := p.name(node, path)
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewField(&ast.Field{
+			Name:       name,
+			Arguments:  make([]*ast.Argument, 0),
+			Directives: make([]*ast.Directive, 0),
+		}), nil
+	}
+
+	m, err := p.mapping(node, path, "field", "alias", "arguments", "spread", "on", "directives", "fields")
+	if err != nil {
+		return nil, err
+	}
+	var directives []*ast.Directive
+	if n, ok := m["directives"]; ok {
+		if directives, err = p.directives(n, joinSpecPath(path, "directives")); err != nil {
+			return nil, err
+		}
+	} else {
+		directives = make([]*ast.Directive, 0)
+	}
+
+	if n, ok := m["field"]; ok {
+		if err := p.exclusive(m, path, "field", "spread", "on"); err != nil {
+			return nil, err
+		}
+		name, err := p.name(n, joinSpecPath(path, "field"))
+		if err != nil {
+			return nil, err
+		}
+		f := ast.NewField(&ast.Field{Name: name, Arguments: make([]*ast.Argument, 0), Directives: directives})
+		if n, ok := m["alias"]; ok {
+			if f.Alias, err = p.name(n, joinSpecPath(path, "alias")); err != nil {
+				return nil, err
+			}
+		}
+		if n, ok := m["arguments"]; ok {
+			if f.Arguments, err = p.arguments(n, joinSpecPath(path, "arguments")); err != nil {
+				return nil, err
+			}
+		}
+		if n, ok := m["fields"]; ok {
+			if f.SelectionSet, err = p.selectionSet(n, joinSpecPath(path, "fields")); err != nil {
+				return nil, err
+			}
+		}
+		return f, nil
+	}
+
+	if n, ok := m["spread"]; ok {
+		if err := p.exclusive(m, path, "spread", "alias", "arguments", "on", "fields"); err != nil {
+			return nil, err
+		}
+		name, err := p.name(n, joinSpecPath(path, "spread"))
+		if err != nil {
+			return nil, err
+		}
+		p.spreads = append(p.spreads, specSpread{name: name.Value, node: n, path: joinSpecPath(path, "spread")})
+		return ast.NewFragmentSpread(&ast.FragmentSpread{Name: name, Directives: directives}), nil
+	}
+
+	if err := p.exclusive(m, path, "on", "alias", "arguments"); err != nil {
+		return nil, err
+	}
+	f := ast.NewInlineFragment(&ast.InlineFragment{Directives: directives})
+	if n, ok := m["on"]; ok {
+		on, err := p.name(n, joinSpecPath(path, "on"))
+		if err != nil {
+			return nil, err
+		}
+		f.TypeCondition = ast.NewNamed(&ast.Named{Name: on})
+	}
+	n, ok := m["fields"]
+	if !ok {
+		return nil, specErrorf(node, path, "expected field, spread or fields")
+	}
+	if f.SelectionSet, err = p.selectionSet(n, joinSpecPath(path, "fields")); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// exclusive fails when a selection of the kind given by key has any of the other keys
+func (p *specParser) exclusive(m map[string]*yaml.Node, path, key string, others ...string) error {
+	for _, other := range others {
+		if n, ok := m[other]; ok {
+			return specErrorf(n, joinSpecPath(path, other), "%s can't be used along with %s", other, key)
+		}
+	}
+	return nil
+}
+
+func (p *specParser) directives(node *yaml.Node, path string) ([]*ast.Directive, error) {
+	items, err := p.sequence(node, path)
+	if err != nil {
+		return nil, err
+	}
+	directives := make([]*ast.Directive, 0, len(items))
+	for i, item := range items {
+		itemPath := indexSpecPath(path, i)
+		m, err := p.mapping(item, itemPath, "name", "arguments")
+		if err != nil {
+			return nil, err
+		}
+		n, ok := m["name"]
+		if !ok {
+			return nil, specErrorf(item, itemPath, "missing name")
+		}
+		name, err := p.name(n, joinSpecPath(itemPath, "name"))
+		if err != nil {
+			return nil, err
+		}
+		d := ast.NewDirective(&ast.Directive{Name: name, Arguments: make([]*ast.Argument, 0)})
+		if n, ok := m["arguments"]; ok {
+			if d.Arguments, err = p.arguments(n, joinSpecPath(itemPath, "arguments")); err != nil {
+				return nil, err
+			}
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+func (p *specParser) arguments(node *yaml.Node, path string) ([]*ast.Argument, error) {
+	if node.Kind != yaml.MappingNode {
+		return nil, specErrorf(node, path, "expected an object")
+	}
+	args := make([]*ast.Argument, 0, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		argPath := joinSpecPath(path, node.Content[i].Value)
+		name, err := p.name(node.Content[i], argPath)
+		if err != nil {
+			return nil, err
+		}
+		value, err := p.value(resolveAlias(node.Content[i+1]), argPath)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, ast.NewArgument(&ast.Argument{Name: name, Value: value}))
+	}
+	return args, nil
+}
+
+func (p *specParser) value(node *yaml.Node, path string) (ast.Value, error) {
+	switch node.Kind {
+	case yaml.ScalarNode:
+		switch node.ShortTag() {
+		case "!!int":
+			var i int64
+			if err := node.Decode(&i); err != nil {
+				return nil, specErrorf(node, path, "%s is not a valid integer", node.Value)
+			}
+			return ast.NewIntValue(&ast.IntValue{Value: strconv.FormatInt(i, 10)}), nil
+		case "!!float":
+			return p.float(node, path)
+		case "!!str":
+			return ast.NewStringValue(&ast.StringValue{Value: node.Value}), nil
+		case "!!bool":
+			var b bool
+			if err := node.Decode(&b); err != nil {
+				return nil, specErrorf(node, path, "%s is not a valid boolean", node.Value)
+			}
+			return ast.NewBooleanValue(&ast.BooleanValue{Value: b}), nil
+		case "!!null":
+			return nil, specErrorf(node, path, "null values are not supported")
+		}
+		return nil, specErrorf(node, path, "unsupported value %s", node.Value)
+	case yaml.SequenceNode:
+		items, err := p.sequence(node, path)
+		if err != nil {
+			return nil, err
+		}
+		values := make([]ast.Value, 0, len(items))
+		for i, item := range items {
+			value, err := p.value(item, indexSpecPath(path, i))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return ast.NewListValue(&ast.ListValue{Values: values}), nil
+	case yaml.MappingNode:
+		if len(node.Content) == 2 && strings.HasPrefix(node.Content[0].Value, "$") {
+			return p.typedValue(node.Content[0], resolveAlias(node.Content[1]), path)
+		}
+		fields := make([]*ast.ObjectField, 0, len(node.Content)/2)
+		for i := 0; i < len(node.Content); i += 2 {
+			fieldPath := joinSpecPath(path, node.Content[i].Value)
+			name, err := p.name(node.Content[i], fieldPath)
+			if err != nil {
+				return nil, err
+			}
+			value, err := p.value(resolveAlias(node.Content[i+1]), fieldPath)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, ast.NewObjectField(&ast.ObjectField{Name: name, Value: value}))
+		}
+		return ast.NewObjectValue(&ast.ObjectValue{Fields: fields}), nil
+	case yaml.AliasNode:
+		return p.value(resolveAlias(node), path)
+	}
+	return nil, specErrorf(node, path, "expected a value")
+}
+
+// typedValue reads the values written as {"$enum": "OPEN"}, {"$var": "first"} or {"$float": 1}
+func (p *specParser) typedValue(key, node *yaml.Node, path string) (ast.Value, error) {
+	valuePath := joinSpecPath(path, key.Value)
+	switch key.Value {
+	case "$enum":
+		name, err := p.name(node, valuePath)
+		if err != nil {
+			return nil, err
+		}
+		if name.Value == "true" || name.Value == "false" || name.Value == "null" {
+			return nil, specErrorf(node, valuePath, "%s is not a valid enum value", name.Value)
+		}
+		return ast.NewEnumValue(&ast.EnumValue{Value: name.Value}), nil
+	case "$var":
+		name, err := p.name(node, valuePath)
+		if err != nil {
+			return nil, err
+		}
+		return ast.NewVariable(&ast.Variable{Name: name}), nil
+	case "$float":
+		if node.Kind != yaml.ScalarNode || (node.ShortTag() != "!!int" && node.ShortTag() != "!!float") {
+			return nil, specErrorf(node, valuePath, "expected a number")
+		}
+		return p.float(node, valuePath)
+	}
+	return nil, specErrorf(key, valuePath, "unknown value type %s, expected $enum, $var or $float", key.Value)
+}
+
+// float reads a float, keeping the literal as written when it's a valid GraphQL number
+func (p *specParser) float(node *yaml.Node, path string) (ast.Value, error) {
+	var f float64
+	if err := node.Decode(&f); err != nil || math.IsInf(f, 0) || math.IsNaN(f) {
+		return nil, specErrorf(node, path, "%s is not a valid float", node.Value)
+	}
+	literal := node.Value
+	if !numberExpression.MatchString(literal) {
+		literal = strconv.FormatFloat(f, 'g', -1, 64)
+	}
+	return ast.NewFloatValue(&ast.FloatValue{Value: literal}), nil
+}
+
+// ToSpec exports the operation of a selection, along with the fragments it spreads, as a Spec
+func (s *Selection) ToSpec() (*Spec, error) {
+	doc, err := s.Root().Document()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := doc.Definitions[0].(*ast.OperationDefinition)
+	if !ok {
+		return nil, fmt.Errorf("fluentgraphql: cannot export a %s as a spec", doc.Definitions[0].GetKind())
+	}
+
+	spec := &Spec{Operation: op.Operation}
+	if op.Name != nil {
+		spec.Name = op.Name.Value
+	}
+	for _, v := range op.VariableDefinitions {
+		variable := VariableSpec{Name: v.Variable.Name.Value, Type: printer.Print(v.Type).(string)}
+		if v.DefaultValue != nil {
+			if variable.Default, err = specValue(v.DefaultValue); err != nil {
+				return nil, err
+			}
+		}
+		spec.Variables = append(spec.Variables, variable)
+	}
+	if spec.Directives, err = specDirectives(op.Directives); err != nil {
+		return nil, err
+	}
+	if spec.Fields, err = specSelections(op.SelectionSet); err != nil {
+		return nil, err
+	}
+	for _, def := range doc.Definitions[1:] {
+		f := def.(*ast.FragmentDefinition)
+		fragment := FragmentSpec{Name: f.Name.Value, On: f.TypeCondition.Name.Value}
+		if fragment.Directives, err = specDirectives(f.Directives); err != nil {
+			return nil, err
+		}
+		if fragment.Fields, err = specSelections(f.SelectionSet); err != nil {
+			return nil, err
+		}
+		spec.Fragments = append(spec.Fragments, fragment)
+	}
+	return spec, nil
+}
+
+func specSelections(set *ast.SelectionSet) ([]SelectionSpec, error) {
+	if set == nil {
+		return nil, nil
+	}
+	selections := make([]SelectionSpec, 0, len(set.Selections))
+	for _, selection := range set.Selections {
+		var spec SelectionSpec
+		var err error
+		switch n := selection.(type) {
+		case *ast.Field:
+			spec.Field = n.Name.Value
+			if n.Alias != nil {
+				spec.Alias = n.Alias.Value
+			}
+			if spec.Arguments, err = specArguments(n.Arguments); err != nil {
+				return nil, err
+			}
+			if spec.Directives, err = specDirectives(n.Directives); err != nil {
+				return nil, err
+			}
+			if spec.Fields, err = specSelections(n.SelectionSet); err != nil {
+				return nil, err
+			}
+		case *ast.InlineFragment:
+			spec.On = typeConditionName(n.TypeCondition)
+			if spec.Directives, err = specDirectives(n.Directives); err != nil {
+				return nil, err
+			}
+			if spec.Fields, err = specSelections(n.SelectionSet); err != nil {
+				return nil, err
+			}
+		case *ast.FragmentSpread:
+			spec.Spread = n.Name.Value
+			if spec.Directives, err = specDirectives(n.Directives); err != nil {
+				return nil, err
+			}
+		}
+		selections = append(selections, spec)
+	}
+	return selections, nil
+}
+
+func specDirectives(directives []*ast.Directive) ([]DirectiveSpec, error) {
+	var specs []DirectiveSpec
+	for _, d := range directives {
+		args, err := specArguments(d.Arguments)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, DirectiveSpec{Name: d.Name.Value, Arguments: args})
+	}
+	return specs, nil
+}
+
+func specArguments(args []*ast.Argument) (map[string]interface{}, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	values := make(map[string]interface{}, len(args))
+	for _, arg := range args {
+		value, err := specValue(arg.Value)
+		if err != nil {
+			return nil, err
+		}
+		values[arg.Name.Value] = value
+	}
+	return values, nil
+}
+
+// specValue converts a value to its JSON representation in a Spec
+func specValue(value ast.Value) (interface{}, error) {
+	switch v := value.(type) {
+	case *ast.IntValue:
+		i, err := strconv.ParseInt(v.Value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("fluentgraphql: cannot export integer %s", v.Value)
+		}
+		return i, nil
+	case *ast.FloatValue:
+		f, err := strconv.ParseFloat(v.Value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("fluentgraphql: cannot export float %s", v.Value)
+		}
+		// floats that JSON would print as integers keep their type
+		if f == math.Trunc(f) {
+			return map[string]interface{}{"$float": f}, nil
+		}
+		return f, nil
+	case *ast.StringValue:
+		return v.Value, nil
+	case *ast.BooleanValue:
+		return v.Value, nil
+	case *ast.EnumValue:
+		return map[string]interface{}{"$enum": v.Value}, nil
+	case *ast.Variable:
+		return map[string]interface{}{"$var": v.Name.Value}, nil
+	case *ast.ListValue:
+		list := make([]interface{}, 0, len(v.Values))
+		for _, item := range v.Values {
+			converted, err := specValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, converted)
+		}
+		return list, nil
+	case *ast.ObjectValue:
+		object := make(map[string]interface{}, len(v.Fields))
+		for _, f := range v.Fields {
+			converted, err := specValue(f.Value)
+			if err != nil {
+				return nil, err
+			}
+			object[f.Name.Value] = converted
+		}
+		return object, nil
+	}
+	return nil, fmt.Errorf("fluentgraphql: cannot export a %s", value.GetKind())
+}
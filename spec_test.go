@@ -0,0 +1,228 @@
+package fluentgraphql
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"gopkg.in/yaml.v3"
+)
+
+func TestFromSpec(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		wanted string
+		spec   string
+	}{
+		"Scalars": {
+			wanted: `{ viewer { login name } }`,
+			spec:   `{"fields": [{"field": "viewer", "fields": ["login", "name"]}]}`,
+		},
+		"Arguments": {
+			wanted: `query Issues($first: Int = 10, $labels: [String!]!) {
+				repo: repository(owner: "mergestat", name: "fluentgraphql", private: false) {
+					issues(first: $first, states: [OPEN, CLOSED], filterBy: {labels: $labels, since: 1.5}, weight: 2.0) { totalCount }
+				}
+			}`,
+			spec: `{
+				"name": "Issues",
+				"variables": [{"name": "first", "type": "Int", "default": 10}, {"name": "labels", "type": "[String!]!"}],
+				"fields": [{
+					"field": "repository",
+					"alias": "repo",
+					"arguments": {"owner": "mergestat", "name": "fluentgraphql", "private": false},
+					"fields": [{
+						"field": "issues",
+						"arguments": {
+							"first": {"$var": "first"},
+							"states": [{"$enum": "OPEN"}, {"$enum": "CLOSED"}],
+							"filterBy": {"labels": {"$var": "labels"}, "since": 1.5},
+							"weight": {"$float": 2.0}
+						},
+						"fields": ["totalCount"]
+					}]
+				}]
+			}`,
+		},
+		"YAML": {
+			wanted: `mutation Star($id: ID!) @live {
+				addStar(input: {starrableId: $id}) { starrable { ... on Repository @include(if: true) { stargazerCount } ...starrableFields } }
+			}
+			fragment starrableFields on Starrable { id viewerHasStarred }`,
+			spec: `
+operation: mutation
+name: Star
+variables:
+  - name: id
+    type: ID!
+directives:
+  - name: live
+fields:
+  - field: addStar
+    arguments:
+      input: {starrableId: {$var: id}}
+    fields:
+      - field: starrable
+        fields:
+          - on: Repository
+            directives: [{name: include, arguments: {if: true}}]
+            fields: [stargazerCount]
+          - spread: starrableFields
+fragments:
+  - name: starrableFields
+    on: Starrable
+    fields: [id, viewerHasStarred]
+`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, err := FromSpec(strings.NewReader(testCase.spec))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			wanted, err := Parse(testCase.wanted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(wanted.String(), s.String()); diff != "" {
+				t.Fatal("produced GraphQL query does not match what's wanted", diff)
+			}
+		})
+	}
+}
+
+func TestFromSpecErrors(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		wanted string
+		spec   string
+	}{
+		"Empty": {
+			wanted: "fluentgraphql: empty spec",
+			spec:   ``,
+		},
+		"Syntax": {
+			wanted: "fluentgraphql: invalid spec: yaml: did not find expected node content",
+			spec:   `{"fields": [}`,
+		},
+		"MissingFields": {
+			wanted: "fluentgraphql: invalid spec at the root (line 1, column 1): missing fields",
+			spec:   `{"name": "Q"}`,
+		},
+		"UnknownKey": {
+			wanted: `fluentgraphql: invalid spec at fields[0] (line 2, column 25): unknown key "feilds", expected one of field, alias, arguments, spread, on, directives, fields`,
+			spec: `{"fields": [
+				{"field": "viewer", "feilds": ["login"]}
+			]}`,
+		},
+		"InvalidName": {
+			wanted: `fluentgraphql: invalid spec at fields[1] (line 1, column 22): "first name" is not a valid GraphQL name`,
+			spec:   `{"fields": ["login", "first name"]}`,
+		},
+		"Null": {
+			wanted: "fluentgraphql: invalid spec at fields[0].arguments.after (line 4, column 24): null values are not supported",
+			spec: `
+fields:
+  - field: issues
+    arguments: {after: null}
+    fields: [totalCount]
+`,
+		},
+		"UnknownValueType": {
+			wanted: "fluentgraphql: invalid spec at fields[0].arguments.first.$variable (line 1, column 57): unknown value type $variable, expected $enum, $var or $float",
+			spec:   `{"fields": [{"field": "issues", "arguments": {"first": {"$variable": "first"}}}]}`,
+		},
+		"InvalidType": {
+			wanted: `fluentgraphql: invalid spec at variables[0].type (line 1, column 42): "[Int" is not a valid type reference`,
+			spec:   `{"variables": [{"name": "first", "type": "[Int"}], "fields": ["login"]}`,
+		},
+		"FieldAndSpread": {
+			wanted: "fluentgraphql: invalid spec at fields[0].spread (line 1, column 43): spread can't be used along with field",
+			spec:   `{"fields": [{"field": "viewer", "spread": "viewerFields"}]}`,
+		},
+		"EmptyFields": {
+			wanted: "fluentgraphql: invalid spec at fields[0].fields (line 1, column 43): expected at least one field",
+			spec:   `{"fields": [{"field": "viewer", "fields": []}]}`,
+		},
+		"UnknownFragment": {
+			wanted: "fluentgraphql: invalid spec at fields[0].fields[0].spread (line 1, column 55): unknown fragment viewerFields",
+			spec:   `{"fields": [{"field": "viewer", "fields": [{"spread": "viewerFields"}]}]}`,
+		},
+		"DuplicateFragment": {
+			wanted: "fluentgraphql: invalid spec at fragments[1].name (line 4, column 12): fragment userFields is defined more than once",
+			spec: `fields: [{spread: userFields}]
+fragments:
+  - {name: userFields, on: User, fields: [login]}
+  - {name: userFields, on: User, fields: [name]}
+`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := FromSpec(strings.NewReader(testCase.spec))
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if diff := cmp.Diff(testCase.wanted, err.Error()); diff != "" {
+				t.Fatal("error does not match what's wanted", diff)
+			}
+		})
+	}
+}
+
+func TestToSpec(t *testing.T) {
+	fields := NewFragment("ownerFields", "Owner")
+	fields.Scalar("login")
+
+	q := NewQuery(WithName("Repository"), WithVariableDefinitions(
+		NewVariableDefinition("first", "Int", false, NewIntValue(10)),
+	)).
+		Selection("repository", WithAlias("repo"), WithArguments(
+			NewArgument("name", NewStringValue("fluentgraphql")),
+			NewArgument("owner", NewStringValue("mergestat")),
+		)).
+		Scalar("name").
+		Selection("owner").Spread(fields).Parent().
+		Selection("issues", WithArguments(
+			NewArgument("filterBy", NewObjectValue(
+				NewObjectValueField("labels", NewListValue(NewStringValue("bug"))),
+				NewObjectValueField("weight", NewFloatValue(2)),
+			)),
+			NewArgument("first", NewVariableValue("first")),
+			NewArgument("ratio", NewFloatValue(0.5)),
+			NewArgument("states", NewListValue(NewEnumValue("OPEN"))),
+		), WithDirectives(NewDirective("include", NewArgument("if", NewBooleanValue(true))))).
+		InlineFragment("").Scalar("totalCount").
+		Root()
+
+	spec, err := q.ToSpec()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	b, err := json.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromJSON, err := FromSpec(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(q.String(), fromJSON.String()); diff != "" {
+		t.Fatal("GraphQL query built from the JSON spec does not match what's wanted", diff)
+	}
+
+	b, err = yaml.Marshal(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fromYAML, err := FromSpec(strings.NewReader(string(b)))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff(q.String(), fromYAML.String()); diff != "" {
+		t.Fatal("GraphQL query built from the YAML spec does not match what's wanted", diff)
+	}
+
+	if _, err := NewFragment("ownerFields", "Owner").ToSpec(); err == nil {
+		t.Fatal("expected an error exporting a fragment")
+	}
+}
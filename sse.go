@@ -0,0 +1,546 @@
+package fluentgraphql
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SSEExecutor executes operations over Server-Sent Events with the graphql-sse protocol.
+// By default every operation is POSTed on a connection of its own, which streams its results
+// back. In single connection mode, operations share one event stream, reserved up front.
+type SSEExecutor struct {
+	endpoint string
+	client   *http.Client
+	header   http.Header
+	single   bool
+
+	mu         sync.Mutex
+	stream     *sseStream
+	operations int
+}
+
+type sseExecutorOption func(*SSEExecutor)
+
+// NewSSEExecutor returns an executor for the graphql-sse endpoint at the given URL
+func NewSSEExecutor(endpoint string, options ...sseExecutorOption) *SSEExecutor {
+	e := &SSEExecutor{
+		endpoint: endpoint,
+		client:   http.DefaultClient,
+		header:   make(http.Header),
+	}
+	for _, option := range options {
+		option(e)
+	}
+	return e
+}
+
+// WithSSEHTTPClient specifies the HTTP client used to send requests and read event streams.
+// Its timeout, if any, bounds how long a stream stays open.
+func WithSSEHTTPClient(client *http.Client) sseExecutorOption {
+	return func(e *SSEExecutor) {
+		e.client = client
+	}
+}
+
+// WithSSEHeader adds a header to every request sent by the executor
+func WithSSEHeader(key, value string) sseExecutorOption {
+	return func(e *SSEExecutor) {
+		e.header.Add(key, value)
+	}
+}
+
+// WithSingleConnection makes operations share a single event stream, for servers and
+// browsers limiting the number of connections. The stream is opened by the first operation
+// and kept until Close is called, or reopened by the next operation once it is lost.
+func WithSingleConnection() sseExecutorOption {
+	return func(e *SSEExecutor) {
+		e.single = true
+	}
+}
+
+// Execute sends the request and returns its first response, for operations that have a
+// single result, stopping the operation on the server if it produces more
+func (e *SSEExecutor) Execute(ctx context.Context, req *Request) (*Response, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	messages, err := e.Subscribe(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	for m := range messages {
+		switch m.Type {
+		case SubscriptionNext:
+			return m.Response, nil
+		case SubscriptionError:
+			return nil, m.Err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("fluentgraphql: operation completed without a result")
+}
+
+// Subscribe sends the request and delivers its results on the returned channel, as next
+// messages followed by a complete one. The channel is closed once the operation completes,
+// its event stream is lost, or ctx is done, in which case the operation is stopped.
+// Servers answering with a plain JSON response have it delivered as a single result.
+func (e *SSEExecutor) Subscribe(ctx context.Context, req *Request) (<-chan *SubscriptionMessage, error) {
+	if err := sendableVariables(req.Variables, "server-sent events"); err != nil {
+		return nil, err
+	}
+	if e.single {
+		return e.subscribeSingle(ctx, req)
+	}
+
+	body, err := req.body()
+	if err != nil {
+		return nil, err
+	}
+	httpReq, err := e.newRequest(ctx, http.MethodPost, e.endpoint, body)
+	if err != nil {
+		return nil, err
+	}
+	copyHeader(httpReq.Header, req.Header)
+	httpReq.Header.Set("Accept", "text/event-stream")
+	res, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+
+	if res.StatusCode != http.StatusOK || !isEventStream(res) {
+		defer res.Body.Close()
+		response, err := decodeResponse(res)
+		if err != nil {
+			return nil, err
+		}
+		messages := make(chan *SubscriptionMessage, 2)
+		messages <- &SubscriptionMessage{Type: SubscriptionNext, Response: response}
+		messages <- &SubscriptionMessage{Type: SubscriptionComplete}
+		close(messages)
+		return messages, nil
+	}
+
+	messages := make(chan *SubscriptionMessage)
+	go func() {
+		defer close(messages)
+		defer res.Body.Close()
+		events := newSSEReader(res.Body)
+		for {
+			event, err := events.next()
+			if err != nil {
+				if ctx.Err() == nil {
+					deliver(ctx, messages, &SubscriptionMessage{Type: SubscriptionError, Err: streamError(err)})
+				}
+				return
+			}
+			switch event.name {
+			case "next":
+				var response Response
+				if err := json.Unmarshal(event.data, &response); err != nil {
+					deliver(ctx, messages, &SubscriptionMessage{Type: SubscriptionError, Err: fmt.Errorf("fluentgraphql: invalid next event: %w", err)})
+					return
+				}
+				deliver(ctx, messages, &SubscriptionMessage{Type: SubscriptionNext, Response: &response})
+			case "complete":
+				deliver(ctx, messages, &SubscriptionMessage{Type: SubscriptionComplete})
+				return
+			}
+		}
+	}()
+	return messages, nil
+}
+
+// Close closes the event stream shared by operations in single connection mode
+func (e *SSEExecutor) Close() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stream != nil {
+		e.stream.cancel()
+		e.stream = nil
+	}
+	return nil
+}
+
+// sseTokenHeader carries the token of the reserved stream in single connection mode
+const sseTokenHeader = "X-GraphQL-Event-Stream-Token"
+
+// subscribeSingle sends an operation over the shared stream, which it opens if needed
+func (e *SSEExecutor) subscribeSingle(ctx context.Context, req *Request) (<-chan *SubscriptionMessage, error) {
+	stream, err := e.connection(ctx)
+	if err != nil {
+		return nil, err
+	}
+	e.mu.Lock()
+	e.operations++
+	id := strconv.Itoa(e.operations)
+	e.mu.Unlock()
+
+	// the operation is registered first, as its results may arrive before the POST returns
+	op := stream.add(id)
+	body, err := req.body()
+	if err != nil {
+		stream.remove(id)
+		return nil, err
+	}
+	extensions := map[string]interface{}{"operationId": id}
+	for key, value := range req.Extensions {
+		extensions[key] = value
+	}
+	body["extensions"] = extensions
+
+	httpReq, err := e.newRequest(ctx, http.MethodPost, e.endpoint, body)
+	if err != nil {
+		stream.remove(id)
+		return nil, err
+	}
+	copyHeader(httpReq.Header, req.Header)
+	httpReq.Header.Set(sseTokenHeader, stream.token)
+	res, err := e.client.Do(httpReq)
+	if err != nil {
+		stream.remove(id)
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusAccepted {
+		stream.remove(id)
+		response, err := decodeResponse(res)
+		if err != nil {
+			return nil, err
+		}
+		if len(response.Errors) > 0 {
+			return nil, response.Errors
+		}
+		return nil, &HTTPError{StatusCode: res.StatusCode}
+	}
+
+	messages := make(chan *SubscriptionMessage)
+	go func() {
+		defer close(messages)
+		defer stream.remove(id)
+		for {
+			select {
+			case <-ctx.Done():
+				e.stop(stream.token, id)
+				return
+			case <-op.ready:
+			case <-stream.done:
+			}
+			// the events received before the stream was lost are delivered first
+			for _, event := range op.take() {
+				switch event.name {
+				case "next":
+					var response Response
+					if err := json.Unmarshal(event.Payload, &response); err != nil {
+						deliver(ctx, messages, &SubscriptionMessage{Type: SubscriptionError, Err: fmt.Errorf("fluentgraphql: invalid next event: %w", err)})
+						e.stop(stream.token, id)
+						return
+					}
+					deliver(ctx, messages, &SubscriptionMessage{Type: SubscriptionNext, Response: &response})
+				case "complete":
+					deliver(ctx, messages, &SubscriptionMessage{Type: SubscriptionComplete})
+					return
+				}
+			}
+			select {
+			case <-stream.done:
+				if ctx.Err() == nil {
+					deliver(ctx, messages, &SubscriptionMessage{Type: SubscriptionError, Err: streamError(stream.err)})
+				}
+				return
+			default:
+			}
+		}
+	}()
+	return messages, nil
+}
+
+// connection returns the shared stream, reserving and opening it unless it is open already
+func (e *SSEExecutor) connection(ctx context.Context) (*sseStream, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.stream != nil {
+		select {
+		case <-e.stream.done:
+		default:
+			return e.stream, nil
+		}
+	}
+
+	httpReq, err := e.newRequest(ctx, http.MethodPut, e.endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := e.client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if res.StatusCode != http.StatusCreated {
+		return nil, &HTTPError{StatusCode: res.StatusCode, Body: b}
+	}
+	token := strings.TrimSpace(string(b))
+
+	// the stream outlives ctx, which only bounds how long opening it may take
+	streamCtx, cancel := context.WithCancel(context.Background())
+	opened := make(chan struct{})
+	defer close(opened)
+	go func() {
+		select {
+		case <-ctx.Done():
+			cancel()
+		case <-opened:
+		}
+	}()
+
+	httpReq, err = e.newRequest(streamCtx, http.MethodGet, e.endpoint, nil)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	httpReq.Header.Set("Accept", "text/event-stream")
+	httpReq.Header.Set(sseTokenHeader, token)
+	res, err = e.client.Do(httpReq)
+	if err != nil {
+		cancel()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+	if res.StatusCode != http.StatusOK || !isEventStream(res) {
+		defer cancel()
+		defer res.Body.Close()
+		b, _ := ioutil.ReadAll(res.Body)
+		return nil, &HTTPError{StatusCode: res.StatusCode, Body: b}
+	}
+
+	e.stream = &sseStream{
+		token:      token,
+		cancel:     cancel,
+		operations: make(map[string]*sseOperation),
+		done:       make(chan struct{}),
+	}
+	go e.stream.read(res.Body)
+	return e.stream, nil
+}
+
+// stop tells the server to stop an operation of the shared stream
+func (e *SSEExecutor) stop(token, id string) {
+	u, err := url.Parse(e.endpoint)
+	if err != nil {
+		return
+	}
+	query := u.Query()
+	query.Set("operationId", id)
+	u.RawQuery = query.Encode()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	httpReq, err := e.newRequest(ctx, http.MethodDelete, u.String(), nil)
+	if err != nil {
+		return
+	}
+	httpReq.Header.Set(sseTokenHeader, token)
+	if res, err := e.client.Do(httpReq); err == nil {
+		res.Body.Close()
+	}
+}
+
+// newRequest returns a request with the headers of the executor, and body as JSON if any
+func (e *SSEExecutor) newRequest(ctx context.Context, method, target string, body interface{}) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		r = bytes.NewReader(b)
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, method, target, r)
+	if err != nil {
+		return nil, err
+	}
+	copyHeader(httpReq.Header, e.header)
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/json")
+	}
+	return httpReq, nil
+}
+
+// sseStream is the event stream shared by operations in single connection mode
+type sseStream struct {
+	token  string
+	cancel context.CancelFunc
+
+	mu         sync.Mutex
+	operations map[string]*sseOperation
+
+	// done is closed once the stream is lost, for the reason in err
+	done chan struct{}
+	err  error
+}
+
+// sseOperation queues the events of an operation of the shared stream, so that an
+// operation whose messages are not received doesn't hold up the others
+type sseOperation struct {
+	mu     sync.Mutex
+	events []*sseOperationEvent
+	// ready is signaled when events are queued
+	ready chan struct{}
+}
+
+// sseOperationEvent is an event of the shared stream, which wraps the payload of its
+// operation along with the operation id
+type sseOperationEvent struct {
+	name    string
+	ID      string          `json:"id"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+func (op *sseOperation) push(event *sseOperationEvent) {
+	op.mu.Lock()
+	op.events = append(op.events, event)
+	op.mu.Unlock()
+	select {
+	case op.ready <- struct{}{}:
+	default:
+	}
+}
+
+// take returns the queued events, emptying the queue
+func (op *sseOperation) take() []*sseOperationEvent {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	events := op.events
+	op.events = nil
+	return events
+}
+
+func (s *sseStream) add(id string) *sseOperation {
+	op := &sseOperation{ready: make(chan struct{}, 1)}
+	s.mu.Lock()
+	s.operations[id] = op
+	s.mu.Unlock()
+	return op
+}
+
+func (s *sseStream) remove(id string) {
+	s.mu.Lock()
+	delete(s.operations, id)
+	s.mu.Unlock()
+}
+
+// read dispatches the events of the stream to their operations until the stream is lost
+func (s *sseStream) read(body io.ReadCloser) {
+	defer body.Close()
+	events := newSSEReader(body)
+	for {
+		event, err := events.next()
+		if err != nil {
+			s.err = err
+			close(s.done)
+			return
+		}
+		if event.name != "next" && event.name != "complete" {
+			continue
+		}
+		e := &sseOperationEvent{name: event.name}
+		if err := json.Unmarshal(event.data, e); err != nil {
+			continue
+		}
+		s.mu.Lock()
+		op := s.operations[e.ID]
+		s.mu.Unlock()
+		if op != nil {
+			op.push(e)
+		}
+	}
+}
+
+// streamError returns the error to deliver when an event stream was lost
+func streamError(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("fluentgraphql: event stream lost: %w", err)
+}
+
+func isEventStream(res *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// sseEvent is an event of a text/event-stream
+type sseEvent struct {
+	name string
+	data []byte
+}
+
+// sseReader reads the events of a text/event-stream
+type sseReader struct {
+	r *bufio.Reader
+}
+
+func newSSEReader(r io.Reader) *sseReader {
+	return &sseReader{r: bufio.NewReader(r)}
+}
+
+// next returns the next event of the stream, skipping comments and the fields other than
+// event and data. An event left incomplete at the end of the stream is discarded.
+func (r *sseReader) next() (*sseEvent, error) {
+	var event sseEvent
+	var data [][]byte
+	var pending bool
+	for {
+		line, err := r.r.ReadBytes('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+
+		if len(line) == 0 {
+			if !pending {
+				continue
+			}
+			if event.name == "" {
+				event.name = "message"
+			}
+			event.data = bytes.Join(data, []byte("\n"))
+			return &event, nil
+		}
+		if line[0] == ':' {
+			continue
+		}
+
+		field, value := line, []byte(nil)
+		if i := bytes.IndexByte(line, ':'); i >= 0 {
+			field, value = line[:i], bytes.TrimPrefix(line[i+1:], []byte(" "))
+		}
+		switch string(field) {
+		case "event":
+			event.name = string(value)
+			pending = true
+		case "data":
+			data = append(data, value)
+			pending = true
+		}
+	}
+}
@@ -0,0 +1,244 @@
+package fluentgraphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestSSEExecutorSubscribe(t *testing.T) {
+	q := NewSubscription(WithName("Reviews")).Selection("reviewAdded").Scalar("stars").Root()
+
+	type test struct {
+		name        string
+		contentType string
+		status      int
+		body        string
+		expected    []string
+	}
+
+	var tests = []test{
+		{
+			name:        "Events",
+			contentType: "text/event-stream; charset=utf-8",
+			body: ":\n\n" +
+				"event: next\ndata: {\"data\":{\"reviewAdded\":{\"stars\":4}}}\n\n" +
+				"event: next\r\nid: 2\r\ndata: {\"data\":\r\ndata: {\"reviewAdded\":{\"stars\":5}}}\r\n\r\n" +
+				"event: complete\ndata:\n\n",
+			expected: []string{
+				`next {"reviewAdded":{"stars":4}}`,
+				"next {\"reviewAdded\":{\"stars\":5}}",
+				"complete",
+			},
+		},
+		{
+			name:        "Interrupted",
+			contentType: "text/event-stream",
+			body:        "event: next\ndata: {\"data\":1}\n\nevent: next\ndata: {\"data\":2}\n",
+			expected: []string{
+				"next 1",
+				"error fluentgraphql: event stream lost: unexpected EOF",
+			},
+		},
+		{
+			name:        "JSON",
+			contentType: "application/json",
+			body:        `{"data":{"reviewAdded":null}}`,
+			expected:    []string{`next {"reviewAdded":null}`, "complete"},
+		},
+		{
+			name:        "GraphQLErrors",
+			contentType: "application/graphql-response+json",
+			status:      http.StatusBadRequest,
+			body:        `{"errors":[{"message":"unknown field"}]}`,
+			expected:    []string{"next  unknown field", "complete"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				var body map[string]interface{}
+				json.NewDecoder(r.Body).Decode(&body)
+				if r.Method != http.MethodPost || r.Header.Get("Accept") != "text/event-stream" || body["query"] != q.String() {
+					t.Errorf("unexpected request %s %v %v", r.Method, r.Header, body)
+				}
+				w.Header().Set("Content-Type", test.contentType)
+				if test.status != 0 {
+					w.WriteHeader(test.status)
+				}
+				io.WriteString(w, test.body)
+			}))
+			defer server.Close()
+
+			messages, err := NewSSEExecutor(server.URL).Subscribe(context.Background(), NewRequest(q, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.expected, collect(messages)); diff != "" {
+				t.Errorf("unexpected messages (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSSEExecutorExecute(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		io.WriteString(w, "event: next\ndata: {\"data\":{\"viewer\":{\"login\":\"octocat\"}}}\n\nevent: complete\n\n")
+	}))
+	defer server.Close()
+
+	q := NewQuery().Selection("viewer").Scalar("login").Root()
+	response, err := NewSSEExecutor(server.URL, WithSSEHeader("Authorization", "bearer token")).Execute(context.Background(), NewRequest(q, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response.Data) != `{"viewer":{"login":"octocat"}}` {
+		t.Errorf("unexpected data %s", response.Data)
+	}
+
+	_, err = NewSSEExecutor(server.URL).Execute(context.Background(), NewRequest(q, nil))
+	if err == nil || err.Error() != "fluentgraphql: unexpected HTTP status 401" {
+		t.Errorf("expected HTTP error, got %v", err)
+	}
+}
+
+// singleConnectionServer is a graphql-sse server in single connection mode. Operations
+// named Ticks send a tick every few milliseconds until they are stopped, and the others
+// send their operation id back and complete.
+type singleConnectionServer struct {
+	mu           sync.Mutex
+	reservations int
+	events       chan string
+	stopped      []string
+}
+
+func (s *singleConnectionServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut && r.Header.Get(sseTokenHeader) != "token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	switch r.Method {
+	case http.MethodPut:
+		s.mu.Lock()
+		s.reservations++
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, "token")
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		for {
+			select {
+			case event := <-s.events:
+				io.WriteString(w, event)
+				w.(http.Flusher).Flush()
+			case <-r.Context().Done():
+				return
+			}
+		}
+	case http.MethodPost:
+		var body struct {
+			OperationName string
+			Extensions    struct{ OperationID string }
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		id := body.Extensions.OperationID
+		w.WriteHeader(http.StatusAccepted)
+		go func() {
+			if body.OperationName != "Ticks" {
+				s.events <- fmt.Sprintf("event: next\ndata: {\"id\":%q,\"payload\":{\"data\":%q}}\n\n", id, id)
+				s.events <- fmt.Sprintf("event: complete\ndata: {\"id\":%q}\n\n", id)
+				return
+			}
+			for {
+				if s.isStopped(id) {
+					return
+				}
+				s.events <- fmt.Sprintf("event: next\ndata: {\"id\":%q,\"payload\":{\"data\":\"tick\"}}\n\n", id)
+				time.Sleep(5 * time.Millisecond)
+			}
+		}()
+	case http.MethodDelete:
+		s.mu.Lock()
+		s.stopped = append(s.stopped, r.URL.Query().Get("operationId"))
+		s.mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *singleConnectionServer) isStopped(id string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, stopped := range s.stopped {
+		if stopped == id {
+			return true
+		}
+	}
+	return false
+}
+
+func TestSSEExecutorSingleConnection(t *testing.T) {
+	s := &singleConnectionServer{events: make(chan string)}
+	server := httptest.NewServer(s)
+	defer server.Close()
+
+	e := NewSSEExecutor(server.URL+"/stream", WithSingleConnection())
+	defer e.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ticks, err := e.Subscribe(ctx, NewRequest(NewSubscription(WithName("Ticks")).Scalar("tick").Root(), nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := <-ticks; m.Type != SubscriptionNext || string(m.Response.Data) != `"tick"` {
+		t.Errorf("unexpected message %+v", m)
+	}
+
+	var wg sync.WaitGroup
+	results := make([]string, 3)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			response, err := e.Execute(context.Background(), NewRequest(NewQuery().Scalar("id").Root(), nil))
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			results[i] = string(response.Data)
+		}(i)
+	}
+	wg.Wait()
+	sort.Strings(results)
+	if diff := cmp.Diff([]string{`"2"`, `"3"`, `"4"`}, results); diff != "" {
+		t.Errorf("unexpected results (-want +got):\n%s", diff)
+	}
+
+	cancel()
+	for range ticks {
+	}
+	if !s.isStopped("1") {
+		t.Error("expected operation 1 to be stopped")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.reservations != 1 {
+		t.Errorf("expected a single reservation, got %d", s.reservations)
+	}
+}
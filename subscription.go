@@ -0,0 +1,416 @@
+package fluentgraphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// SubscriptionProtocol is a WebSocket sub-protocol carrying GraphQL subscriptions
+type SubscriptionProtocol string
+
+const (
+	// GraphQLTransportWS is the graphql-transport-ws protocol of the graphql-ws library
+	GraphQLTransportWS SubscriptionProtocol = "graphql-transport-ws"
+	// GraphQLWS is the legacy graphql-ws protocol of subscriptions-transport-ws
+	GraphQLWS SubscriptionProtocol = "graphql-ws"
+)
+
+// The types of the messages delivered for a subscription
+const (
+	// SubscriptionNext carries a response of the subscription
+	SubscriptionNext = "next"
+	// SubscriptionError carries the error that ended the subscription
+	SubscriptionError = "error"
+	// SubscriptionComplete tells that the server completed the subscription
+	SubscriptionComplete = "complete"
+)
+
+// SubscriptionMessage is a message delivered for a subscription. Next messages carry a
+// response, and error messages the GraphQL errors sent by the server, as ResponseErrors,
+// or the reason the connection was lost for good.
+type SubscriptionMessage struct {
+	Type     string
+	Response *Response
+	Err      error
+}
+
+// SubscriptionClient executes subscriptions over WebSockets, opening a connection for each
+type SubscriptionClient struct {
+	endpoint     string
+	protocol     SubscriptionProtocol
+	dialer       *websocket.Dialer
+	header       http.Header
+	initPayload  map[string]interface{}
+	ackTimeout   time.Duration
+	pingInterval time.Duration
+	retries      int
+	minBackoff   time.Duration
+	maxBackoff   time.Duration
+}
+
+type subscriptionClientOption func(*SubscriptionClient)
+
+// NewSubscriptionClient returns a client for the GraphQL WebSocket endpoint at the given
+// URL, such as wss://example.com/graphql. It speaks graphql-transport-ws, pings the server
+// every 30 seconds, and reconnects up to 5 times when a connection is lost.
+func NewSubscriptionClient(endpoint string, options ...subscriptionClientOption) *SubscriptionClient {
+	c := &SubscriptionClient{
+		endpoint:     endpoint,
+		protocol:     GraphQLTransportWS,
+		dialer:       websocket.DefaultDialer,
+		header:       make(http.Header),
+		ackTimeout:   10 * time.Second,
+		pingInterval: 30 * time.Second,
+		retries:      5,
+		minBackoff:   500 * time.Millisecond,
+		maxBackoff:   30 * time.Second,
+	}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// WithSubscriptionProtocol specifies the protocol spoken by the client
+func WithSubscriptionProtocol(protocol SubscriptionProtocol) subscriptionClientOption {
+	return func(c *SubscriptionClient) {
+		c.protocol = protocol
+	}
+}
+
+// WithConnectionInitPayload specifies the payload of the connection_init message,
+// which servers commonly read credentials from
+func WithConnectionInitPayload(payload map[string]interface{}) subscriptionClientOption {
+	return func(c *SubscriptionClient) {
+		c.initPayload = payload
+	}
+}
+
+// WithSubscriptionHeader adds a header to the HTTP request opening each connection
+func WithSubscriptionHeader(key, value string) subscriptionClientOption {
+	return func(c *SubscriptionClient) {
+		c.header.Add(key, value)
+	}
+}
+
+// WithKeepAlive specifies how often the client pings the server, with graphql-transport-ws.
+// A connection is considered lost when a ping goes unanswered until the next one.
+// An interval of 0 disables pings.
+func WithKeepAlive(interval time.Duration) subscriptionClientOption {
+	return func(c *SubscriptionClient) {
+		c.pingInterval = interval
+	}
+}
+
+// WithReconnect specifies how many times in a row the client reconnects and subscribes
+// again when a connection is lost, waiting from minBackoff up to maxBackoff, twice as
+// long after each failed attempt. 0 retries disables reconnecting.
+func WithReconnect(retries int, minBackoff, maxBackoff time.Duration) subscriptionClientOption {
+	return func(c *SubscriptionClient) {
+		c.retries = retries
+		c.minBackoff = minBackoff
+		c.maxBackoff = maxBackoff
+	}
+}
+
+// Subscribe opens a connection and starts a subscription, returning once the server
+// acknowledged the connection. The messages of the subscription are delivered on the
+// returned channel, which is closed after a complete or error message, or once ctx is
+// done, in which case the subscription is stopped.
+func (c *SubscriptionClient) Subscribe(ctx context.Context, req *Request) (<-chan *SubscriptionMessage, error) {
+	if err := sendableVariables(req.Variables, "WebSocket"); err != nil {
+		return nil, err
+	}
+	conn, err := c.connect(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	messages := make(chan *SubscriptionMessage)
+	go c.run(ctx, req, conn, messages)
+	return messages, nil
+}
+
+// subscriptionID identifies the subscription of a connection, which only carries one
+const subscriptionID = "1"
+
+// wsMessage is a message of the graphql-transport-ws and graphql-ws protocols
+type wsMessage struct {
+	ID      string          `json:"id,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+}
+
+// wsConn is a connection safe to write to from several goroutines
+type wsConn struct {
+	*websocket.Conn
+	mu sync.Mutex
+}
+
+func (c *wsConn) send(id, messageType string, payload interface{}) error {
+	m := wsMessage{ID: id, Type: messageType}
+	if payload != nil {
+		b, err := json.Marshal(payload)
+		if err != nil {
+			return err
+		}
+		m.Payload = b
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.WriteJSON(m)
+}
+
+func (c *wsConn) receive() (*wsMessage, error) {
+	var m wsMessage
+	if err := c.ReadJSON(&m); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// connect opens a connection, waits for the server to acknowledge it, and subscribes
+func (c *SubscriptionClient) connect(ctx context.Context, req *Request) (*wsConn, error) {
+	dialer := *c.dialer
+	dialer.Subprotocols = []string{string(c.protocol)}
+	header := c.header.Clone()
+	copyHeader(header, req.Header)
+	conn, res, err := dialer.DialContext(ctx, c.endpoint, header)
+	if err != nil {
+		if res != nil {
+			return nil, &HTTPError{StatusCode: res.StatusCode}
+		}
+		return nil, err
+	}
+	if conn.Subprotocol() != string(c.protocol) {
+		conn.Close()
+		return nil, fmt.Errorf("fluentgraphql: server doesn't speak %s", c.protocol)
+	}
+	ws := &wsConn{Conn: conn}
+
+	// the connection is closed if ctx is done before it is acknowledged
+	acknowledged := make(chan struct{})
+	defer close(acknowledged)
+	go func() {
+		select {
+		case <-ctx.Done():
+			conn.Close()
+		case <-acknowledged:
+		}
+	}()
+
+	var payload interface{}
+	if c.initPayload != nil {
+		payload = c.initPayload
+	}
+	if err := ws.send("", "connection_init", payload); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	conn.SetReadDeadline(time.Now().Add(c.ackTimeout))
+	for acked := false; !acked; {
+		m, err := ws.receive()
+		if err != nil {
+			conn.Close()
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			return nil, err
+		}
+		switch m.Type {
+		case "connection_ack":
+			acked = true
+		case "ping":
+			if err := ws.send("", "pong", nil); err != nil {
+				conn.Close()
+				return nil, err
+			}
+		case "ka":
+		case "connection_error":
+			conn.Close()
+			return nil, fmt.Errorf("fluentgraphql: connection refused: %s", m.Payload)
+		default:
+			conn.Close()
+			return nil, fmt.Errorf("fluentgraphql: unexpected %s message before connection_ack", m.Type)
+		}
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	subscribe := "subscribe"
+	if c.protocol == GraphQLWS {
+		subscribe = "start"
+	}
+	body, err := req.body()
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := ws.send(subscriptionID, subscribe, body); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+// run delivers the messages of a subscription, reconnecting when its connection is lost
+func (c *SubscriptionClient) run(ctx context.Context, req *Request, conn *wsConn, out chan<- *SubscriptionMessage) {
+	defer close(out)
+	for {
+		err := c.receive(ctx, conn, out)
+		if err == nil || ctx.Err() != nil {
+			return
+		}
+		if !isRetryable(err) {
+			deliver(ctx, out, &SubscriptionMessage{Type: SubscriptionError, Err: fmt.Errorf("fluentgraphql: subscription connection closed: %w", err)})
+			return
+		}
+		if conn, err = c.reconnect(ctx, req); err != nil {
+			if ctx.Err() == nil {
+				deliver(ctx, out, &SubscriptionMessage{Type: SubscriptionError, Err: fmt.Errorf("fluentgraphql: subscription connection lost: %w", err)})
+			}
+			return
+		}
+	}
+}
+
+// reconnect connects again, backing off between attempts
+func (c *SubscriptionClient) reconnect(ctx context.Context, req *Request) (*wsConn, error) {
+	err := errors.New("fluentgraphql: reconnecting is disabled")
+	backoff := c.minBackoff
+	for attempt := 0; attempt < c.retries; attempt++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+
+		var conn *wsConn
+		if conn, err = c.connect(ctx, req); err == nil {
+			return conn, nil
+		}
+		if !isRetryable(err) {
+			return nil, err
+		}
+		if backoff *= 2; backoff > c.maxBackoff {
+			backoff = c.maxBackoff
+		}
+	}
+	return nil, err
+}
+
+// receive delivers the messages of a connection until the subscription ends, returning
+// nil, or until the connection is lost, returning why
+func (c *SubscriptionClient) receive(ctx context.Context, conn *wsConn, out chan<- *SubscriptionMessage) error {
+	done := make(chan struct{})
+	defer close(done)
+	defer conn.Close()
+
+	// awaiting is set while a ping goes unanswered
+	var awaiting int32
+	go func() {
+		var ticks <-chan time.Time
+		if c.pingInterval > 0 && c.protocol == GraphQLTransportWS {
+			ticker := time.NewTicker(c.pingInterval)
+			defer ticker.Stop()
+			ticks = ticker.C
+		}
+		for {
+			select {
+			case <-done:
+				return
+			case <-ctx.Done():
+				if c.protocol == GraphQLWS {
+					conn.send(subscriptionID, "stop", nil)
+					conn.send("", "connection_terminate", nil)
+				} else {
+					conn.send(subscriptionID, "complete", nil)
+				}
+				conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+				conn.Close()
+				return
+			case <-ticks:
+				if !atomic.CompareAndSwapInt32(&awaiting, 0, 1) {
+					conn.Close()
+					return
+				}
+				conn.send("", "ping", nil)
+			}
+		}
+	}()
+
+	for {
+		m, err := conn.receive()
+		if err != nil {
+			return err
+		}
+		atomic.StoreInt32(&awaiting, 0)
+
+		switch m.Type {
+		case "next", "data":
+			var response Response
+			if err := json.Unmarshal(m.Payload, &response); err != nil {
+				deliver(ctx, out, &SubscriptionMessage{Type: SubscriptionError, Err: fmt.Errorf("fluentgraphql: invalid %s payload: %w", m.Type, err)})
+				return nil
+			}
+			deliver(ctx, out, &SubscriptionMessage{Type: SubscriptionNext, Response: &response})
+		case "error":
+			deliver(ctx, out, &SubscriptionMessage{Type: SubscriptionError, Err: subscriptionErrors(m.Payload)})
+			return nil
+		case "complete":
+			deliver(ctx, out, &SubscriptionMessage{Type: SubscriptionComplete})
+			return nil
+		case "ping":
+			if err := conn.send("", "pong", nil); err != nil {
+				return err
+			}
+		case "connection_error":
+			deliver(ctx, out, &SubscriptionMessage{Type: SubscriptionError, Err: fmt.Errorf("fluentgraphql: connection refused: %s", m.Payload)})
+			return nil
+		}
+	}
+}
+
+// deliver sends a message unless ctx is done first
+func deliver(ctx context.Context, out chan<- *SubscriptionMessage, m *SubscriptionMessage) {
+	select {
+	case out <- m:
+	case <-ctx.Done():
+	}
+}
+
+// subscriptionErrors decodes the payload of an error message, a list of GraphQL errors
+// with graphql-transport-ws, and a single one with graphql-ws
+func subscriptionErrors(payload json.RawMessage) error {
+	var errs ResponseErrors
+	if err := json.Unmarshal(payload, &errs); err == nil {
+		return errs
+	}
+	var single ResponseError
+	if err := json.Unmarshal(payload, &single); err != nil {
+		return fmt.Errorf("fluentgraphql: invalid error payload: %w", err)
+	}
+	return ResponseErrors{&single}
+}
+
+// isRetryable tells whether a connection failed for a reason reconnecting may fix, which
+// excludes the 4400-4499 close codes servers use to reject a client
+func isRetryable(err error) bool {
+	var closeErr *websocket.CloseError
+	if errors.As(err, &closeErr) {
+		return closeErr.Code < 4400 || closeErr.Code > 4499
+	}
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= http.StatusInternalServerError
+	}
+	return true
+}
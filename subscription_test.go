@@ -0,0 +1,309 @@
+package fluentgraphql
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/gorilla/websocket"
+)
+
+// subscriptionServer starts a server upgrading requests to the protocol, and handing
+// the connections to handle
+func subscriptionServer(t *testing.T, protocol SubscriptionProtocol, handle func(conn *websocket.Conn)) string {
+	upgrader := websocket.Upgrader{Subprotocols: []string{string(protocol)}}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		handle(conn)
+	}))
+	t.Cleanup(server.Close)
+	return "ws" + strings.TrimPrefix(server.URL, "http")
+}
+
+// expect reads the next message of a connection, failing unless it has the given type
+func expect(t *testing.T, conn *websocket.Conn, messageType string) *wsMessage {
+	var m wsMessage
+	if err := conn.ReadJSON(&m); err != nil {
+		t.Errorf("reading %s: %v", messageType, err)
+		return &m
+	}
+	if m.Type != messageType {
+		t.Errorf("expected %s message, got %s", messageType, m.Type)
+	}
+	return &m
+}
+
+func write(conn *websocket.Conn, id, messageType, payload string) {
+	m := wsMessage{ID: id, Type: messageType}
+	if payload != "" {
+		m.Payload = json.RawMessage(payload)
+	}
+	conn.WriteJSON(m)
+}
+
+// handshake acknowledges the connection and returns the subscribe message
+func handshake(t *testing.T, conn *websocket.Conn, subscribe string) *wsMessage {
+	expect(t, conn, "connection_init")
+	write(conn, "", "connection_ack", "")
+	return expect(t, conn, subscribe)
+}
+
+func collect(messages <-chan *SubscriptionMessage) []string {
+	var got []string
+	for m := range messages {
+		switch {
+		case m.Response != nil && len(m.Response.Errors) > 0:
+			got = append(got, m.Type+" "+string(m.Response.Data)+" "+m.Response.Errors.Error())
+		case m.Response != nil:
+			got = append(got, m.Type+" "+string(m.Response.Data))
+		case m.Err != nil:
+			got = append(got, m.Type+" "+m.Err.Error())
+		default:
+			got = append(got, m.Type)
+		}
+	}
+	return got
+}
+
+func TestSubscribe(t *testing.T) {
+	q := NewSubscription(WithName("Reviews")).Selection("reviewAdded").Scalar("stars").Root()
+
+	type test struct {
+		name     string
+		options  []subscriptionClientOption
+		handle   func(t *testing.T, conn *websocket.Conn)
+		expected []string
+	}
+
+	var tests = []test{
+		{
+			name:    "Next",
+			options: []subscriptionClientOption{WithConnectionInitPayload(map[string]interface{}{"token": "secret"})},
+			handle: func(t *testing.T, conn *websocket.Conn) {
+				init := expect(t, conn, "connection_init")
+				if string(init.Payload) != `{"token":"secret"}` {
+					t.Errorf("unexpected connection_init payload %s", init.Payload)
+				}
+				write(conn, "", "connection_ack", "")
+				subscribe := expect(t, conn, "subscribe")
+				var body map[string]interface{}
+				json.Unmarshal(subscribe.Payload, &body)
+				if body["operationName"] != "Reviews" || body["query"] != q.String() {
+					t.Errorf("unexpected subscribe payload %s", subscribe.Payload)
+				}
+				write(conn, subscribe.ID, "next", `{"data":{"reviewAdded":{"stars":4}}}`)
+				write(conn, subscribe.ID, "next", `{"data":{"reviewAdded":{"stars":5}}}`)
+				write(conn, subscribe.ID, "complete", "")
+			},
+			expected: []string{
+				`next {"reviewAdded":{"stars":4}}`,
+				`next {"reviewAdded":{"stars":5}}`,
+				"complete",
+			},
+		},
+		{
+			name: "Error",
+			handle: func(t *testing.T, conn *websocket.Conn) {
+				subscribe := handshake(t, conn, "subscribe")
+				write(conn, subscribe.ID, "error", `[{"message":"unknown field"},{"message":"bad argument"}]`)
+			},
+			expected: []string{"error unknown field; bad argument"},
+		},
+		{
+			name:    "Ping",
+			options: []subscriptionClientOption{WithKeepAlive(20 * time.Millisecond)},
+			handle: func(t *testing.T, conn *websocket.Conn) {
+				subscribe := handshake(t, conn, "subscribe")
+				write(conn, "", "ping", "")
+				expect(t, conn, "pong")
+				expect(t, conn, "ping")
+				write(conn, "", "pong", "")
+				write(conn, subscribe.ID, "complete", "")
+			},
+			expected: []string{"complete"},
+		},
+		{
+			name: "Reconnect",
+			options: []subscriptionClientOption{
+				WithReconnect(2, time.Millisecond, time.Millisecond),
+			},
+			handle: func() func(t *testing.T, conn *websocket.Conn) {
+				var connections int32
+				return func(t *testing.T, conn *websocket.Conn) {
+					subscribe := handshake(t, conn, "subscribe")
+					if atomic.AddInt32(&connections, 1) == 1 {
+						write(conn, subscribe.ID, "next", `{"data":1}`)
+						return
+					}
+					write(conn, subscribe.ID, "next", `{"data":2}`)
+					write(conn, subscribe.ID, "complete", "")
+				}
+			}(),
+			expected: []string{"next 1", "next 2", "complete"},
+		},
+		{
+			name:    "ConnectionLost",
+			options: []subscriptionClientOption{WithReconnect(0, 0, 0)},
+			handle: func(t *testing.T, conn *websocket.Conn) {
+				handshake(t, conn, "subscribe")
+			},
+			expected: []string{"error fluentgraphql: subscription connection lost: fluentgraphql: reconnecting is disabled"},
+		},
+		{
+			name:    "Rejected",
+			options: []subscriptionClientOption{WithReconnect(3, time.Millisecond, time.Millisecond)},
+			handle: func(t *testing.T, conn *websocket.Conn) {
+				handshake(t, conn, "subscribe")
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4403, "Forbidden"))
+			},
+			expected: []string{"error fluentgraphql: subscription connection closed: websocket: close 4403: Forbidden"},
+		},
+		{
+			name:    "Legacy",
+			options: []subscriptionClientOption{WithSubscriptionProtocol(GraphQLWS)},
+			handle: func(t *testing.T, conn *websocket.Conn) {
+				expect(t, conn, "connection_init")
+				write(conn, "", "connection_ack", "")
+				write(conn, "", "ka", "")
+				start := expect(t, conn, "start")
+				write(conn, "", "ka", "")
+				write(conn, start.ID, "data", `{"data":{"reviewAdded":{"stars":3}}}`)
+				write(conn, start.ID, "error", `{"message":"forbidden"}`)
+			},
+			expected: []string{
+				`next {"reviewAdded":{"stars":3}}`,
+				"error forbidden",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			client := NewSubscriptionClient("", test.options...)
+			client.endpoint = subscriptionServer(t, client.protocol, func(conn *websocket.Conn) {
+				test.handle(t, conn)
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			messages, err := client.Subscribe(ctx, NewRequest(q, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(test.expected, collect(messages)); diff != "" {
+				t.Errorf("unexpected messages (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestSubscribeCancel(t *testing.T) {
+	for _, protocol := range []SubscriptionProtocol{GraphQLTransportWS, GraphQLWS} {
+		t.Run(string(protocol), func(t *testing.T) {
+			stopped := make(chan []string, 1)
+			endpoint := subscriptionServer(t, protocol, func(conn *websocket.Conn) {
+				subscribe := "subscribe"
+				if protocol == GraphQLWS {
+					subscribe = "start"
+				}
+				m := handshake(t, conn, subscribe)
+				write(conn, m.ID, "next", `{"data":1}`)
+
+				var received []string
+				for {
+					var m wsMessage
+					if err := conn.ReadJSON(&m); err != nil {
+						break
+					}
+					received = append(received, m.Type)
+				}
+				stopped <- received
+			})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			client := NewSubscriptionClient(endpoint, WithSubscriptionProtocol(protocol))
+			messages, err := client.Subscribe(ctx, NewRequest(NewSubscription().Scalar("ticks").Root(), nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			<-messages
+			cancel()
+			for range messages {
+			}
+
+			expected := []string{"complete"}
+			if protocol == GraphQLWS {
+				expected = []string{"stop", "connection_terminate"}
+			}
+			select {
+			case received := <-stopped:
+				if diff := cmp.Diff(expected, received); diff != "" {
+					t.Errorf("unexpected messages sent on cancel (-want +got):\n%s", diff)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("server connection was not closed")
+			}
+		})
+	}
+}
+
+func TestSubscribeConnectionError(t *testing.T) {
+	type test struct {
+		name     string
+		protocol SubscriptionProtocol
+		handle   func(conn *websocket.Conn)
+		expected string
+	}
+
+	var tests = []test{
+		{
+			name:     "Protocol",
+			protocol: "graphql-other",
+			handle:   func(conn *websocket.Conn) {},
+			expected: "fluentgraphql: server doesn't speak graphql-transport-ws",
+		},
+		{
+			name:     "Forbidden",
+			protocol: GraphQLTransportWS,
+			handle: func(conn *websocket.Conn) {
+				conn.ReadMessage()
+				conn.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(4403, "Forbidden"))
+			},
+			expected: "websocket: close 4403: Forbidden",
+		},
+		{
+			name:     "ConnectionError",
+			protocol: GraphQLWS,
+			handle: func(conn *websocket.Conn) {
+				conn.ReadMessage()
+				write(conn, "", "connection_error", `{"message":"invalid token"}`)
+			},
+			expected: `fluentgraphql: connection refused: {"message":"invalid token"}`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			endpoint := subscriptionServer(t, test.protocol, test.handle)
+			protocol := test.protocol
+			if protocol != GraphQLWS {
+				protocol = GraphQLTransportWS
+			}
+			client := NewSubscriptionClient(endpoint, WithSubscriptionProtocol(protocol))
+			_, err := client.Subscribe(context.Background(), NewRequest(NewSubscription().Scalar("ticks").Root(), nil))
+			if err == nil || err.Error() != test.expected {
+				t.Errorf("expected error %q, got %v", test.expected, err)
+			}
+		})
+	}
+}
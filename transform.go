@@ -0,0 +1,372 @@
+package fluentgraphql
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/printer"
+)
+
+// InlineFragments returns a copy of the selection with every fragment spread expanded,
+// for servers that don't support named fragments. A spread becomes an inline fragment
+// on the type condition of the fragment, keeping the directives of the spread, or its
+// fields directly when the spread is within a fragment on that same type.
+func (s *Selection) InlineFragments() (*Selection, error) {
+	s, err := s.typed()
+	if err != nil {
+		return nil, err
+	}
+	definitions, err := s.definitions()
+	if err != nil {
+		return nil, err
+	}
+	fragments := make(map[string]*ast.FragmentDefinition)
+	for _, def := range definitions[1:] {
+		f := def.(*ast.FragmentDefinition)
+		fragments[f.Name.Value] = f
+	}
+
+	i := &inliner{fragments: fragments}
+	var node ast.Node
+	switch n := s.node.(type) {
+	case *ast.OperationDefinition:
+		copied := *n
+		copied.SelectionSet, err = i.selectionSet(n.SelectionSet, "", nil)
+		node = &copied
+	case *ast.FragmentDefinition:
+		copied := *n
+		copied.SelectionSet, err = i.selectionSet(n.SelectionSet, n.TypeCondition.Name.Value, []string{n.Name.Value})
+		node = &copied
+	case *ast.Field:
+		copied := *n
+		copied.SelectionSet, err = i.selectionSet(n.SelectionSet, "", nil)
+		node = &copied
+	case *ast.InlineFragment:
+		copied := *n
+		copied.SelectionSet, err = i.selectionSet(n.SelectionSet, typeConditionName(n.TypeCondition), nil)
+		node = &copied
+	default:
+		return nil, fmt.Errorf("fluentgraphql: cannot inline fragments of a %s", s.node.GetKind())
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Selection{node: node}, nil
+}
+
+type inliner struct {
+	fragments map[string]*ast.FragmentDefinition
+}
+
+// selectionSet copies a selection set, expanding its fragment spreads. typeCondition is the
+// type the selections are known to apply to, which is empty when unknown, and stack holds the
+// names of the fragments being expanded.
+func (i *inliner) selectionSet(set *ast.SelectionSet, typeCondition string, stack []string) (*ast.SelectionSet, error) {
+	if set == nil {
+		return nil, nil
+	}
+
+	selections := make([]ast.Selection, 0, len(set.Selections))
+	for _, selection := range set.Selections {
+		switch n := selection.(type) {
+		case *ast.Field:
+			sub, err := i.selectionSet(n.SelectionSet, "", stack)
+			if err != nil {
+				return nil, err
+			}
+			copied := *n
+			copied.SelectionSet = sub
+			selections = append(selections, &copied)
+		case *ast.InlineFragment:
+			condition := typeCondition
+			if n.TypeCondition != nil {
+				condition = n.TypeCondition.Name.Value
+			}
+			sub, err := i.selectionSet(n.SelectionSet, condition, stack)
+			if err != nil {
+				return nil, err
+			}
+			copied := *n
+			copied.SelectionSet = sub
+			selections = append(selections, &copied)
+		case *ast.FragmentSpread:
+			name := n.Name.Value
+			f, ok := i.fragments[name]
+			if !ok {
+				return nil, fmt.Errorf("fluentgraphql: unknown fragment %s", name)
+			}
+			for j, spread := range stack {
+				if spread == name {
+					return nil, fmt.Errorf("fluentgraphql: fragment cycle %s -> %s", strings.Join(stack[j:], " -> "), name)
+				}
+			}
+			condition := f.TypeCondition.Name.Value
+			sub, err := i.selectionSet(f.SelectionSet, condition, append(stack[:len(stack):len(stack)], name))
+			if err != nil {
+				return nil, err
+			}
+			if len(n.Directives) == 0 && condition == typeCondition {
+				selections = append(selections, sub.Selections...)
+				continue
+			}
+			selections = append(selections, ast.NewInlineFragment(&ast.InlineFragment{
+				TypeCondition: f.TypeCondition,
+				Directives:    n.Directives,
+				SelectionSet:  sub,
+			}))
+		}
+	}
+	return ast.NewSelectionSet(&ast.SelectionSet{Selections: selections}), nil
+}
+
+// ExtractFragments returns a copy of the operation with the sub-selections that are
+// repeated on the same type hoisted into fragment definitions, named after the type.
+// The schema tells the type of each sub-selection. The largest repeated sub-selections
+// are extracted first, until no sub-selection is repeated. Sub-selections of fewer than
+// two fields, not counting __typename, are left as they are, as are the meta fields
+// such as __schema and __type.
+func (s *Selection) ExtractFragments(schema graphql.Schema) (*Selection, error) {
+	s, err := s.typed()
+	if err != nil {
+		return nil, err
+	}
+	definitions, err := s.definitions()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := s.node.(*ast.OperationDefinition)
+	if !ok {
+		return nil, errors.New("fluentgraphql: fragments can only be extracted from operations")
+	}
+
+	var root *graphql.Object
+	switch op.Operation {
+	case ast.OperationTypeQuery:
+		root = schema.QueryType()
+	case ast.OperationTypeMutation:
+		root = schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = schema.SubscriptionType()
+	}
+	if root == nil {
+		return nil, fmt.Errorf("fluentgraphql: schema has no %s type", op.Operation)
+	}
+
+	copied := *op
+	copied.SelectionSet = copySelectionSet(op.SelectionSet)
+	e := &extractor{schema: schema, names: make(map[string]bool)}
+	for _, def := range definitions[1:] {
+		f := *def.(*ast.FragmentDefinition)
+		f.SelectionSet = copySelectionSet(f.SelectionSet)
+		e.fragments = append(e.fragments, &f)
+		e.names[f.Name.Value] = true
+	}
+
+	for {
+		e.occurrences = make(map[string][]*ast.Field)
+		e.keys = nil
+		if err := e.collect(copied.SelectionSet, root); err != nil {
+			return nil, err
+		}
+		for _, f := range e.fragments {
+			t := schema.Type(f.TypeCondition.Name.Value)
+			if t == nil {
+				return nil, fmt.Errorf("fluentgraphql: unknown type %s", f.TypeCondition.Name.Value)
+			}
+			if err := e.collect(f.SelectionSet, t); err != nil {
+				return nil, err
+			}
+		}
+		if !e.extract() {
+			break
+		}
+	}
+
+	return &Selection{node: &copied, fragments: e.fragments}, nil
+}
+
+type extractor struct {
+	schema    graphql.Schema
+	fragments []*ast.FragmentDefinition
+	// names holds the fragment names that are taken
+	names map[string]bool
+
+	// occurrences holds the fields with the same type and sub-selection, by key
+	occurrences map[string][]*ast.Field
+	// keys holds the keys of occurrences in the order they were found
+	keys []string
+}
+
+// collect registers the fields of a selection set on type t, and of their sub-selections
+func (e *extractor) collect(set *ast.SelectionSet, t graphql.Type) error {
+	if set == nil {
+		return nil
+	}
+	for _, selection := range set.Selections {
+		switch n := selection.(type) {
+		case *ast.Field:
+			// meta fields such as __schema aren't fields of the schema types
+			if n.SelectionSet == nil || len(n.SelectionSet.Selections) == 0 || strings.HasPrefix(n.Name.Value, "__") {
+				continue
+			}
+			fieldType, err := fieldType(t, n.Name.Value)
+			if err != nil {
+				return err
+			}
+			if !isSingleSpread(n.SelectionSet) && selectionSize(n.SelectionSet) >= minExtractedSize {
+				key := fieldType.Name() + " " + printer.Print(n.SelectionSet).(string)
+				if _, ok := e.occurrences[key]; !ok {
+					e.keys = append(e.keys, key)
+				}
+				e.occurrences[key] = append(e.occurrences[key], n)
+			}
+			if err := e.collect(n.SelectionSet, fieldType); err != nil {
+				return err
+			}
+		case *ast.InlineFragment:
+			condition := t
+			if n.TypeCondition != nil {
+				if condition = e.schema.Type(n.TypeCondition.Name.Value); condition == nil {
+					return fmt.Errorf("fluentgraphql: unknown type %s", n.TypeCondition.Name.Value)
+				}
+			}
+			if err := e.collect(n.SelectionSet, condition); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// extract hoists the largest repeated sub-selection into a fragment, returning false when there is none
+func (e *extractor) extract() bool {
+	var key string
+	for _, k := range e.keys {
+		if len(e.occurrences[k]) > 1 && len(k) > len(key) {
+			key = k
+		}
+	}
+	if key == "" {
+		return false
+	}
+
+	fields := e.occurrences[key]
+	typeName := key[:strings.Index(key, " ")]
+	name := e.fragmentName(typeName)
+	e.fragments = append(e.fragments, ast.NewFragmentDefinition(&ast.FragmentDefinition{
+		Name:          ast.NewName(&ast.Name{Value: name}),
+		TypeCondition: ast.NewNamed(&ast.Named{Name: ast.NewName(&ast.Name{Value: typeName})}),
+		Directives:    make([]*ast.Directive, 0),
+		SelectionSet:  copySelectionSet(fields[0].SelectionSet),
+	}))
+	for _, f := range fields {
+		f.SelectionSet = ast.NewSelectionSet(&ast.SelectionSet{
+			Selections: []ast.Selection{ast.NewFragmentSpread(&ast.FragmentSpread{
+				Name:       ast.NewName(&ast.Name{Value: name}),
+				Directives: make([]*ast.Directive, 0),
+			})},
+		})
+	}
+	return true
+}
+
+// fragmentName returns a fragment name for a type, such as repositoryFields, that isn't taken
+func (e *extractor) fragmentName(typeName string) string {
+	runes := []rune(typeName)
+	runes[0] = unicode.ToLower(runes[0])
+	base := string(runes) + "Fields"
+	name := base
+	for i := 2; e.names[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	e.names[name] = true
+	return name
+}
+
+// fieldType returns the named type of a field of an object or interface type
+func fieldType(t graphql.Type, name string) (graphql.Type, error) {
+	var fields graphql.FieldDefinitionMap
+	switch t := t.(type) {
+	case *graphql.Object:
+		fields = t.Fields()
+	case *graphql.Interface:
+		fields = t.Fields()
+	}
+	f, ok := fields[name]
+	if !ok {
+		return nil, fmt.Errorf("fluentgraphql: unknown field %s on type %s", name, t.Name())
+	}
+	return graphql.GetNamed(f.Type).(graphql.Type), nil
+}
+
+// minExtractedSize is the size of the smallest sub-selections hoisted into fragments
+const minExtractedSize = 2
+
+// selectionSize counts the fields and fragment spreads of a selection set and of its
+// sub-selections, leaving out __typename
+func selectionSize(set *ast.SelectionSet) int {
+	if set == nil {
+		return 0
+	}
+	size := 0
+	for _, selection := range set.Selections {
+		switch n := selection.(type) {
+		case *ast.Field:
+			if n.Name.Value != "__typename" {
+				size++
+			}
+			size += selectionSize(n.SelectionSet)
+		case *ast.InlineFragment:
+			size += selectionSize(n.SelectionSet)
+		case *ast.FragmentSpread:
+			size++
+		}
+	}
+	return size
+}
+
+func isSingleSpread(set *ast.SelectionSet) bool {
+	if len(set.Selections) != 1 {
+		return false
+	}
+	spread, ok := set.Selections[0].(*ast.FragmentSpread)
+	return ok && len(spread.Directives) == 0
+}
+
+func typeConditionName(named *ast.Named) string {
+	if named == nil {
+		return ""
+	}
+	return named.Name.Value
+}
+
+// copySelectionSet copies a selection set deeply enough that its selection sets can be
+// changed without affecting the original
+func copySelectionSet(set *ast.SelectionSet) *ast.SelectionSet {
+	if set == nil {
+		return nil
+	}
+	selections := make([]ast.Selection, 0, len(set.Selections))
+	for _, selection := range set.Selections {
+		switch n := selection.(type) {
+		case *ast.Field:
+			copied := *n
+			copied.SelectionSet = copySelectionSet(n.SelectionSet)
+			selections = append(selections, &copied)
+		case *ast.InlineFragment:
+			copied := *n
+			copied.SelectionSet = copySelectionSet(n.SelectionSet)
+			selections = append(selections, &copied)
+		case *ast.FragmentSpread:
+			copied := *n
+			selections = append(selections, &copied)
+		}
+	}
+	return ast.NewSelectionSet(&ast.SelectionSet{Selections: selections})
+}
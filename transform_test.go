@@ -0,0 +1,183 @@
+package fluentgraphql
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestInlineFragments(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		wanted   string
+		document string
+	}{
+		"InlineFragment": {
+			wanted:   `{ hero { ... on Character { name friends { name } } } }`,
+			document: `{ hero { ...heroFields } } fragment heroFields on Character { name friends { name } }`,
+		},
+		"Transitive": {
+			wanted: `{ hero { ... on Character { name ... on Droid { primaryFunction } } } }`,
+			document: `{ hero { ...heroFields } }
+				fragment heroFields on Character { name ...droidFields }
+				fragment droidFields on Droid { primaryFunction }`,
+		},
+		"SameTypeCondition": {
+			wanted: `{ hero { ... on Character { name id } } }`,
+			document: `{ hero { ...heroFields } }
+				fragment heroFields on Character { name ...idFields }
+				fragment idFields on Character { id }`,
+		},
+		"Directives": {
+			wanted:   `query($withName: Boolean!) { hero { ... on Character @include(if: $withName) { name } } }`,
+			document: `query($withName: Boolean!) { hero { ...heroFields @include(if: $withName) } } fragment heroFields on Character { name }`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			s, err := Parse(testCase.document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			inlined, err := s.InlineFragments()
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			wanted, err := Parse(testCase.wanted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(wanted.String(), inlined.String()); diff != "" {
+				t.Fatal("produced GraphQL query does not match what's wanted", diff)
+			}
+			if inlined.String() == s.String() {
+				t.Fatal("expected the original selection to be left as is")
+			}
+		})
+	}
+}
+
+func TestInlineFragmentsOfBuiltFragments(t *testing.T) {
+	fields := NewFragment("heroFields", "Character")
+	fields.Scalar("name")
+
+	inlined, err := NewQuery().Selection("hero").Spread(fields).Root().InlineFragments()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := queryMatchesTree(t, `{ hero { ... on Character { name } } }`, inlined.node); diff != "" {
+		t.Log("produced GraphQL query does not match what's wanted", diff)
+		t.Fatal()
+	}
+
+	s, err := Parse(`{ hero { ...missing } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.InlineFragments(); err == nil || err.Error() != "fluentgraphql: unknown fragment missing" {
+		t.Fatalf("expected an unknown fragment error, got %v", err)
+	}
+}
+
+func TestExtractFragments(t *testing.T) {
+	s := NewSchema()
+	s.Type("Query").
+		Field("repository", "Repository", WithArgumentDefinitions(NewArgumentDefinition("name", "String!"))).
+		Field("viewer", "User")
+	s.Type("Repository").Field("name", "String!").Field("owner", "User").Field("issues", "[Issue!]!")
+	s.Type("User").Field("login", "String!").Field("url", "String!")
+	s.Type("Issue").Field("title", "String!").Field("author", "User")
+	schema, err := s.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	q, err := Parse(`{
+		a: repository(name: "a") { name owner { login url } issues { title author { login url } } }
+		b: repository(name: "b") { name owner { login url } issues { title author { login url } } }
+		viewer { login url }
+	}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	extracted, err := q.ExtractFragments(schema)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	wanted, err := Parse(`{
+		a: repository(name: "a") { ...repositoryFields }
+		b: repository(name: "b") { ...repositoryFields }
+		viewer { ...userFields }
+	}
+	fragment repositoryFields on Repository { name owner { ...userFields } issues { title author { ...userFields } } }
+	fragment userFields on User { login url }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diff := cmp.Diff(wanted.String(), extracted.String()); diff != "" {
+		t.Fatal("produced GraphQL query does not match what's wanted", diff)
+	}
+	if err := extracted.Validate(schema); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	inlined, err := extracted.InlineFragments()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := inlined.Validate(schema); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestExtractFragmentsSkipped(t *testing.T) {
+	s := NewSchema()
+	s.Type("Query").
+		Field("repository", "Repository", WithArgumentDefinitions(NewArgumentDefinition("name", "String!"))).
+		Field("viewer", "User")
+	s.Type("Repository").Field("owner", "User")
+	s.Type("User").Field("id", "ID!").Field("login", "String!")
+	schema, err := s.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, testCase := range map[string]struct {
+		query, wanted string
+	}{
+		"SingleField": {
+			query: `{
+				a: repository(name: "a") { owner { __typename id } }
+				b: repository(name: "b") { owner { __typename id } }
+				viewer { __typename id }
+			}`,
+			wanted: `{
+				a: repository(name: "a") { ...repositoryFields }
+				b: repository(name: "b") { ...repositoryFields }
+				viewer { __typename id }
+			}
+			fragment repositoryFields on Repository { owner { __typename id } }`,
+		},
+		"MetaFields": {
+			query:  NewIntrospectionQuery().String(),
+			wanted: NewIntrospectionQuery().String(),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			q, err := Parse(testCase.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			extracted, err := q.ExtractFragments(schema)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			wanted, err := Parse(testCase.wanted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(wanted.String(), extracted.String()); diff != "" {
+				t.Fatal("produced GraphQL query does not match what's wanted", diff)
+			}
+		})
+	}
+}
@@ -0,0 +1,236 @@
+package fluentgraphql
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// typenamer adds __typename, and optionally a key field, to the selection sets of objects
+type typenamer struct {
+	schema   *graphql.Schema
+	keyField string
+}
+
+type typenameOption func(*typenamer)
+
+// WithSchemaKeyField also adds a key field, such as id, to the selection sets of the
+// types of the schema that have such a field, unless they select it under its own name.
+// Adding it fails when another field is aliased to its name, such as id: databaseId.
+func WithSchemaKeyField(schema graphql.Schema, field string) typenameOption {
+	return func(t *typenamer) {
+		t.schema = &schema
+		t.keyField = field
+	}
+}
+
+// WithTypenames adds __typename to every field with a sub-selection and to every inline
+// fragment and fragment definition of the operation when it is printed, as normalized
+// caches and decoding unions and interfaces need, unless they already select it
+func WithTypenames(options ...typenameOption) OperationOption {
+	return func(s *Selection) {
+		t := &typenamer{}
+		for _, option := range options {
+			option(t)
+		}
+		s.typenames = t
+	}
+}
+
+// AddTypenames returns a copy of an operation or fragment definition, along with the
+// fragments it spreads, with __typename added to every field with a sub-selection and to
+// every inline fragment and fragment definition, unless they already select it.
+// The fields of the root operation type are left as they are.
+func (s *Selection) AddTypenames(options ...typenameOption) (*Selection, error) {
+	t := &typenamer{}
+	for _, option := range options {
+		option(t)
+	}
+	return t.apply(s)
+}
+
+// typed returns the selection with the typenames asked for by WithTypenames added, or the
+// selection itself when none were
+func (s *Selection) typed() (*Selection, error) {
+	if s.typenames == nil {
+		return s, nil
+	}
+	return s.typenames.apply(s)
+}
+
+func (t *typenamer) apply(s *Selection) (*Selection, error) {
+	definitions, err := s.definitions()
+	if err != nil {
+		return nil, err
+	}
+
+	var node ast.Node
+	switch n := s.node.(type) {
+	case *ast.OperationDefinition:
+		copied := *n
+		copied.SelectionSet = copySelectionSet(n.SelectionSet)
+		root, err := t.rootType(n.Operation)
+		if err != nil {
+			return nil, err
+		}
+		if err := t.selectionSet(copied.SelectionSet, root); err != nil {
+			return nil, err
+		}
+		node = &copied
+	case *ast.FragmentDefinition:
+		copied, err := t.fragment(n)
+		if err != nil {
+			return nil, err
+		}
+		node = copied
+	default:
+		return nil, fmt.Errorf("fluentgraphql: cannot add typenames to a %s", s.node.GetKind())
+	}
+
+	var fragments []*ast.FragmentDefinition
+	for _, def := range definitions[1:] {
+		copied, err := t.fragment(def.(*ast.FragmentDefinition))
+		if err != nil {
+			return nil, err
+		}
+		fragments = append(fragments, copied)
+	}
+	return &Selection{node: node, fragments: fragments}, nil
+}
+
+// rootType returns the root type of an operation, or nil without a schema
+func (t *typenamer) rootType(operation string) (graphql.Type, error) {
+	if t.schema == nil {
+		return nil, nil
+	}
+	var root *graphql.Object
+	switch operation {
+	case ast.OperationTypeQuery:
+		root = t.schema.QueryType()
+	case ast.OperationTypeMutation:
+		root = t.schema.MutationType()
+	case ast.OperationTypeSubscription:
+		root = t.schema.SubscriptionType()
+	}
+	if root == nil {
+		return nil, fmt.Errorf("fluentgraphql: schema has no %s type", operation)
+	}
+	return root, nil
+}
+
+// fragment returns a copy of a fragment definition with typenames added
+func (t *typenamer) fragment(f *ast.FragmentDefinition) (*ast.FragmentDefinition, error) {
+	copied := *f
+	copied.SelectionSet = copySelectionSet(f.SelectionSet)
+	condition, err := t.conditionType(f.TypeCondition, nil)
+	if err != nil {
+		return nil, err
+	}
+	if err := t.selectionSet(copied.SelectionSet, condition); err != nil {
+		return nil, err
+	}
+	if copied.SelectionSet, err = t.withTypename(copied.SelectionSet, condition); err != nil {
+		return nil, err
+	}
+	return &copied, nil
+}
+
+// selectionSet adds typenames to the sub-selections of a selection set on type typ,
+// which is nil without a schema
+func (t *typenamer) selectionSet(set *ast.SelectionSet, typ graphql.Type) error {
+	if set == nil {
+		return nil
+	}
+	for _, selection := range set.Selections {
+		switch n := selection.(type) {
+		case *ast.Field:
+			if n.SelectionSet == nil {
+				continue
+			}
+			var sub graphql.Type
+			// the types of introspection fields aren't among those of the schema
+			if typ != nil && !strings.HasPrefix(n.Name.Value, "__") {
+				var err error
+				if sub, err = fieldType(typ, n.Name.Value); err != nil {
+					return err
+				}
+			}
+			if err := t.selectionSet(n.SelectionSet, sub); err != nil {
+				return err
+			}
+			set, err := t.withTypename(n.SelectionSet, sub)
+			if err != nil {
+				return err
+			}
+			n.SelectionSet = set
+		case *ast.InlineFragment:
+			condition, err := t.conditionType(n.TypeCondition, typ)
+			if err != nil {
+				return err
+			}
+			if err := t.selectionSet(n.SelectionSet, condition); err != nil {
+				return err
+			}
+			if n.SelectionSet, err = t.withTypename(n.SelectionSet, condition); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// conditionType returns the type of a type condition, or typ without one
+func (t *typenamer) conditionType(condition *ast.Named, typ graphql.Type) (graphql.Type, error) {
+	if condition == nil || t.schema == nil {
+		return typ, nil
+	}
+	named := t.schema.Type(condition.Name.Value)
+	if named == nil {
+		return nil, fmt.Errorf("fluentgraphql: unknown type %s", condition.Name.Value)
+	}
+	return named, nil
+}
+
+// withTypename returns the selection set with __typename, and the key field when typ has
+// it, prepended unless they are already selected under their own name. Another field
+// aliased to their name, such as id: databaseId, is an error, as they can't be added
+// without conflicting with it.
+func (t *typenamer) withTypename(set *ast.SelectionSet, typ graphql.Type) (*ast.SelectionSet, error) {
+	// keys maps the response keys of the fields to their names
+	keys := make(map[string]string)
+	for _, selection := range set.Selections {
+		if f, ok := selection.(*ast.Field); ok {
+			keys[responseKey(f)] = f.Name.Value
+		}
+	}
+
+	names := []string{"__typename"}
+	if t.keyField != "" && typ != nil {
+		if _, err := fieldType(typ, t.keyField); err == nil {
+			names = append(names, t.keyField)
+		}
+	}
+	var added []ast.Selection
+	for _, name := range names {
+		switch field, ok := keys[name]; {
+		case !ok:
+			added = append(added, newField(name))
+		case field != name:
+			return nil, fmt.Errorf("fluentgraphql: cannot add %s, as %s is aliased to it", name, field)
+		}
+	}
+	if len(added) == 0 {
+		return set, nil
+	}
+	return ast.NewSelectionSet(&ast.SelectionSet{Selections: append(added, set.Selections...)}), nil
+}
+
+func newField(name string) *ast.Field {
+	return ast.NewField(&ast.Field{
+		Name:       ast.NewName(&ast.Name{Value: name}),
+		Arguments:  make([]*ast.Argument, 0),
+		Directives: make([]*ast.Directive, 0),
+	})
+}
@@ -0,0 +1,147 @@
+package fluentgraphql
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestAddTypenames(t *testing.T) {
+	s := NewSchema()
+	s.Type("Query").
+		Field("repository", "Repository", WithArgumentDefinitions(NewArgumentDefinition("name", "String!"))).
+		Field("search", "[SearchResult!]!").
+		Field("node", "Node", WithArgumentDefinitions(NewArgumentDefinition("id", "ID!")))
+	s.Interface("Node").Field("id", "ID!")
+	s.Type("Repository", WithInterfaces("Node")).Field("id", "ID!").Field("name", "String!").Field("owner", "User").Field("license", "License")
+	s.Type("User", WithInterfaces("Node")).Field("id", "ID!").Field("login", "String!")
+	s.Type("License").Field("key", "String!")
+	s.Union("SearchResult", []string{"Repository", "User"})
+	schema, err := s.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, testCase := range map[string]struct {
+		options  []typenameOption
+		document string
+		wanted   string
+	}{
+		"Typenames": {
+			document: `{ repository(name: "fluentgraphql") { name owner { login } } }`,
+			wanted:   `{ repository(name: "fluentgraphql") { __typename name owner { __typename login } } }`,
+		},
+		"AlreadySelected": {
+			options:  []typenameOption{WithSchemaKeyField(schema, "id")},
+			document: `{ repository(name: "fluentgraphql") { id __typename owner { kind: __typename login } } }`,
+			wanted:   `{ repository(name: "fluentgraphql") { id __typename owner { __typename id kind: __typename login } } }`,
+		},
+		"AliasedKeyField": {
+			options:  []typenameOption{WithSchemaKeyField(schema, "id")},
+			document: `{ repository(name: "fluentgraphql") { nodeId: id name } }`,
+			wanted:   `{ repository(name: "fluentgraphql") { __typename id nodeId: id name } }`,
+		},
+		"KeyFields": {
+			options:  []typenameOption{WithSchemaKeyField(schema, "id")},
+			document: `{ repository(name: "fluentgraphql") { name license { key } } search { ... on User { login } ... on Repository { name } } }`,
+			wanted: `{
+				repository(name: "fluentgraphql") { __typename id name license { __typename key } }
+				search { __typename ... on User { __typename id login } ... on Repository { __typename id name } }
+			}`,
+		},
+		"Fragments": {
+			options:  []typenameOption{WithSchemaKeyField(schema, "id")},
+			document: `{ node(id: "R1") { ...nodeFields } } fragment nodeFields on Node { ... on Repository { owner { login } } }`,
+			wanted: `{ node(id: "R1") { __typename id ...nodeFields } }
+				fragment nodeFields on Node { __typename id ... on Repository { __typename id owner { __typename id login } } }`,
+		},
+		"Introspection": {
+			options:  []typenameOption{WithSchemaKeyField(schema, "id")},
+			document: `{ __type(name: "Repository") { name fields { name } } }`,
+			wanted:   `{ __type(name: "Repository") { __typename name fields { __typename name } } }`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			q, err := Parse(testCase.document)
+			if err != nil {
+				t.Fatal(err)
+			}
+			typed, err := q.AddTypenames(testCase.options...)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			wanted, err := Parse(testCase.wanted)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if diff := cmp.Diff(wanted.String(), typed.String()); diff != "" {
+				t.Fatal("produced GraphQL query does not match what's wanted", diff)
+			}
+			if typed.String() == q.String() {
+				t.Fatal("expected the original selection to be left as is")
+			}
+		})
+	}
+}
+
+func TestAddTypenamesErrors(t *testing.T) {
+	schema, err := NewSchema().Type("Query").Field("viewer", "User").Schema().Type("User").Field("login", "String!").Schema().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q := NewQuery().Selection("viewer").Selection("repositories").Scalar("name").Root()
+	if _, err := q.AddTypenames(WithSchemaKeyField(schema, "id")); err == nil {
+		t.Error("expected an error for a field missing from the schema")
+	}
+	if _, err := NewMutation().Selection("addStar").Scalar("id").Root().AddTypenames(WithSchemaKeyField(schema, "id")); err == nil {
+		t.Error("expected an error for a schema without a mutation type")
+	}
+
+	keyed, err := NewSchema().Type("Query").Field("viewer", "User").Schema().Type("User").Field("id", "ID!").Field("databaseId", "Int!").Schema().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	q = NewQuery().Selection("viewer").Scalar("databaseId", WithAlias("id")).Root()
+	_, err = q.AddTypenames(WithSchemaKeyField(keyed, "id"))
+	if err == nil || err.Error() != "fluentgraphql: cannot add id, as databaseId is aliased to it" {
+		t.Errorf("expected an error for a field aliased to the key field, got %v", err)
+	}
+}
+
+func TestWithTypenames(t *testing.T) {
+	fields := NewFragment("ownerFields", "User")
+	fields.Scalar("login")
+	q := NewQuery(WithName("Repo"), WithTypenames()).
+		Selection("repository").Scalar("name").Selection("owner").Spread(fields).
+		Root()
+
+	wanted := `query Repo {
+  repository {
+    __typename
+    name
+    owner {
+      __typename
+      ...ownerFields
+    }
+  }
+}
+
+fragment ownerFields on User {
+  __typename
+  login
+}`
+	if diff := cmp.Diff(wanted, q.String()); diff != "" {
+		t.Fatal("produced GraphQL query does not match what's wanted", diff)
+	}
+	if diff := cmp.Diff("query Repo{repository{__typename name owner{__typename...ownerFields}}}fragment ownerFields on User{__typename login}", q.Minified()); diff != "" {
+		t.Fatal("produced GraphQL query does not match what's wanted", diff)
+	}
+
+	inlined, err := q.InlineFragments()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if diff := cmp.Diff("query Repo{repository{__typename name owner{__typename...on User{__typename login}}}}", inlined.Minified()); diff != "" {
+		t.Fatal("produced GraphQL query does not match what's wanted", diff)
+	}
+}
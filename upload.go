@@ -0,0 +1,277 @@
+package fluentgraphql
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// Upload is a file sent along with an operation, following the GraphQL multipart request
+// specification. As files can't be written as literals, uploads are given in the variables
+// of a request, anywhere in their values, and arguments and object values refer to them
+// with NewVariableValue, or they are used as values with NewUploadValue. Values are walked as they are encoded as JSON: uploads are found
+// within slices, maps and the exported fields of structs, while sending a request fails
+// when an upload is out of reach, such as in an unexported field or a value with its
+// own MarshalJSON. The reader is consumed when the request is sent.
+type Upload struct {
+	Reader      io.Reader
+	Filename    string
+	ContentType string
+}
+
+// NewUpload returns an upload of the content of r as a file with the given name, its
+// content type guessed from the extension of the name
+func NewUpload(r io.Reader, filename string) *Upload {
+	contentType := mime.TypeByExtension(filepath.Ext(filename))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return &Upload{Reader: r, Filename: filename, ContentType: contentType}
+}
+
+// boundUpload is an upload used as a value, standing for the variable it is bound to once
+// the value is used in the arguments of a field
+type boundUpload struct {
+	upload   *Upload
+	variable *ast.Variable
+}
+
+// NewUploadValue returns a value for an upload, which can be used in arguments and within
+// object and list values in place of a variable. Once used in the arguments of a field,
+// the upload is bound to a variable of type Upload! declared on the operation, named after
+// the field and argument, and NewRequest adds the upload to the variables of the request.
+// Fragments can't declare variables: within a fragment built with NewFragment, declare
+// the variable on the operation and give the upload as a variable of the request instead.
+//
+//	q.Selection("uploadLog", WithArguments(NewArgument("input", NewObjectValue(
+//		NewObjectValueField("file", NewUploadValue(NewUpload(f, "build.log"))),
+//	))))
+//	// mutation($uploadLog_input: Upload!) { uploadLog(input: {file: $uploadLog_input}) { ... } }
+func NewUploadValue(upload *Upload) *Value {
+	variable := ast.NewVariable(&ast.Variable{Name: ast.NewName(&ast.Name{})})
+	return &Value{astValue: variable, uploads: []*boundUpload{{upload: upload, variable: variable}}}
+}
+
+// bindUploads binds the uploads used within the value of an argument of the field of s to
+// variables declared on its operation, numbered when their names are taken
+func (s *Selection) bindUploads(argName string, uploads []*boundUpload) {
+	if len(uploads) == 0 {
+		return
+	}
+	root := s.Root()
+	op, _ := root.node.(*ast.OperationDefinition)
+	if root.uploads == nil {
+		root.uploads = make(map[string]*Upload)
+	}
+	base := strings.Join(append(s.path(), argName), "_")
+	for _, b := range uploads {
+		if b.variable.Name.Value != "" {
+			// the value was bound already, by another argument using it
+			continue
+		}
+		name := base
+		for i := 2; declaresVariable(op, name) || root.uploads[name] != nil; i++ {
+			name = fmt.Sprintf("%s_%d", base, i)
+		}
+		b.variable.Name.Value = name
+		root.uploads[name] = b.upload
+		if op != nil {
+			op.VariableDefinitions = append(op.VariableDefinitions, NewVariableDefinition(name, "Upload", true, nil).astVarDef)
+		}
+	}
+}
+
+// MarshalJSON encodes the upload as null, which servers replace with the file
+func (u *Upload) MarshalJSON() ([]byte, error) {
+	return []byte("null"), nil
+}
+
+// uploadPaths are the uploads of a request, with the paths of the variables holding them
+type uploadPaths struct {
+	uploads []*Upload
+	paths   map[*Upload][]string
+	// err reports an upload found where its path in the JSON variables isn't known
+	err error
+	// walking holds the pointers and maps being walked, which cycles lead back to
+	walking map[uintptr]bool
+}
+
+var (
+	uploadType    = reflect.TypeOf(&Upload{})
+	marshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+)
+
+// findUploads returns the uploads found in the variables, in the order of their paths
+func findUploads(variables map[string]interface{}) *uploadPaths {
+	u := &uploadPaths{paths: make(map[*Upload][]string), walking: make(map[uintptr]bool)}
+	u.find("variables", reflect.ValueOf(variables), "")
+	return u
+}
+
+// find records the uploads within value, found at path in the JSON encoding of the
+// variables. Within a value out of reach, which unreachable describes, uploads are errors.
+func (u *uploadPaths) find(path string, value reflect.Value, unreachable string) {
+	if u.err != nil || !value.IsValid() {
+		return
+	}
+	if value.Type() == uploadType {
+		if value.IsNil() {
+			return
+		}
+		if unreachable != "" {
+			u.err = fmt.Errorf("fluentgraphql: upload at %s in %s, which can't be sent", path, unreachable)
+			return
+		}
+		upload := value.Interface().(*Upload)
+		if _, ok := u.paths[upload]; !ok {
+			u.uploads = append(u.uploads, upload)
+		}
+		u.paths[upload] = append(u.paths[upload], path)
+		return
+	}
+	if unreachable == "" && value.Type().Implements(marshalerType) {
+		unreachable = "a " + value.Type().String() + " with its own MarshalJSON"
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Map:
+		if value.IsNil() || u.walking[value.Pointer()] {
+			return
+		}
+		u.walking[value.Pointer()] = true
+		defer delete(u.walking, value.Pointer())
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			u.find(path, value.Elem(), unreachable)
+		}
+	case reflect.Slice, reflect.Array:
+		if value.Type().Elem().Kind() == reflect.Uint8 {
+			// bytes are encoded as a base64 string
+			return
+		}
+		for i := 0; i < value.Len(); i++ {
+			u.find(path+"."+strconv.Itoa(i), value.Index(i), unreachable)
+		}
+	case reflect.Map:
+		keys := make([]string, 0, value.Len())
+		values := make(map[string]reflect.Value, value.Len())
+		for _, key := range value.MapKeys() {
+			var k string
+			switch key.Kind() {
+			case reflect.String:
+				k = key.String()
+			case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+				k = strconv.FormatInt(key.Int(), 10)
+			case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				k = strconv.FormatUint(key.Uint(), 10)
+			default:
+				if unreachable == "" {
+					unreachable = "a map with keys of type " + key.Type().String()
+				}
+				k = strconv.Itoa(len(keys))
+			}
+			keys = append(keys, k)
+			values[k] = value.MapIndex(key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			u.find(path+"."+key, values[key], unreachable)
+		}
+	case reflect.Struct:
+		t := value.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			switch {
+			case f.Tag.Get("json") == "-":
+				continue
+			case f.PkgPath != "":
+				if unreachable == "" {
+					u.find(path+"."+f.Name, value.Field(i), "the unexported field "+f.Name+" of a "+t.String())
+				}
+				continue
+			case f.Anonymous && name == "" && indirect(f.Type).Kind() == reflect.Struct:
+				// the fields of embedded structs are promoted
+				u.find(path, value.Field(i), unreachable)
+				continue
+			case name == "":
+				name = f.Name
+			}
+			u.find(path+"."+name, value.Field(i), unreachable)
+		}
+	}
+}
+
+// indirect returns the type t points to, or t if it isn't a pointer
+func indirect(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// sendableVariables returns an error when the variables hold uploads, which transports
+// sending JSON messages, rather than multipart requests, can't carry
+func sendableVariables(variables map[string]interface{}, transport string) error {
+	if uploads := findUploads(variables); len(uploads.uploads) > 0 || uploads.err != nil {
+		return fmt.Errorf("fluentgraphql: uploads can't be sent over %s", transport)
+	}
+	return nil
+}
+
+// writeMultipart writes the operations, map and files fields of a multipart request
+// with the given JSON body
+func (u *uploadPaths) writeMultipart(w *multipart.Writer, body map[string]interface{}) error {
+	operations, err := w.CreateFormField("operations")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(operations).Encode(body); err != nil {
+		return err
+	}
+
+	fileMap := make(map[string][]string, len(u.uploads))
+	for i, upload := range u.uploads {
+		fileMap[strconv.Itoa(i)] = u.paths[upload]
+	}
+	m, err := w.CreateFormField("map")
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(m).Encode(fileMap); err != nil {
+		return err
+	}
+
+	for i, upload := range u.uploads {
+		header := make(textproto.MIMEHeader)
+		header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%d"; filename="%s"`, i, escapeQuotes(upload.Filename)))
+		header.Set("Content-Type", upload.ContentType)
+		part, err := w.CreatePart(header)
+		if err != nil {
+			return err
+		}
+		if _, err := io.Copy(part, upload.Reader); err != nil {
+			return fmt.Errorf("fluentgraphql: reading upload %s: %w", upload.Filename, err)
+		}
+	}
+	return w.Close()
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
@@ -0,0 +1,296 @@
+package fluentgraphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestUpload(t *testing.T) {
+	q := NewMutation(WithVariableDefinitions(
+		NewVariableDefinition("log", "Upload", true, nil),
+		NewVariableDefinition("input", "ArtifactsInput", true, nil),
+	)).
+		Selection("addArtifacts", WithArguments(
+			NewArgument("log", NewVariableValue("log")),
+			NewArgument("input", NewObjectValue(NewObjectValueField("run", NewVariableValue("input")))),
+		)).
+		Scalar("count").
+		Root()
+
+	log := NewUpload(strings.NewReader("build ok\n"), "build")
+	image := NewUpload(strings.NewReader("\x89PNG"), "screen \"1\".png")
+	variables := map[string]interface{}{
+		"log": log,
+		"input": map[string]interface{}{
+			"id":     42,
+			"images": []*Upload{image},
+			"all":    []interface{}{log, image},
+		},
+	}
+
+	type file struct {
+		Filename, ContentType, Content string
+	}
+	var operations map[string]interface{}
+	var fileMap map[string][]string
+	files := make(map[string]file)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+			return
+		}
+		json.Unmarshal([]byte(r.FormValue("operations")), &operations)
+		json.Unmarshal([]byte(r.FormValue("map")), &fileMap)
+		for name, headers := range r.MultipartForm.File {
+			f, _ := headers[0].Open()
+			b, _ := ioutil.ReadAll(f)
+			files[name] = file{headers[0].Filename, headers[0].Header.Get("Content-Type"), string(b)}
+		}
+		io.WriteString(w, `{"data":{"addArtifacts":{"count":2}}}`)
+	}))
+	defer server.Close()
+
+	response, err := NewHTTPExecutor(server.URL).Execute(context.Background(), NewRequest(q, variables))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(response.Data) != `{"addArtifacts":{"count":2}}` {
+		t.Errorf("unexpected data %s", response.Data)
+	}
+
+	expectedOperations := map[string]interface{}{
+		"query": q.String(),
+		"variables": map[string]interface{}{
+			"log": nil,
+			"input": map[string]interface{}{
+				"id":     float64(42),
+				"images": []interface{}{nil},
+				"all":    []interface{}{nil, nil},
+			},
+		},
+	}
+	if diff := cmp.Diff(expectedOperations, operations); diff != "" {
+		t.Errorf("unexpected operations (-want +got):\n%s", diff)
+	}
+	expectedMap := map[string][]string{
+		"0": {"variables.input.all.0", "variables.log"},
+		"1": {"variables.input.all.1", "variables.input.images.0"},
+	}
+	if diff := cmp.Diff(expectedMap, fileMap); diff != "" {
+		t.Errorf("unexpected map (-want +got):\n%s", diff)
+	}
+	expectedFiles := map[string]file{
+		"0": {"build", "application/octet-stream", "build ok\n"},
+		"1": {"screen \"1\".png", "image/png", "\x89PNG"},
+	}
+	if diff := cmp.Diff(expectedFiles, files); diff != "" {
+		t.Errorf("unexpected files (-want +got):\n%s", diff)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("disk failure")
+}
+
+func TestUploadValue(t *testing.T) {
+	log := NewUpload(strings.NewReader("build ok\n"), "build.log")
+	image := NewUpload(strings.NewReader("\x89PNG"), "screen.png")
+	q := NewMutation(WithVariableDefinitions(NewVariableDefinition("addArtifacts_log", "String", false, nil))).
+		Selection("addArtifacts", WithArguments(
+			NewArgument("log", NewUploadValue(log)),
+			NewArgument("input", NewObjectValue(
+				NewObjectValueField("id", NewIntValue(42)),
+				NewObjectValueField("images", NewListValue(NewUploadValue(image))),
+			)),
+		)).
+		Scalar("count").
+		Root()
+
+	wanted := `mutation($addArtifacts_log: String, $addArtifacts_log_2: Upload!, $addArtifacts_input: Upload!) {
+		addArtifacts(log: $addArtifacts_log_2, input: {id: 42, images: [$addArtifacts_input]}) { count }
+	}`
+	if diff := queryMatchesTree(t, wanted, q.node); diff != "" {
+		t.Fatal("produced GraphQL query does not match what's wanted", diff)
+	}
+
+	var operations map[string]interface{}
+	var fileMap map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Error(err)
+			return
+		}
+		json.Unmarshal([]byte(r.FormValue("operations")), &operations)
+		json.Unmarshal([]byte(r.FormValue("map")), &fileMap)
+		io.WriteString(w, `{"data":{"addArtifacts":{"count":2}}}`)
+	}))
+	defer server.Close()
+
+	if _, err := NewHTTPExecutor(server.URL).Execute(context.Background(), NewRequest(q, map[string]interface{}{"addArtifacts_log": "build"})); err != nil {
+		t.Fatal(err)
+	}
+	expectedVariables := map[string]interface{}{
+		"addArtifacts_log":   "build",
+		"addArtifacts_log_2": nil,
+		"addArtifacts_input": nil,
+	}
+	if diff := cmp.Diff(expectedVariables, operations["variables"]); diff != "" {
+		t.Errorf("unexpected variables (-want +got):\n%s", diff)
+	}
+	expectedMap := map[string][]string{
+		"0": {"variables.addArtifacts_input"},
+		"1": {"variables.addArtifacts_log_2"},
+	}
+	if diff := cmp.Diff(expectedMap, fileMap); diff != "" {
+		t.Errorf("unexpected map (-want +got):\n%s", diff)
+	}
+}
+
+func TestUploadReadError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		io.WriteString(w, `{"data":{}}`)
+	}))
+	defer server.Close()
+
+	q := NewMutation().Scalar("upload", WithArguments(NewArgument("file", NewVariableValue("file")))).Root()
+	_, err := NewHTTPExecutor(server.URL).Execute(context.Background(), NewRequest(q, map[string]interface{}{
+		"file": NewUpload(failingReader{}, "broken.bin"),
+	}))
+	if err == nil || !strings.Contains(err.Error(), "fluentgraphql: reading upload broken.bin: disk failure") {
+		t.Errorf("expected the read error, got %v", err)
+	}
+}
+
+type artifactInput struct {
+	Name   string    `json:"name"`
+	Log    *Upload   `json:"log,omitempty"`
+	Images []*Upload `json:"images"`
+	Skip   *Upload   `json:"-"`
+	ArtifactMetadata
+}
+
+// ArtifactMetadata is exported for its fields to be promoted, as encoding/json does
+type ArtifactMetadata struct {
+	Attachment *Upload
+}
+
+type hiddenUpload struct {
+	upload *Upload
+}
+
+type encodedUpload struct {
+	Upload *Upload
+}
+
+func (encodedUpload) MarshalJSON() ([]byte, error) {
+	return []byte(`"encoded"`), nil
+}
+
+func TestFindUploads(t *testing.T) {
+	log := NewUpload(strings.NewReader("build ok\n"), "build.log")
+	image := NewUpload(strings.NewReader("\x89PNG"), "screen.png")
+	cyclic := map[string]interface{}{"log": log}
+	cyclic["self"] = cyclic
+
+	type test struct {
+		name      string
+		variables map[string]interface{}
+		// paths are the paths of each upload found, and err the error expected instead
+		paths [][]string
+		err   string
+	}
+
+	var tests = []test{
+		{
+			name: "Struct",
+			variables: map[string]interface{}{"input": &artifactInput{
+				Log:              log,
+				Images:           []*Upload{image, nil},
+				Skip:             image,
+				ArtifactMetadata: ArtifactMetadata{Attachment: log},
+			}},
+			paths: [][]string{{"variables.input.log", "variables.input.Attachment"}, {"variables.input.images.0"}},
+		},
+		{
+			name: "TypedCollections",
+			variables: map[string]interface{}{
+				"byName":  map[string]*Upload{"b": image, "a": log},
+				"objects": []map[string]interface{}{{"file": log}},
+				"byIndex": map[int][]*Upload{1: {image}},
+			},
+			paths: [][]string{{"variables.byIndex.1.0", "variables.byName.b"}, {"variables.byName.a", "variables.objects.0.file"}},
+		},
+		{
+			name:      "Cycle",
+			variables: map[string]interface{}{"input": cyclic},
+			paths:     [][]string{{"variables.input.log"}},
+		},
+		{
+			name:      "UnexportedField",
+			variables: map[string]interface{}{"input": hiddenUpload{upload: log}},
+			err:       "fluentgraphql: upload at variables.input.upload in the unexported field upload of a fluentgraphql.hiddenUpload, which can't be sent",
+		},
+		{
+			name:      "Marshaler",
+			variables: map[string]interface{}{"input": []encodedUpload{{Upload: log}}},
+			err:       "fluentgraphql: upload at variables.input.0.Upload in a fluentgraphql.encodedUpload with its own MarshalJSON, which can't be sent",
+		},
+		{
+			name:      "MapKeys",
+			variables: map[string]interface{}{"input": map[[2]int]*Upload{{1, 2}: log}},
+			err:       "fluentgraphql: upload at variables.input.0 in a map with keys of type [2]int, which can't be sent",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			u := findUploads(test.variables)
+			if test.err != "" {
+				if u.err == nil || u.err.Error() != test.err {
+					t.Fatalf("expected error %q, got %v", test.err, u.err)
+				}
+				return
+			}
+			if u.err != nil {
+				t.Fatalf("unexpected error: %v", u.err)
+			}
+			paths := make([][]string, 0, len(u.uploads))
+			for _, upload := range u.uploads {
+				paths = append(paths, u.paths[upload])
+			}
+			if diff := cmp.Diff(test.paths, paths); diff != "" {
+				t.Errorf("unexpected paths (-want +got):\n%s", diff)
+			}
+		})
+	}
+}
+
+func TestUploadUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("unexpected request")
+	}))
+	defer server.Close()
+
+	q := NewMutation().Scalar("upload", WithArguments(NewArgument("input", NewVariableValue("input")))).Root()
+	variables := map[string]interface{}{"input": hiddenUpload{upload: NewUpload(strings.NewReader("content"), "a.txt")}}
+	if _, err := NewHTTPExecutor(server.URL).Execute(context.Background(), NewRequest(q, variables)); err == nil || !strings.Contains(err.Error(), "variables.input.upload") {
+		t.Errorf("expected an error for the unreachable upload, got %v", err)
+	}
+
+	variables = map[string]interface{}{"file": NewUpload(strings.NewReader("content"), "a.txt")}
+	if _, err := NewSSEExecutor(server.URL).Execute(context.Background(), NewRequest(q, variables)); err == nil || !strings.Contains(err.Error(), "uploads can't be sent over server-sent events") {
+		t.Errorf("expected an error sending an upload over server-sent events, got %v", err)
+	}
+}
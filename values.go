@@ -5,11 +5,14 @@ import (
 	"strconv"
 
 	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/printer"
 )
 
 // Value represents a GraphQL value
 type Value struct {
 	astValue ast.Value
+	// uploads are the uploads used within the value, see NewUploadValue
+	uploads []*boundUpload
 }
 
 // NewIntValue returns an integer value
@@ -60,45 +63,52 @@ func NewEnumValue(val string) *Value {
 // NewListValue returns a list value
 func NewListValue(values ...*Value) *Value {
 	vals := make([]ast.Value, 0, len(values))
+	var uploads []*boundUpload
 	for _, v := range values {
 		vals = append(vals, v.astValue)
+		uploads = append(uploads, v.uploads...)
 	}
 
 	return &Value{
 		astValue: ast.NewListValue(&ast.ListValue{
 			Values: vals,
 		}),
+		uploads: uploads,
 	}
 }
 
-type objectValueField struct {
+// ObjectValueField is a field of an object value
+type ObjectValueField struct {
 	fieldName string
 	value     *Value
 }
 
 // NewObjectValueField returns a field for an object value
-func NewObjectValueField(fieldName string, value *Value) *objectValueField {
-	return &objectValueField{
+func NewObjectValueField(fieldName string, value *Value) *ObjectValueField {
+	return &ObjectValueField{
 		fieldName: fieldName,
 		value:     value,
 	}
 }
 
 // NewObjectValue returns an object value
-func NewObjectValue(values ...*objectValueField) *Value {
+func NewObjectValue(values ...*ObjectValueField) *Value {
 	fields := make([]*ast.ObjectField, 0, len(values))
+	var uploads []*boundUpload
 
 	for _, f := range values {
 		fields = append(fields, ast.NewObjectField(&ast.ObjectField{
 			Name:  ast.NewName(&ast.Name{Value: f.fieldName}),
 			Value: f.value.astValue,
 		}))
+		uploads = append(uploads, f.value.uploads...)
 	}
 
 	return &Value{
 		astValue: ast.NewObjectValue(&ast.ObjectValue{
 			Fields: fields,
 		}),
+		uploads: uploads,
 	}
 }
 
@@ -110,3 +120,55 @@ func NewVariableValue(name string) *Value {
 		}),
 	}
 }
+
+// Kind returns the kind of the value, one of the graphql-go kinds such as IntValue, ListValue or Variable
+func (v *Value) Kind() string {
+	return v.astValue.GetKind()
+}
+
+// Interface returns the value as a Go value: an int, float64, string (for strings and
+// enums), bool, []interface{} or map[string]interface{}. Variables have no value
+// until the operation is sent, so they are returned as nil.
+func (v *Value) Interface() interface{} {
+	return goValue(v.astValue, nil)
+}
+
+// String returns the value as a GraphQL literal
+func (v *Value) String() string {
+	return printer.Print(v.astValue).(string)
+}
+
+// goValue converts a value literal into its Go representation, looking up variables in vars
+func goValue(value ast.Value, vars map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case *ast.IntValue:
+		if i, err := strconv.ParseInt(v.Value, 10, 64); err == nil {
+			return int(i)
+		}
+	case *ast.FloatValue:
+		if f, err := strconv.ParseFloat(v.Value, 64); err == nil {
+			return f
+		}
+	case *ast.StringValue:
+		return v.Value
+	case *ast.BooleanValue:
+		return v.Value
+	case *ast.EnumValue:
+		return v.Value
+	case *ast.ListValue:
+		list := make([]interface{}, 0, len(v.Values))
+		for _, item := range v.Values {
+			list = append(list, goValue(item, vars))
+		}
+		return list
+	case *ast.ObjectValue:
+		object := make(map[string]interface{}, len(v.Fields))
+		for _, f := range v.Fields {
+			object[f.Name.Value] = goValue(f.Value, vars)
+		}
+		return object
+	case *ast.Variable:
+		return vars[v.Name.Value]
+	}
+	return nil
+}
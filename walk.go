@@ -0,0 +1,260 @@
+package fluentgraphql
+
+import (
+	"strconv"
+
+	"github.com/graphql-go/graphql/language/ast"
+)
+
+// WalkAction tells Walk how to continue after entering a node
+type WalkAction int
+
+const (
+	// Continue walks the children of the node
+	Continue WalkAction = iota
+	// SkipChildren leaves the node without walking its children
+	SkipChildren
+)
+
+// Visitor is called by Walk when entering and leaving each node of a selection tree
+type Visitor interface {
+	Enter(c *Cursor) WalkAction
+	Leave(c *Cursor)
+}
+
+// VisitorFunc is an adapter to allow the use of ordinary functions as visitors that
+// only act when entering nodes
+type VisitorFunc func(c *Cursor) WalkAction
+
+// Enter calls f(c)
+func (f VisitorFunc) Enter(c *Cursor) WalkAction {
+	return f(c)
+}
+
+// Leave does nothing
+func (f VisitorFunc) Leave(c *Cursor) {}
+
+// Cursor is a node of a selection tree being walked: a selection (the root, a field,
+// an inline fragment or a fragment spread), an argument, a directive or a value.
+type Cursor struct {
+	node ast.Node
+	path []string
+
+	selection *Selection
+	argument  *argument
+	directive *directive
+	value     *Value
+
+	replacement ast.Node
+	deleted     bool
+}
+
+// Kind returns the kind of the node, one of the graphql-go kinds such as Field, Argument or IntValue
+func (c *Cursor) Kind() string {
+	return c.node.GetKind()
+}
+
+// Path returns the response keys of the fields from the root to the node, followed for
+// arguments and values by the argument name and the object field names and list indexes
+// down to the value
+func (c *Cursor) Path() []string {
+	return c.path
+}
+
+// Selection returns the node when it is a selection, or nil
+func (c *Cursor) Selection() *Selection {
+	return c.selection
+}
+
+// Argument returns the node when it is an argument, or nil
+func (c *Cursor) Argument() *argument {
+	return c.argument
+}
+
+// Directive returns the node when it is a directive, or nil
+func (c *Cursor) Directive() *directive {
+	return c.directive
+}
+
+// Value returns the node when it is a value, or nil
+func (c *Cursor) Value() *Value {
+	return c.value
+}
+
+// ReplaceSelection replaces a field, inline fragment or fragment spread with a selection
+// built elsewhere, such as NewQuery().Selection("viewer"). The replacement isn't walked.
+func (c *Cursor) ReplaceSelection(s *Selection) {
+	if _, ok := s.node.(ast.Selection); ok && c.selection != nil {
+		c.replacement = s.node
+	}
+}
+
+// ReplaceArgument replaces an argument. The replacement isn't walked.
+func (c *Cursor) ReplaceArgument(arg *argument) {
+	if c.argument != nil {
+		c.replacement = arg.astArg
+	}
+}
+
+// ReplaceDirective replaces a directive. The replacement isn't walked.
+func (c *Cursor) ReplaceDirective(d *directive) {
+	if c.directive != nil {
+		c.replacement = d.astDirective
+	}
+}
+
+// ReplaceValue replaces a value. The replacement isn't walked.
+func (c *Cursor) ReplaceValue(v *Value) {
+	if c.value != nil {
+		c.replacement = v.astValue
+	}
+}
+
+// Delete removes the node from its parent. The root of the walk, and values
+// that aren't within a list or an object, can't be deleted.
+func (c *Cursor) Delete() {
+	c.deleted = true
+}
+
+// Walk walks a selection tree depth-first, calling v.Enter before the children of a
+// node and v.Leave after them. The children of a field are its arguments, then its
+// directives, then its selections. Nodes can be replaced or deleted as they are walked,
+// which changes the tree of the selection in place.
+func Walk(s *Selection, v Visitor) {
+	w := &walker{visitor: v}
+	w.visit(&Cursor{node: s.node, path: s.path(), selection: s}, func() {
+		w.children(s)
+	})
+}
+
+type walker struct {
+	visitor Visitor
+}
+
+// visit enters and leaves the node of c, walking its children in between,
+// and reports the node to keep in its place, which is nil when it was deleted
+func (w *walker) visit(c *Cursor, children func()) ast.Node {
+	action := w.visitor.Enter(c)
+	if c.deleted {
+		return nil
+	}
+	if c.replacement != nil {
+		return c.replacement
+	}
+	if action != SkipChildren {
+		children()
+	}
+	w.visitor.Leave(c)
+	if c.deleted {
+		return nil
+	}
+	if c.replacement != nil {
+		return c.replacement
+	}
+	return c.node
+}
+
+func (w *walker) children(s *Selection) {
+	path := s.path()
+	switch n := s.node.(type) {
+	case *ast.OperationDefinition:
+		n.Directives = w.directives(n.Directives, path)
+		w.selectionSet(n.SelectionSet, s)
+	case *ast.FragmentDefinition:
+		n.Directives = w.directives(n.Directives, path)
+		w.selectionSet(n.SelectionSet, s)
+	case *ast.Field:
+		n.Arguments = w.arguments(n.Arguments, path)
+		n.Directives = w.directives(n.Directives, path)
+		w.selectionSet(n.SelectionSet, s)
+	case *ast.InlineFragment:
+		n.Directives = w.directives(n.Directives, path)
+		w.selectionSet(n.SelectionSet, s)
+	case *ast.FragmentSpread:
+		n.Directives = w.directives(n.Directives, path)
+	}
+}
+
+func (w *walker) selectionSet(set *ast.SelectionSet, parent *Selection) {
+	if set == nil {
+		return
+	}
+	kept := make([]ast.Selection, 0, len(set.Selections))
+	for _, selection := range set.Selections {
+		s := &Selection{parent: parent, node: selection.(ast.Node)}
+		node := w.visit(&Cursor{node: s.node, path: s.path(), selection: s}, func() {
+			w.children(s)
+		})
+		if node != nil {
+			kept = append(kept, node.(ast.Selection))
+		}
+	}
+	set.Selections = kept
+}
+
+func (w *walker) arguments(args []*ast.Argument, path []string) []*ast.Argument {
+	kept := make([]*ast.Argument, 0, len(args))
+	for _, arg := range args {
+		arg := arg
+		argPath := append(path[:len(path):len(path)], arg.Name.Value)
+		node := w.visit(&Cursor{node: arg, path: argPath, argument: &argument{astArg: arg}}, func() {
+			if value := w.value(arg.Value, argPath, false); value != nil {
+				arg.Value = value
+			}
+		})
+		if node != nil {
+			kept = append(kept, node.(*ast.Argument))
+		}
+	}
+	return kept
+}
+
+func (w *walker) directives(directives []*ast.Directive, path []string) []*ast.Directive {
+	kept := make([]*ast.Directive, 0, len(directives))
+	for _, d := range directives {
+		d := d
+		node := w.visit(&Cursor{node: d, path: path, directive: &directive{astDirective: d}}, func() {
+			d.Arguments = w.arguments(d.Arguments, path)
+		})
+		if node != nil {
+			kept = append(kept, node.(*ast.Directive))
+		}
+	}
+	return kept
+}
+
+// value walks a value, returning the value to keep in its place, which is nil when it
+// was deleted; deletable tells whether the value is within a list or an object
+func (w *walker) value(value ast.Value, path []string, deletable bool) ast.Value {
+	if value == nil {
+		return nil
+	}
+	c := &Cursor{node: value, path: path, value: &Value{astValue: value}}
+	node := w.visit(c, func() {
+		switch v := value.(type) {
+		case *ast.ListValue:
+			kept := make([]ast.Value, 0, len(v.Values))
+			for i, item := range v.Values {
+				if item = w.value(item, append(path[:len(path):len(path)], strconv.Itoa(i)), true); item != nil {
+					kept = append(kept, item)
+				}
+			}
+			v.Values = kept
+		case *ast.ObjectValue:
+			kept := make([]*ast.ObjectField, 0, len(v.Fields))
+			for _, f := range v.Fields {
+				if f.Value = w.value(f.Value, append(path[:len(path):len(path)], f.Name.Value), true); f.Value != nil {
+					kept = append(kept, f)
+				}
+			}
+			v.Fields = kept
+		}
+	})
+	if node == nil {
+		if !deletable {
+			return value
+		}
+		return nil
+	}
+	return node.(ast.Value)
+}
@@ -0,0 +1,152 @@
+package fluentgraphql
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type recordingVisitor struct {
+	events []string
+}
+
+func (v *recordingVisitor) Enter(c *Cursor) WalkAction {
+	v.events = append(v.events, "enter "+c.Kind()+" "+strings.Join(c.Path(), "."))
+	return Continue
+}
+
+func (v *recordingVisitor) Leave(c *Cursor) {
+	v.events = append(v.events, "leave "+c.Kind())
+}
+
+func TestWalkOrder(t *testing.T) {
+	q, err := Parse(`{ repo: repository(owner: "mergestat", states: [OPEN]) @include(if: true) { name ... on Repository { ...fields } } }`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := &recordingVisitor{}
+	Walk(q, v)
+
+	wanted := []string{
+		"enter OperationDefinition ",
+		"enter Field repo",
+		"enter Argument repo.owner",
+		"enter StringValue repo.owner",
+		"leave StringValue",
+		"leave Argument",
+		"enter Argument repo.states",
+		"enter ListValue repo.states",
+		"enter EnumValue repo.states.0",
+		"leave EnumValue",
+		"leave ListValue",
+		"leave Argument",
+		"enter Directive repo",
+		"enter Argument repo.if",
+		"enter BooleanValue repo.if",
+		"leave BooleanValue",
+		"leave Argument",
+		"leave Directive",
+		"enter Field repo.name",
+		"leave Field",
+		"enter InlineFragment repo",
+		"enter FragmentSpread repo",
+		"leave FragmentSpread",
+		"leave InlineFragment",
+		"leave Field",
+		"leave OperationDefinition",
+	}
+	if diff := cmp.Diff(wanted, v.events); diff != "" {
+		t.Fatal("walked nodes do not match what's wanted", diff)
+	}
+}
+
+func TestWalkChanges(t *testing.T) {
+	for name, testCase := range map[string]struct {
+		wanted  string
+		visitor VisitorFunc
+	}{
+		"SkipChildren": {
+			wanted: `{ a: repository(owner: "mergestat") { name stargazerCount } b: repository(owner: "github") { name } }`,
+			visitor: func(c *Cursor) WalkAction {
+				if c.Kind() == "Field" && strings.Join(c.Path(), ".") == "a" {
+					return SkipChildren
+				}
+				if c.Kind() == "Field" && c.Path()[len(c.Path())-1] == "stargazerCount" {
+					c.Delete()
+				}
+				return Continue
+			},
+		},
+		"DeleteField": {
+			wanted: `{ a: repository(owner: "mergestat") { name } b: repository(owner: "github") { name stargazerCount } }`,
+			visitor: func(c *Cursor) WalkAction {
+				if s := c.Selection(); s != nil && strings.Join(c.Path(), ".") == "a.stargazerCount" {
+					c.Delete()
+				}
+				return Continue
+			},
+		},
+		"ReplaceValue": {
+			wanted: `{ a: repository(owner: "fluent") { name stargazerCount } b: repository(owner: "fluent") { name stargazerCount } }`,
+			visitor: func(c *Cursor) WalkAction {
+				if c.Kind() == "StringValue" {
+					c.ReplaceValue(NewStringValue("fluent"))
+				}
+				return Continue
+			},
+		},
+		"ReplaceSelection": {
+			wanted: `{ a: repository(owner: "mergestat") { name stargazerCount } viewer { login } }`,
+			visitor: func(c *Cursor) WalkAction {
+				if strings.Join(c.Path(), ".") == "b" && c.Selection() != nil {
+					c.ReplaceSelection(NewQuery().Selection("viewer").Scalar("login"))
+				}
+				return Continue
+			},
+		},
+		"DeleteArgument": {
+			wanted: `{ a: repository { name stargazerCount } b: repository { name stargazerCount } }`,
+			visitor: func(c *Cursor) WalkAction {
+				if c.Argument() != nil {
+					c.Delete()
+				}
+				return Continue
+			},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			q := NewQuery().
+				Selection("repository", WithAlias("a"), WithArguments(NewArgument("owner", NewStringValue("mergestat")))).
+				Scalar("name").Scalar("stargazerCount").
+				Parent().
+				Selection("repository", WithAlias("b"), WithArguments(NewArgument("owner", NewStringValue("github")))).
+				Scalar("name").Scalar("stargazerCount").
+				Root()
+
+			Walk(q, testCase.visitor)
+
+			if diff := queryMatchesTree(t, testCase.wanted, q.node); diff != "" {
+				t.Log("walked GraphQL query does not match what's wanted", diff)
+				t.Fatal()
+			}
+		})
+	}
+}
+
+func TestWalkDeleteListItems(t *testing.T) {
+	q := NewQuery().Scalar("issues", WithArguments(NewArgument("states", NewListValue(NewEnumValue("OPEN"), NewEnumValue("CLOSED"), NewEnumValue("MERGED")))))
+
+	Walk(q, VisitorFunc(func(c *Cursor) WalkAction {
+		if c.Kind() == "EnumValue" && c.Path()[len(c.Path())-1] != "0" {
+			c.Delete()
+		}
+		return Continue
+	}))
+
+	if diff := queryMatchesTree(t, `{ issues(states: [OPEN]) }`, q.node); diff != "" {
+		t.Log("walked GraphQL query does not match what's wanted", diff)
+		t.Fatal()
+	}
+}